@@ -0,0 +1,104 @@
+package alert
+
+import (
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/latifrons/etherxray/ethnode"
+	"github.com/latifrons/etherxray/model"
+	"github.com/latifrons/etherxray/notify"
+	"github.com/sirupsen/logrus"
+	"math/big"
+	"time"
+)
+
+// Engine evaluates the alert rules against every new block and delivers the alerts to the sinks
+type Engine struct {
+	EthNode *ethnode.EthNode
+	// Sinks by name: "webhook", "file", "stdout"
+	Sinks map[string]notify.Sink
+
+	rules []*Rule
+}
+
+// AddRule compiles the rule and checks its sinks
+func (e *Engine) AddRule(rule *Rule) (err error) {
+	if err = rule.compile(); err != nil {
+		return fmt.Errorf("rule %s: %v", rule.Name, err)
+	}
+	for _, sink := range rule.Sinks {
+		if _, ok := e.Sinks[sink]; !ok {
+			return fmt.Errorf("rule %s: sink %s is not configured", rule.Name, sink)
+		}
+	}
+	e.rules = append(e.rules, rule)
+	return
+}
+
+func (e *Engine) Name() string {
+	return "AlertEngine"
+}
+
+func (e *Engine) HandleBlock(height uint64, hash common.Hash, txs []model.Tx) {
+	block := &blockEnv{
+		engine:    e,
+		height:    height,
+		txs:       txs,
+		nonceGaps: make(map[common.Address]*big.Rat),
+	}
+	now := time.Now()
+	for _, rule := range e.rules {
+		if !rule.perTx {
+			matched, err := rule.expr.Eval(block)
+			if err != nil {
+				logrus.WithError(err).WithField("rule", rule.Name).Warn("failed to evaluate alert rule")
+				continue
+			}
+			if matched {
+				e.raise(rule, &model.Alert{Height: height}, now)
+			}
+			continue
+		}
+		for i := range txs {
+			tx := &txs[i]
+			matched, err := rule.expr.Eval(&txEnv{block: block, tx: tx})
+			if err != nil {
+				logrus.WithError(err).WithField("rule", rule.Name).WithField("tx", tx.BasicTx.Hash().Hex()).
+					Warn("failed to evaluate alert rule")
+				continue
+			}
+			if !matched {
+				continue
+			}
+			hash := tx.BasicTx.Hash()
+			from := tx.From
+			e.raise(rule, &model.Alert{
+				Height: height,
+				TxHash: &hash,
+				From:   &from,
+				To:     tx.BasicTx.To(),
+			}, now)
+		}
+	}
+}
+
+func (e *Engine) raise(rule *Rule, alert *model.Alert, now time.Time) {
+	if !rule.admit(alert, now) {
+		return
+	}
+	alert.Rule = rule.Name
+	alert.Description = rule.Description
+	alert.Time = now.Unix()
+	alert.Id = fmt.Sprintf("%s-%d", rule.Name, alert.Height)
+	if alert.TxHash != nil {
+		alert.Id = fmt.Sprintf("%s-%s", rule.Name, alert.TxHash.Hex())
+	}
+	if len(rule.Sinks) == 0 {
+		for _, sink := range e.Sinks {
+			sink.Send(alert.Id, alert)
+		}
+		return
+	}
+	for _, name := range rule.Sinks {
+		e.Sinks[name].Send(alert.Id, alert)
+	}
+}
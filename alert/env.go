@@ -0,0 +1,275 @@
+package alert
+
+import (
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/latifrons/etherxray/model"
+	"github.com/latifrons/etherxray/tools"
+	"math/big"
+	"strings"
+)
+
+// txFields are the fields of the transaction a rule is evaluated against. Ether amounts are in ETH, gas prices in gwei.
+// Fields that do not apply to the transaction, such as tx.to of a contract creation, have no value.
+var txFields = map[string]func(tx *model.Tx) interface{}{
+	"tx.hash": func(tx *model.Tx) interface{} { return tx.BasicTx.Hash().Hex() },
+	"tx.from": func(tx *model.Tx) interface{} { return tx.From.Hex() },
+	"tx.to": func(tx *model.Tx) interface{} {
+		if tx.BasicTx.To() == nil {
+			return nil
+		}
+		return tx.BasicTx.To().Hex()
+	},
+	"tx.contract": func(tx *model.Tx) interface{} {
+		if tx.Receipt.ContractAddress == (common.Address{}) {
+			return nil
+		}
+		return tx.Receipt.ContractAddress.Hex()
+	},
+	"tx.value": func(tx *model.Tx) interface{} { return tools.FromDecimals(tx.BasicTx.Value(), 18) },
+	"tx.value_usd": func(tx *model.Tx) interface{} {
+		if tx.EthUsd == nil {
+			return nil
+		}
+		return new(big.Rat).Mul(tools.FromDecimals(tx.BasicTx.Value(), 18), tx.EthUsd)
+	},
+	"tx.nonce":     func(tx *model.Tx) interface{} { return new(big.Rat).SetInt64(int64(tx.BasicTx.Nonce())) },
+	"tx.gas_used":  func(tx *model.Tx) interface{} { return new(big.Rat).SetInt64(int64(tx.Receipt.GasUsed)) },
+	"tx.gas_price": func(tx *model.Tx) interface{} { return tools.FromDecimals(tx.BasicTx.GasPrice(), 9) },
+	"tx.gas_cost":  func(tx *model.Tx) interface{} { return tools.FromDecimals(tx.GasCost, 18) },
+	"tx.failed":    func(tx *model.Tx) interface{} { return tx.Receipt.Status != types.ReceiptStatusSuccessful },
+	"tx.failure": func(tx *model.Tx) interface{} {
+		if tx.Failure == nil {
+			return nil
+		}
+		return tx.Failure.Reason
+	},
+	"tx.selector": func(tx *model.Tx) interface{} {
+		if len(tx.BasicTx.Data()) < 4 {
+			return nil
+		}
+		return hexutil.Encode(tx.BasicTx.Data()[:4])
+	},
+	"tx.rating": func(tx *model.Tx) interface{} { return new(big.Rat).SetInt64(int64(tx.Rating)) },
+	"tx.logs":   func(tx *model.Tx) interface{} { return new(big.Rat).SetInt64(int64(len(tx.Receipt.Logs))) },
+}
+
+// txFunctions are the functions over the transaction, with their number of arguments
+var txFunctions = map[string]int{
+	// tag("swap") tells whether the classifier tagged the transaction with the category
+	"tag": 1,
+	// mev("sandwich_victim") tells whether the transaction plays the MEV role
+	"mev": 1,
+	// event("Transfer") tells whether the transaction emits the event, given by name, signature or topic
+	"event": 1,
+	// event_from(0x...) tells whether the contract emits a log in the transaction
+	"event_from": 1,
+	// token_amount(0x...) is the amount of the token transferred in the transaction, in token units
+	"token_amount": 1,
+}
+
+// blockFields are the fields of the block. They can be used in transaction rules too.
+var blockFields = map[string]bool{
+	"block.height":   true,
+	"block.tx_count": true,
+	"block.gas_used": true,
+	// base fee in gwei, zero before London
+	"block.base_fee": true,
+}
+
+var blockFunctions = map[string]int{
+	// nonce_gap(0x...) is the distance between the next nonce of the account and its lowest queued transaction in the txpool
+	"nonce_gap": 1,
+}
+
+// validate checks that the expression only uses known names and tells whether it applies to transactions
+func validate(expr *Expr) (perTx bool, err error) {
+	for _, name := range expr.Names() {
+		if strings.HasSuffix(name, "()") {
+			function := strings.TrimSuffix(name, "()")
+			if _, ok := txFunctions[function]; ok {
+				perTx = true
+				continue
+			}
+			if _, ok := blockFunctions[function]; ok {
+				continue
+			}
+			err = fmt.Errorf("unknown function %s", function)
+			return
+		}
+		if _, ok := txFields[name]; ok {
+			perTx = true
+			continue
+		}
+		if !blockFields[name] {
+			err = fmt.Errorf("unknown field %s", name)
+			return
+		}
+	}
+	return
+}
+
+// blockEnv evaluates rules against a block. Values read from the node are cached for the block.
+type blockEnv struct {
+	engine *Engine
+	height uint64
+	txs    []model.Tx
+
+	baseFee   *big.Rat
+	nonceGaps map[common.Address]*big.Rat
+}
+
+func (e *blockEnv) field(name string) (interface{}, error) {
+	switch name {
+	case "block.height":
+		return new(big.Rat).SetInt64(int64(e.height)), nil
+	case "block.tx_count":
+		return new(big.Rat).SetInt64(int64(len(e.txs))), nil
+	case "block.gas_used":
+		var gasUsed uint64
+		for _, tx := range e.txs {
+			gasUsed += tx.Receipt.GasUsed
+		}
+		return new(big.Rat).SetInt64(int64(gasUsed)), nil
+	case "block.base_fee":
+		if e.baseFee == nil {
+			baseFee, err := e.engine.EthNode.RpcWrapper.GetBaseFee(tools.GetContextDefault(), e.height)
+			if err != nil {
+				return nil, err
+			}
+			e.baseFee = tools.FromDecimals(baseFee, 9)
+		}
+		return e.baseFee, nil
+	}
+	return nil, fmt.Errorf("unknown field %s", name)
+}
+
+func (e *blockEnv) call(name string, args []interface{}) (interface{}, error) {
+	if arity, ok := blockFunctions[name]; !ok || arity != len(args) {
+		return nil, fmt.Errorf("bad call %s with %d arguments", name, len(args))
+	}
+	address, err := addressArg(name, args[0])
+	if err != nil {
+		return nil, err
+	}
+	if gap, ok := e.nonceGaps[address]; ok {
+		return gap, nil
+	}
+	queued, err := e.engine.EthNode.RpcWrapper.GetQueuedNonces(tools.GetContextDefault(), address)
+	if err != nil {
+		return nil, err
+	}
+	gap := new(big.Rat)
+	if len(queued) > 0 {
+		next, err := e.engine.EthNode.RpcWrapper.PendingNonceAt(tools.GetContextDefault(), address)
+		if err != nil {
+			return nil, err
+		}
+		if queued[0] > next {
+			gap.SetInt64(int64(queued[0] - next))
+		}
+	}
+	e.nonceGaps[address] = gap
+	return gap, nil
+}
+
+// txEnv evaluates rules against one transaction of the block
+type txEnv struct {
+	block *blockEnv
+	tx    *model.Tx
+}
+
+func (e *txEnv) field(name string) (interface{}, error) {
+	if f, ok := txFields[name]; ok {
+		return f(e.tx), nil
+	}
+	return e.block.field(name)
+}
+
+func (e *txEnv) call(name string, args []interface{}) (interface{}, error) {
+	arity, ok := txFunctions[name]
+	if !ok {
+		return e.block.call(name, args)
+	}
+	if arity != len(args) {
+		return nil, fmt.Errorf("%s takes %d arguments", name, arity)
+	}
+	switch name {
+	case "tag", "mev", "event":
+		s, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("%s expects a string", name)
+		}
+		switch name {
+		case "tag":
+			return e.tx.HasTag(s), nil
+		case "mev":
+			return e.tx.HasMev(s), nil
+		}
+		return e.emits(s), nil
+	case "event_from":
+		address, err := addressArg(name, args[0])
+		if err != nil {
+			return nil, err
+		}
+		for _, log := range e.tx.Receipt.Logs {
+			if log.Address == address {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "token_amount":
+		token, err := addressArg(name, args[0])
+		if err != nil {
+			return nil, err
+		}
+		amount := new(big.Rat)
+		for _, transfer := range e.tx.TokenTransfers {
+			if transfer.Token == token {
+				amount.Add(amount, tools.FromDecimals(transfer.Amount, transfer.Decimals))
+			}
+		}
+		return amount, nil
+	}
+	return nil, fmt.Errorf("unknown function %s", name)
+}
+
+// emits tells whether a log of the transaction is the event, given by topic, signature or name
+func (e *txEnv) emits(event string) bool {
+	isTopic := strings.HasPrefix(event, "0x") && len(event) == 2+2*common.HashLength
+	for _, log := range e.tx.Receipt.Logs {
+		if len(log.Topics) == 0 {
+			continue
+		}
+		if isTopic {
+			if strings.EqualFold(log.Topics[0].Hex(), event) {
+				return true
+			}
+			continue
+		}
+		sig, ok := e.block.engine.EthNode.Registry.Event(log.Topics[0])
+		if !ok {
+			continue
+		}
+		name := sig.Text
+		if i := strings.Index(name, "("); i >= 0 && !strings.Contains(event, "(") {
+			name = name[:i]
+		}
+		if name == event {
+			return true
+		}
+	}
+	return false
+}
+
+func addressArg(function string, arg interface{}) (address common.Address, err error) {
+	s, ok := arg.(string)
+	if !ok || !common.IsHexAddress(s) {
+		err = fmt.Errorf("%s expects an address", function)
+		return
+	}
+	address = common.HexToAddress(s)
+	return
+}
@@ -0,0 +1,430 @@
+package alert
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode"
+)
+
+// The expression language of the rules:
+//
+//	expr    = or
+//	or      = and { "||" and }
+//	and     = not { "&&" not }
+//	not     = "!" not | compare
+//	compare = operand [ ( "==" | "!=" | ">" | ">=" | "<" | "<=" ) operand ]
+//	operand = number | string | hex | "true" | "false" | name | name "(" [ expr { "," expr } ] ")" | "(" expr ")"
+//
+// Numbers are decimals, strings are quoted with " or ', hex literals such as addresses are written bare.
+// Values are numbers, strings and booleans. Strings compare case-insensitively.
+// A field without a value, such as the USD value of an unpriced transaction, makes every comparison false.
+
+type node interface {
+	eval(env env) (interface{}, error)
+}
+
+// env resolves the fields and functions of an expression
+type env interface {
+	field(name string) (interface{}, error)
+	call(name string, args []interface{}) (interface{}, error)
+}
+
+type literal struct {
+	value interface{}
+}
+
+type field struct {
+	name string
+}
+
+type call struct {
+	name string
+	args []node
+}
+
+type unary struct {
+	operand node
+}
+
+type binary struct {
+	op    string
+	left  node
+	right node
+}
+
+func (n *literal) eval(env env) (interface{}, error) {
+	return n.value, nil
+}
+
+func (n *field) eval(env env) (interface{}, error) {
+	return env.field(n.name)
+}
+
+func (n *call) eval(env env) (interface{}, error) {
+	args := make([]interface{}, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	return env.call(n.name, args)
+}
+
+func (n *unary) eval(env env) (interface{}, error) {
+	v, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return nil, fmt.Errorf("! expects a boolean, got %v", v)
+	}
+	return !b, nil
+}
+
+func (n *binary) eval(env env) (interface{}, error) {
+	left, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "&&", "||":
+		l, ok := left.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%s expects booleans, got %v", n.op, left)
+		}
+		// short circuit
+		if l == (n.op == "||") {
+			return l, nil
+		}
+		right, err := n.right.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		r, ok := right.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%s expects booleans, got %v", n.op, right)
+		}
+		return r, nil
+	}
+	right, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	return compare(n.op, left, right)
+}
+
+func compare(op string, left interface{}, right interface{}) (interface{}, error) {
+	if left == nil || right == nil {
+		return false, nil
+	}
+	var c int
+	switch l := left.(type) {
+	case *big.Rat:
+		r, ok := right.(*big.Rat)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare number %s with %v", l.FloatString(6), right)
+		}
+		c = l.Cmp(r)
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare string %q with %v", l, right)
+		}
+		if op != "==" && op != "!=" {
+			return nil, fmt.Errorf("strings only support == and !=")
+		}
+		if !strings.EqualFold(l, r) {
+			c = 1
+		}
+	case bool:
+		r, ok := right.(bool)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare boolean with %v", right)
+		}
+		if op != "==" && op != "!=" {
+			return nil, fmt.Errorf("booleans only support == and !=")
+		}
+		if l != r {
+			c = 1
+		}
+	default:
+		return nil, fmt.Errorf("cannot compare %v", left)
+	}
+	switch op {
+	case "==":
+		return c == 0, nil
+	case "!=":
+		return c != 0, nil
+	case ">":
+		return c > 0, nil
+	case ">=":
+		return c >= 0, nil
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	}
+	return nil, fmt.Errorf("unknown operator %s", op)
+}
+
+// Expr is a compiled rule expression
+type Expr struct {
+	Source string
+	root   node
+	// names are the fields and functions the expression uses, functions with a "()" suffix
+	names []string
+}
+
+// Eval evaluates the expression, which must be boolean
+func (e *Expr) Eval(env env) (bool, error) {
+	v, err := e.root.eval(env)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression is not a condition: %v", v)
+	}
+	return b, nil
+}
+
+// Names returns the fields and functions the expression uses, functions with a "()" suffix
+func (e *Expr) Names() []string {
+	return e.names
+}
+
+// Compile parses the expression
+func Compile(source string) (expr *Expr, err error) {
+	p := &parser{}
+	p.tokens, err = lex(source)
+	if err != nil {
+		return
+	}
+	expr = &Expr{Source: source}
+	expr.root, err = p.parseOr()
+	if err != nil {
+		return
+	}
+	if p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %q", p.tokens[p.pos].text)
+		return
+	}
+	expr.names = p.names
+	return
+}
+
+const (
+	tokenNumber = iota
+	tokenString
+	tokenName
+	tokenOp
+)
+
+type token struct {
+	kind int
+	text string
+}
+
+func lex(source string) (tokens []token, err error) {
+	runes := []rune(source)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"' || r == '\'':
+			j := i + 1
+			for j < len(runes) && runes[j] != r {
+				j++
+			}
+			if j == len(runes) {
+				return nil, errors.New("unterminated string")
+			}
+			tokens = append(tokens, token{tokenString, string(runes[i+1 : j])})
+			i = j + 1
+		case r == '0' && i+1 < len(runes) && (runes[i+1] == 'x' || runes[i+1] == 'X'):
+			// hex literal, kept as a string
+			j := i + 2
+			for j < len(runes) && isHexDigit(runes[j]) {
+				j++
+			}
+			tokens = append(tokens, token{tokenString, string(runes[i:j])})
+			i = j
+		case unicode.IsDigit(r):
+			j := i
+			for j < len(runes) && (unicode.IsDigit(runes[j]) || runes[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, token{tokenNumber, string(runes[i:j])})
+			i = j
+		case unicode.IsLetter(r) || r == '_':
+			j := i
+			for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j]) || runes[j] == '_' || runes[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, token{tokenName, string(runes[i:j])})
+			i = j
+		default:
+			op := ""
+			for _, candidate := range []string{"&&", "||", "==", "!=", ">=", "<=", ">", "<", "!", "(", ")", ","} {
+				if strings.HasPrefix(string(runes[i:]), candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q", r)
+			}
+			tokens = append(tokens, token{tokenOp, op})
+			i += len(op)
+		}
+	}
+	return
+}
+
+func isHexDigit(r rune) bool {
+	return unicode.IsDigit(r) || (r >= 'a' && r <= 'f') || (r >= 'A' && r <= 'F')
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	names  []string
+}
+
+func (p *parser) peek(op string) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].kind == tokenOp && p.tokens[p.pos].text == op
+}
+
+func (p *parser) expect(op string) error {
+	if !p.peek(op) {
+		return fmt.Errorf("expected %q", op)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek("||") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &binary{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek("&&") {
+		p.pos++
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &binary{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.peek("!") {
+		p.pos++
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &unary{operand: operand}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *parser) parseCompare() (node, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"==", "!=", ">=", "<=", ">", "<"} {
+		if p.peek(op) {
+			p.pos++
+			right, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			return &binary{op: op, left: left, right: right}, nil
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) parseOperand() (node, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, errors.New("unexpected end of expression")
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+	switch t.kind {
+	case tokenNumber:
+		v, ok := new(big.Rat).SetString(t.text)
+		if !ok {
+			return nil, fmt.Errorf("bad number %s", t.text)
+		}
+		return &literal{value: v}, nil
+	case tokenString:
+		return &literal{value: t.text}, nil
+	case tokenName:
+		switch t.text {
+		case "true":
+			return &literal{value: true}, nil
+		case "false":
+			return &literal{value: false}, nil
+		}
+		if !p.peek("(") {
+			p.names = append(p.names, t.text)
+			return &field{name: t.text}, nil
+		}
+		p.names = append(p.names, t.text+"()")
+		p.pos++
+		n := &call{name: t.text}
+		for !p.peek(")") {
+			if len(n.args) > 0 {
+				if err := p.expect(","); err != nil {
+					return nil, err
+				}
+			}
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			n.args = append(n.args, arg)
+		}
+		p.pos++
+		return n, nil
+	case tokenOp:
+		if t.text == "(" {
+			n, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err = p.expect(")"); err != nil {
+				return nil, err
+			}
+			return n, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected %q", t.text)
+}
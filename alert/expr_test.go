@@ -0,0 +1,236 @@
+package alert
+
+import (
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/latifrons/etherxray/model"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testEnv resolves fields from a map, calls return their first argument, fail() fails
+type testEnv map[string]interface{}
+
+func (e testEnv) field(name string) (interface{}, error) {
+	v, ok := e[name]
+	if !ok {
+		return nil, errors.New("unknown field " + name)
+	}
+	return v, nil
+}
+
+func (e testEnv) call(name string, args []interface{}) (interface{}, error) {
+	if name == "fail" || len(args) == 0 {
+		return nil, errors.New("call failed")
+	}
+	return args[0], nil
+}
+
+func TestEval(t *testing.T) {
+	env := testEnv{
+		"value":  big.NewRat(15, 10),
+		"count":  big.NewRat(3, 1),
+		"from":   "0xAbC0000000000000000000000000000000000001",
+		"failed": true,
+		"ok":     false,
+		"usd":    nil,
+	}
+	tests := []struct {
+		source string
+		want   bool
+	}{
+		// precedence: ! binds tighter than &&, && tighter than ||
+		{"true || false && false", true},
+		{"(true || false) && false", false},
+		{"!false && false", false},
+		{"!(false && false)", true},
+		{"!!true", true},
+		{"false || false || true", true},
+		{"true && true && false", false},
+		{"failed && count > 2 || ok", true},
+		{"ok || failed && count > 5", false},
+		// numbers
+		{"value > 1.4", true},
+		{"value >= 1.5", true},
+		{"value < 1.5", false},
+		{"value <= 1.5", true},
+		{"count == 3", true},
+		{"count != 3", false},
+		{"0.5 < value", true},
+		// strings compare case-insensitively, hex literals are strings
+		{"from == 0xabc0000000000000000000000000000000000001", true},
+		{"from != '0xABC0000000000000000000000000000000000001'", false},
+		{`"Swap" == 'swap'`, true},
+		// booleans
+		{"failed == true", true},
+		{"ok != false", false},
+		{"failed", true},
+		// a field without a value makes every comparison false
+		{"usd > 0", false},
+		{"usd <= 0", false},
+		{"usd == usd", false},
+		{"usd != 1", false},
+		// calls
+		{"id(failed)", true},
+		{"id(count) > 2 && id('a') == 'A'", true},
+		// the right side is not evaluated once the left side decides
+		{"true || fail()", true},
+		{"false && fail()", false},
+	}
+	for _, test := range tests {
+		expr, err := Compile(test.source)
+		if err != nil {
+			t.Errorf("%s: %v", test.source, err)
+			continue
+		}
+		got, err := expr.Eval(env)
+		if err != nil {
+			t.Errorf("%s: %v", test.source, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%s = %v, want %v", test.source, got, test.want)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		err    string
+	}{
+		{"", "unexpected end"},
+		{"count >", "unexpected end"},
+		{"'open", "unterminated string"},
+		{"count # 1", "unexpected character"},
+		{"(count > 1", `expected ")"`},
+		{"count > 1)", `unexpected ")"`},
+		{"count 1", `unexpected "1"`},
+		{"1.2.3 > 1", "bad number"},
+		{"id(1 2)", `expected ","`},
+		{"&& true", `unexpected "&&"`},
+	}
+	for _, test := range tests {
+		_, err := Compile(test.source)
+		if err == nil {
+			t.Errorf("%s compiled, want an error", test.source)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: error %q, want %q", test.source, err, test.err)
+		}
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	env := testEnv{
+		"count":  big.NewRat(3, 1),
+		"from":   "0xabc",
+		"failed": true,
+	}
+	tests := []struct {
+		source string
+		err    string
+	}{
+		{"count == 'three'", "cannot compare number"},
+		{"from > 'a'", "strings only support"},
+		{"from == 1", "cannot compare string"},
+		{"failed > false", "booleans only support"},
+		{"failed == 1", "cannot compare boolean"},
+		{"!count", "! expects a boolean"},
+		{"count && true", "&& expects booleans"},
+		{"false || count", "|| expects booleans"},
+		{"count", "not a condition"},
+		{"missing > 1", "unknown field"},
+		{"fail() == 1", "call failed"},
+	}
+	for _, test := range tests {
+		expr, err := Compile(test.source)
+		if err != nil {
+			t.Errorf("%s: %v", test.source, err)
+			continue
+		}
+		_, err = expr.Eval(env)
+		if err == nil {
+			t.Errorf("%s evaluated, want an error", test.source)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: error %q, want %q", test.source, err, test.err)
+		}
+	}
+}
+
+func TestNames(t *testing.T) {
+	expr, err := Compile("tx.value > 1 && (tag('swap') || !block.height) && nonce_gap(tx.from) > 0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"tx.value", "tag()", "block.height", "nonce_gap()", "tx.from"}
+	if !reflect.DeepEqual(expr.Names(), want) {
+		t.Errorf("names %v, want %v", expr.Names(), want)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		source string
+		perTx  bool
+		err    bool
+	}{
+		{"block.tx_count > 100", false, false},
+		{"nonce_gap(0x00000000000000000000000000000000000000a1) > 5", false, false},
+		{"tx.value > 1", true, false},
+		{"block.base_fee > 100 && tag('swap')", true, false},
+		{"tx.unknown > 1", false, true},
+		{"unknown('x')", false, true},
+	}
+	for _, test := range tests {
+		expr, err := Compile(test.source)
+		if err != nil {
+			t.Fatalf("%s: %v", test.source, err)
+		}
+		perTx, err := validate(expr)
+		if (err != nil) != test.err {
+			t.Errorf("%s: error %v, want error %v", test.source, err, test.err)
+			continue
+		}
+		if err == nil && perTx != test.perTx {
+			t.Errorf("%s: per transaction %v, want %v", test.source, perTx, test.perTx)
+		}
+	}
+}
+
+func TestContractCreationHasNoRecipient(t *testing.T) {
+	contract := common.HexToAddress("0x00000000000000000000000000000000000000c1")
+	tx := &model.Tx{
+		BasicTx: types.NewContractCreation(0, big.NewInt(0), 100000, big.NewInt(1), nil),
+		Receipt: &types.Receipt{ContractAddress: contract},
+	}
+	env := &txEnv{block: &blockEnv{}, tx: tx}
+	tests := []struct {
+		source string
+		want   bool
+	}{
+		{"tx.to == ''", false},
+		{"tx.to != 0x00000000000000000000000000000000000000c1", false},
+		{"tx.contract == 0x00000000000000000000000000000000000000c1", true},
+	}
+	for _, test := range tests {
+		expr, err := Compile(test.source)
+		if err != nil {
+			t.Fatalf("%s: %v", test.source, err)
+		}
+		got, err := expr.Eval(env)
+		if err != nil {
+			t.Errorf("%s: %v", test.source, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%s = %v, want %v", test.source, got, test.want)
+		}
+	}
+}
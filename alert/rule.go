@@ -0,0 +1,119 @@
+package alert
+
+import (
+	"fmt"
+	"github.com/latifrons/etherxray/model"
+	"time"
+)
+
+// Dedup keys: a rule sends one alert per key within the dedup window
+const (
+	DedupTx    = "tx"
+	DedupBlock = "block"
+	DedupFrom  = "from"
+	DedupTo    = "to"
+	DedupRule  = "rule"
+)
+
+// Rule raises an alert for every transaction, or for the block, matching its expression.
+// A rule using a transaction field or function applies to each transaction, otherwise once per block.
+type Rule struct {
+	Name        string
+	Description string
+	Source      string
+	// Sinks names the sinks the alerts go to, all of them when empty
+	Sinks []string
+	// RateLimit is the most alerts sent per RateWindow, zero is unlimited
+	RateLimit  int
+	RateWindow time.Duration
+	// Dedup is the key alerts are deduplicated by, DedupTx for transaction rules and DedupBlock for block rules by default
+	Dedup       string
+	DedupWindow time.Duration
+
+	expr       *Expr
+	perTx      bool
+	sent       []time.Time
+	suppressed uint64
+	seen       map[string]time.Time
+}
+
+// compile parses the expression and sets the defaults
+func (r *Rule) compile() (err error) {
+	r.expr, err = Compile(r.Source)
+	if err != nil {
+		return
+	}
+	r.perTx, err = validate(r.expr)
+	if err != nil {
+		return
+	}
+	if r.RateWindow == 0 {
+		r.RateWindow = time.Minute
+	}
+	if r.DedupWindow == 0 {
+		r.DedupWindow = time.Hour
+	}
+	switch r.Dedup {
+	case "":
+		r.Dedup = DedupBlock
+		if r.perTx {
+			r.Dedup = DedupTx
+		}
+	case DedupTx, DedupFrom, DedupTo:
+		if !r.perTx {
+			return fmt.Errorf("dedup by %s needs a transaction rule", r.Dedup)
+		}
+	case DedupBlock, DedupRule:
+	default:
+		return fmt.Errorf("unknown dedup key %s", r.Dedup)
+	}
+	r.seen = make(map[string]time.Time)
+	return
+}
+
+// admit applies the deduplication and the rate limit to the alert and tells whether to send it
+func (r *Rule) admit(alert *model.Alert, now time.Time) bool {
+	for key, at := range r.seen {
+		if now.Sub(at) > r.DedupWindow {
+			delete(r.seen, key)
+		}
+	}
+	key := r.dedupKey(alert)
+	if _, ok := r.seen[key]; ok {
+		return false
+	}
+
+	if r.RateLimit > 0 {
+		i := 0
+		for i < len(r.sent) && now.Sub(r.sent[i]) > r.RateWindow {
+			i++
+		}
+		r.sent = r.sent[i:]
+		if len(r.sent) >= r.RateLimit {
+			r.suppressed++
+			return false
+		}
+		r.sent = append(r.sent, now)
+	}
+	r.seen[key] = now
+	alert.Suppressed = r.suppressed
+	r.suppressed = 0
+	return true
+}
+
+func (r *Rule) dedupKey(alert *model.Alert) string {
+	switch r.Dedup {
+	case DedupTx:
+		return alert.TxHash.Hex()
+	case DedupFrom:
+		return alert.From.Hex()
+	case DedupTo:
+		if alert.To == nil {
+			return ""
+		}
+		return alert.To.Hex()
+	case DedupBlock:
+		return fmt.Sprint(alert.Height)
+	}
+	return ""
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/latifrons/etherxray/core"
+	"github.com/latifrons/etherxray/store"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"os"
+	"text/tabwriter"
+)
+
+// dbCmd groups the maintenance commands of the local index. The node must be stopped, the index is locked while open.
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Maintain the local index",
+}
+
+var dbPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Apply the retention policy of [store.retention] once, then compact",
+	Run: func(cmd *cobra.Command, args []string) {
+		withIndex(func(repository *store.LevelRepository, dataFolder string) {
+			pruned, err := core.NewPruner(repository, core.NewWatchlist(dataFolder)).Prune()
+			if err != nil {
+				logrus.WithError(err).Fatal("failed to prune index")
+			}
+			fmt.Printf("pruned %d blocks\n", pruned)
+			if err = repository.Compact(); err != nil {
+				logrus.WithError(err).Fatal("failed to compact index")
+			}
+		})
+	},
+}
+
+var dbStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Report the space used per table",
+	Run: func(cmd *cobra.Command, args []string) {
+		withIndex(func(repository *store.LevelRepository, dataFolder string) {
+			first, last, err := repository.HeightRange()
+			switch err {
+			case nil:
+				fmt.Printf("blocks %d - %d\n", first, last)
+			case store.ErrNotFound:
+				fmt.Println("no blocks")
+			default:
+				logrus.WithError(err).Fatal("failed to read index")
+			}
+			stats, err := repository.Stats()
+			if err != nil {
+				logrus.WithError(err).Fatal("failed to read index")
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', tabwriter.AlignRight)
+			fmt.Fprintln(w, "table\tkeys\tbytes\tdisk bytes\t")
+			var keys, size, disk uint64
+			for _, table := range stats {
+				fmt.Fprintf(w, "%s\t%d\t%d\t%d\t\n", table.Name, table.Keys, table.Bytes, table.DiskBytes)
+				keys += table.Keys
+				size += table.Bytes
+				disk += table.DiskBytes
+			}
+			fmt.Fprintf(w, "total\t%d\t%d\t%d\t\n", keys, size, disk)
+			_ = w.Flush()
+		})
+	},
+}
+
+var dbVacuumCmd = &cobra.Command{
+	Use:   "vacuum",
+	Short: "Compact the index to reclaim the space of deleted entries",
+	Run: func(cmd *cobra.Command, args []string) {
+		withIndex(func(repository *store.LevelRepository, dataFolder string) {
+			if err := repository.Compact(); err != nil {
+				logrus.WithError(err).Fatal("failed to compact index")
+			}
+		})
+	},
+}
+
+// withIndex opens the index of the data folder for f
+func withIndex(f func(repository *store.LevelRepository, dataFolder string)) {
+	folderConfigs := ensureFolders()
+	readConfig(folderConfigs.Config)
+	readPrivate(folderConfigs.Private)
+	repository := core.NewRepository(folderConfigs.Data)
+	defer repository.Stop()
+	f(repository, folderConfigs.Data)
+}
+
+func init() {
+	dbCmd.AddCommand(dbPruneCmd, dbStatsCmd, dbVacuumCmd)
+	rootCmd.AddCommand(dbCmd)
+}
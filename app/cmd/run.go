@@ -46,7 +46,8 @@ var runCmd = &cobra.Command{
 		// init logs and other facilities before the node starts
 
 		node := &core.Node{
-			DataFolder: folderConfigs.Data,
+			DataFolder:    folderConfigs.Data,
+			PrivateFolder: folderConfigs.Private,
 		}
 		node.Setup()
 		node.Start()
@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/latifrons/etherxray/metrics"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Guard authenticates the API keys and rate limits the clients.
+// Requests with a key are limited per key, anonymous requests per IP, except on the Unix socket where peers have no IP. Each route costs Costs[route] tokens, 1 by default.
+type Guard struct {
+	// Required rejects the requests without a key
+	Required bool
+	// AnonymousScopes are granted to the requests without a key, read only by default
+	AnonymousScopes []string
+	Keys            []*Key
+	// TrustedProxies are the networks whose X-Forwarded-For header is believed
+	TrustedProxies []*net.IPNet
+	// KeyRate and IpRate are in tokens per second, zero disables the limit
+	KeyRate  float64
+	KeyBurst float64
+	IpRate   float64
+	IpBurst  float64
+	Costs    map[string]float64
+
+	keyring   *keyring
+	anonymous *Key
+	keys      *Limiter
+	ips       *Limiter
+}
+
+func (g *Guard) InitDefault() {
+	g.keyring = newKeyring(g.Keys)
+	g.anonymous = &Key{
+		Name:   "anonymous",
+		Scopes: g.AnonymousScopes,
+	}
+	g.keys = &Limiter{Rate: g.KeyRate, Burst: g.KeyBurst}
+	g.keys.InitDefault()
+	g.ips = &Limiter{Rate: g.IpRate, Burst: g.IpBurst}
+	g.ips.InitDefault()
+}
+
+// Admit checks the request to the route, and spends its cost.
+// It returns the status to answer with when the request is rejected.
+func (g *Guard) Admit(c *gin.Context, route string, scope string) (status int, err error) {
+	var key *Key
+	if secret := apiKey(c); secret != "" {
+		key = g.keyring.find(secret)
+		if key == nil {
+			return g.reject(route, "bad_key", http.StatusUnauthorized, errors.New("unknown api key"))
+		}
+		if !key.HasScope(scope) {
+			return g.reject(route, "scope", http.StatusForbidden, fmt.Errorf("api key lacks the %s scope", scope))
+		}
+	} else if g.Required {
+		return g.reject(route, "no_key", http.StatusUnauthorized, errors.New("api key required"))
+	} else if !g.AllowsAnonymous(scope) {
+		return g.reject(route, "scope", http.StatusUnauthorized, fmt.Errorf("the %s scope requires an api key", scope))
+	}
+
+	limiter, client, rate, burst := g.ips, g.clientIp(c.Request), 0.0, 0.0
+	if key != nil {
+		limiter, client, rate, burst = g.keys, key.Name, key.Rate, key.Burst
+	} else if isLocal(c.Request) {
+		return
+	}
+	if limiter.Rate <= 0 && rate <= 0 {
+		return
+	}
+	ok, remaining, wait := limiter.Take(client, g.cost(route), rate, burst)
+	c.Header("X-RateLimit-Remaining", fmt.Sprintf("%d", int64(remaining)))
+	if !ok {
+		c.Header("Retry-After", fmt.Sprintf("%d", int64(math.Ceil(wait.Seconds()))))
+		return g.reject(route, "rate", http.StatusTooManyRequests, errors.New("rate limit exceeded"))
+	}
+	return
+}
+
+type localKey struct{}
+
+// LocalConn marks the requests of a connection accepted on a Unix socket. Meant for http.Server.ConnContext.
+func LocalConn(ctx context.Context, _ net.Conn) context.Context {
+	return context.WithValue(ctx, localKey{}, true)
+}
+
+func isLocal(req *http.Request) bool {
+	local, _ := req.Context().Value(localKey{}).(bool)
+	return local
+}
+
+// AllowsAnonymous tells whether requests without a key may use the scope
+func (g *Guard) AllowsAnonymous(scope string) bool {
+	return !g.Required && g.anonymous.HasScope(scope)
+}
+
+func (g *Guard) reject(route string, reason string, status int, err error) (int, error) {
+	metrics.Rejected(route, reason)
+	return status, err
+}
+
+func (g *Guard) cost(route string) float64 {
+	if cost, ok := g.Costs[route]; ok && cost > 0 {
+		return cost
+	}
+	return 1
+}
+
+// clientIp is the address of the peer, or the last address it forwarded for when the peer is a trusted proxy
+func (g *Guard) clientIp(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	if !g.trusted(host) {
+		return host
+	}
+	hops := strings.Split(req.Header.Get("X-Forwarded-For"), ",")
+	// the proxies append to the header, walk back to the first hop not added by a trusted proxy
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			break
+		}
+		if !g.trusted(hop) {
+			return hop
+		}
+		host = hop
+	}
+	return host
+}
+
+func (g *Guard) trusted(host string) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, network := range g.TrustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// apiKey reads the key from the X-Api-Key header or a bearer token.
+// Query parameters are not accepted, they end up in the access logs.
+func apiKey(c *gin.Context) string {
+	if key := c.GetHeader("X-Api-Key"); key != "" {
+		return key
+	}
+	if authorization := c.GetHeader("Authorization"); strings.HasPrefix(authorization, "Bearer ") {
+		return strings.TrimPrefix(authorization, "Bearer ")
+	}
+	return ""
+}
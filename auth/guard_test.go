@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"github.com/gin-gonic/gin"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLimiterTake(t *testing.T) {
+	l := &Limiter{Rate: 1, Burst: 3}
+	l.InitDefault()
+	for i := 0; i < 3; i++ {
+		if ok, _, _ := l.Take("a", 1, 0, 0); !ok {
+			t.Fatalf("call %d should fit in the burst", i)
+		}
+	}
+	ok, remaining, wait := l.Take("a", 1, 0, 0)
+	if ok {
+		t.Fatal("empty bucket admitted a call")
+	}
+	if remaining >= 1 || wait <= 0 || wait > time.Second {
+		t.Fatalf("remaining %v wait %v", remaining, wait)
+	}
+	if ok, _, _ := l.Take("b", 1, 0, 0); !ok {
+		t.Fatal("clients must not share buckets")
+	}
+	// a cost above the burst is capped, so a full bucket still admits it
+	if ok, _, _ := l.Take("c", 10, 0, 0); !ok {
+		t.Fatal("cost above the burst was not capped")
+	}
+	// per key overrides
+	if ok, _, _ := l.Take("d", 5, 5, 5); !ok {
+		t.Fatal("overridden burst not applied")
+	}
+}
+
+func TestBucketRefill(t *testing.T) {
+	now := time.Now()
+	b := &bucket{rate: 2, burst: 4, tokens: 0, last: now}
+	b.refill(now.Add(time.Second))
+	if b.tokens != 2 {
+		t.Fatalf("tokens %v after one second at 2/s", b.tokens)
+	}
+	b.refill(now.Add(time.Hour))
+	if b.tokens != 4 {
+		t.Fatalf("tokens %v above the burst", b.tokens)
+	}
+}
+
+func TestGuardAdmit(t *testing.T) {
+	_, proxy, _ := net.ParseCIDR("10.0.0.1/32")
+	g := &Guard{
+		Keys: []*Key{
+			{Name: "reader", Secret: "r"},
+			{Name: "tracer", Secret: "t", Scopes: []string{ScopeRead, ScopeTrace}},
+		},
+		TrustedProxies: []*net.IPNet{proxy},
+		KeyRate:        1,
+		KeyBurst:       2,
+		IpRate:         1,
+		IpBurst:        1,
+		Costs:          map[string]float64{"trace": 2},
+	}
+	g.InitDefault()
+
+	tests := []struct {
+		name   string
+		route  string
+		scope  string
+		key    string
+		remote string
+		xff    string
+		status int
+	}{
+		{name: "anonymous read", route: "block", scope: ScopeRead, remote: "1.1.1.1:1", status: 0},
+		{name: "anonymous ip limited", route: "block", scope: ScopeRead, remote: "1.1.1.1:2", status: http.StatusTooManyRequests},
+		{name: "spoofed forwarded ignored", route: "block", scope: ScopeRead, remote: "1.1.1.1:3", xff: "9.9.9.9", status: http.StatusTooManyRequests},
+		{name: "trusted proxy forwards", route: "block", scope: ScopeRead, remote: "10.0.0.1:1", xff: "2.2.2.2", status: 0},
+		{name: "anonymous trace", route: "trace", scope: ScopeTrace, remote: "3.3.3.3:1", status: http.StatusUnauthorized},
+		{name: "anonymous watch", route: "watchlist", scope: ScopeWatch, remote: "3.3.3.3:1", status: http.StatusUnauthorized},
+		{name: "unknown key", route: "block", scope: ScopeRead, key: "x", remote: "4.4.4.4:1", status: http.StatusUnauthorized},
+		{name: "key without scope", route: "trace", scope: ScopeTrace, key: "r", remote: "4.4.4.4:1", status: http.StatusForbidden},
+		{name: "key with scope", route: "trace", scope: ScopeTrace, key: "t", remote: "4.4.4.4:1", status: 0},
+		{name: "key limited by cost", route: "block", scope: ScopeRead, key: "t", remote: "5.5.5.5:1", status: http.StatusTooManyRequests},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		c.Request.RemoteAddr = test.remote
+		if test.key != "" {
+			c.Request.Header.Set("X-Api-Key", test.key)
+		}
+		if test.xff != "" {
+			c.Request.Header.Set("X-Forwarded-For", test.xff)
+		}
+		status, err := g.Admit(c, test.route, test.scope)
+		if status != test.status {
+			t.Errorf("%s: status %d, want %d (%v)", test.name, status, test.status, err)
+		}
+		if (err != nil) != (test.status != 0) {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+		}
+	}
+}
+
+func TestApiKeyFromQueryIgnored(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/?api_key=secret", nil)
+	if key := apiKey(c); key != "" {
+		t.Fatalf("key %q read from the query", key)
+	}
+	c.Request.Header.Set("Authorization", "Bearer secret")
+	if key := apiKey(c); key != "secret" {
+		t.Fatalf("bearer key %q", key)
+	}
+}
@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+)
+
+// Scopes granted to API keys
+const (
+	// ScopeRead covers the block, transaction, address, token, pool and price queries
+	ScopeRead = "read"
+	// ScopeTrace covers the traces and state diffs, replayed by the node on every call
+	ScopeTrace = "trace"
+	// ScopeWatch covers the changes to the watchlist
+	ScopeWatch = "watch"
+)
+
+// Key is an API key and what it may do. A key without scopes may only read.
+type Key struct {
+	Name   string
+	Secret string
+	Scopes []string
+	// Rate and Burst override the per key limits when set
+	Rate  float64
+	Burst float64
+}
+
+func (k *Key) HasScope(scope string) bool {
+	if len(k.Scopes) == 0 {
+		return scope == ScopeRead
+	}
+	for _, s := range k.Scopes {
+		if s == scope || s == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// keyring finds keys by secret without leaking their content through timing
+type keyring struct {
+	keys map[[sha256.Size]byte]*Key
+}
+
+func newKeyring(keys []*Key) *keyring {
+	r := &keyring{keys: make(map[[sha256.Size]byte]*Key)}
+	for _, key := range keys {
+		r.keys[sha256.Sum256([]byte(key.Secret))] = key
+	}
+	return r
+}
+
+func (r *keyring) find(secret string) *Key {
+	digest := sha256.Sum256([]byte(secret))
+	key, ok := r.keys[digest]
+	if !ok {
+		return nil
+	}
+	if subtle.ConstantTimeCompare([]byte(key.Secret), []byte(secret)) != 1 {
+		return nil
+	}
+	return key
+}
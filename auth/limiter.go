@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// idleBuckets is how often full buckets are dropped
+const idleBuckets = time.Minute * 10
+
+// bucket is a token bucket refilled continuously at rate tokens per second, up to burst
+type bucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func (b *bucket) refill(now time.Time) {
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+}
+
+// Limiter holds one token bucket per client
+type Limiter struct {
+	Rate  float64
+	Burst float64
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+func (l *Limiter) InitDefault() {
+	l.buckets = make(map[string]*bucket)
+	if l.Burst < l.Rate {
+		l.Burst = l.Rate
+	}
+}
+
+// Take spends cost tokens of the client. When the bucket runs short, it returns how long to wait instead.
+// A cost larger than the burst is capped so that expensive calls remain possible.
+// rate and burst override the limiter settings when positive.
+func (l *Limiter) Take(client string, cost float64, rate float64, burst float64) (ok bool, remaining float64, wait time.Duration) {
+	if rate <= 0 {
+		rate = l.Rate
+	}
+	if burst <= 0 {
+		burst = l.Burst
+	}
+	if burst < rate {
+		burst = rate
+	}
+	if cost > burst {
+		cost = burst
+	}
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+	b, found := l.buckets[client]
+	if !found {
+		b = &bucket{tokens: burst, last: now}
+		l.buckets[client] = b
+	}
+	b.rate = rate
+	b.burst = burst
+	b.refill(now)
+	if b.tokens < cost {
+		wait = time.Duration((cost - b.tokens) / rate * float64(time.Second))
+		return false, b.tokens, wait
+	}
+	b.tokens -= cost
+	return true, b.tokens, 0
+}
+
+// sweep drops the buckets refilled to their burst, they are recreated full. The lock must be held.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < idleBuckets {
+		return
+	}
+	l.swept = now
+	for client, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*b.rate >= b.burst {
+			delete(l.buckets, client)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package classifier
+
+import (
+	"encoding/hex"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/latifrons/etherxray/decoder"
+	"github.com/latifrons/etherxray/model"
+)
+
+// Classifier tags transactions with categories derived from the called selector,
+// the emitted events and the internal value transfers
+type Classifier struct {
+	methods map[string]string
+	events  map[common.Hash][]eventRule
+}
+
+func (c *Classifier) InitDefault() {
+	c.methods = make(map[string]string)
+	c.events = make(map[common.Hash][]eventRule)
+	for sig, category := range methodRules {
+		c.methods[hex.EncodeToString(decoder.Selector(sig))] = category
+	}
+	for _, rule := range eventRules {
+		topic := decoder.Topic(rule.Signature)
+		c.events[topic] = append(c.events[topic], rule)
+	}
+}
+
+// Classify returns the categories of the transaction, in a stable order
+func (c *Classifier) Classify(tx *model.Tx) (tags []string) {
+	found := make(map[string]bool)
+
+	data := tx.BasicTx.Data()
+	if tx.BasicTx.To() == nil {
+		found[model.TxTypeDeployment] = true
+	} else if len(data) == 0 {
+		found[model.TxTypeTransfer] = true
+	} else if len(data) >= 4 {
+		if category, ok := c.methods[hex.EncodeToString(data[:4])]; ok {
+			found[category] = true
+		}
+	}
+
+	if tx.Receipt != nil {
+		for _, log := range tx.Receipt.Logs {
+			if len(log.Topics) == 0 {
+				continue
+			}
+			for _, rule := range c.events[log.Topics[0]] {
+				if rule.Topics == 0 || rule.Topics == len(log.Topics) {
+					found[rule.Category] = true
+				}
+			}
+		}
+	}
+
+	// a contract call that only moves ether around is a transfer
+	if len(found) == 0 && len(tx.InternalTransfers) > 0 {
+		found[model.TxTypeTransfer] = true
+	}
+
+	for _, category := range categoryOrder {
+		if found[category] {
+			tags = append(tags, category)
+		}
+	}
+	if len(tags) == 0 {
+		tags = append(tags, model.TxTypeUnknown)
+	}
+	return
+}
+
+var categoryOrder = []string{
+	model.TxTypeTransfer,
+	model.TxTypeDeployment,
+	model.TxTypeErc20Transfer,
+	model.TxTypeErc20Approve,
+	model.TxTypeNftTransfer,
+	model.TxTypeSwap,
+	model.TxTypeLiquidityAdd,
+	model.TxTypeLiquidityRemove,
+	model.TxTypeBridge,
+	model.TxTypeLending,
+	model.TxTypeMultisig,
+}
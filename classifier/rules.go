@@ -0,0 +1,119 @@
+package classifier
+
+import "github.com/latifrons/etherxray/model"
+
+// methodRules maps function signatures called at top level to a category
+var methodRules = map[string]string{
+	"transfer(address,uint256)":                                     model.TxTypeErc20Transfer,
+	"transferFrom(address,address,uint256)":                         model.TxTypeErc20Transfer,
+	"approve(address,uint256)":                                      model.TxTypeErc20Approve,
+	"increaseAllowance(address,uint256)":                            model.TxTypeErc20Approve,
+	"permit(address,address,uint256,uint256,uint8,bytes32,bytes32)": model.TxTypeErc20Approve,
+
+	"safeTransferFrom(address,address,uint256)":                        model.TxTypeNftTransfer,
+	"safeTransferFrom(address,address,uint256,bytes)":                  model.TxTypeNftTransfer,
+	"safeTransferFrom(address,address,uint256,uint256,bytes)":          model.TxTypeNftTransfer,
+	"safeBatchTransferFrom(address,address,uint256[],uint256[],bytes)": model.TxTypeNftTransfer,
+
+	"swapExactTokensForTokens(uint256,uint256,address[],address,uint256)":                              model.TxTypeSwap,
+	"swapTokensForExactTokens(uint256,uint256,address[],address,uint256)":                              model.TxTypeSwap,
+	"swapExactETHForTokens(uint256,address[],address,uint256)":                                         model.TxTypeSwap,
+	"swapTokensForExactETH(uint256,uint256,address[],address,uint256)":                                 model.TxTypeSwap,
+	"swapExactTokensForETH(uint256,uint256,address[],address,uint256)":                                 model.TxTypeSwap,
+	"swapETHForExactTokens(uint256,address[],address,uint256)":                                         model.TxTypeSwap,
+	"swapExactTokensForTokensSupportingFeeOnTransferTokens(uint256,uint256,address[],address,uint256)": model.TxTypeSwap,
+	"swapExactETHForTokensSupportingFeeOnTransferTokens(uint256,address[],address,uint256)":            model.TxTypeSwap,
+	"swapExactTokensForETHSupportingFeeOnTransferTokens(uint256,uint256,address[],address,uint256)":    model.TxTypeSwap,
+	"exactInput((bytes,address,uint256,uint256,uint256))":                                              model.TxTypeSwap,
+	"exactOutput((bytes,address,uint256,uint256,uint256))":                                             model.TxTypeSwap,
+	"execute(bytes,bytes[],uint256)":                                                                   model.TxTypeSwap,
+	"execute(bytes,bytes[])":                                                                           model.TxTypeSwap,
+
+	"addLiquidity(address,address,uint256,uint256,uint256,uint256,address,uint256)":                    model.TxTypeLiquidityAdd,
+	"addLiquidityETH(address,uint256,uint256,uint256,address,uint256)":                                 model.TxTypeLiquidityAdd,
+	"removeLiquidity(address,address,uint256,uint256,uint256,address,uint256)":                         model.TxTypeLiquidityRemove,
+	"removeLiquidityETH(address,uint256,uint256,uint256,address,uint256)":                              model.TxTypeLiquidityRemove,
+	"removeLiquidityETHSupportingFeeOnTransferTokens(address,uint256,uint256,uint256,address,uint256)": model.TxTypeLiquidityRemove,
+
+	"depositETH(uint32,bytes)":                                        model.TxTypeBridge,
+	"depositETHTo(address,uint32,bytes)":                              model.TxTypeBridge,
+	"depositERC20(address,address,uint256,uint32,bytes)":              model.TxTypeBridge,
+	"depositERC20To(address,address,address,uint256,uint32,bytes)":    model.TxTypeBridge,
+	"bridgeETHTo(address,uint32,bytes)":                               model.TxTypeBridge,
+	"bridgeERC20To(address,address,address,uint256,uint32,bytes)":     model.TxTypeBridge,
+	"outboundTransfer(address,address,uint256,uint256,uint256,bytes)": model.TxTypeBridge,
+	"depositEth()": model.TxTypeBridge,
+	"sendToL2(uint256,address,uint256,uint256,uint256,address,uint256)": model.TxTypeBridge,
+
+	"liquidationCall(address,address,address,uint256,bool)": model.TxTypeLending,
+	"liquidateBorrow(address,uint256,address)":              model.TxTypeLending,
+	"deposit(address,uint256,address,uint16)":               model.TxTypeLending,
+	"supply(address,uint256,address,uint16)":                model.TxTypeLending,
+	"withdraw(address,uint256,address)":                     model.TxTypeLending,
+	"borrow(address,uint256,uint256,uint16,address)":        model.TxTypeLending,
+	"repay(address,uint256,uint256,address)":                model.TxTypeLending,
+	"mint(uint256)":                                         model.TxTypeLending,
+	"redeem(uint256)":                                       model.TxTypeLending,
+	"redeemUnderlying(uint256)":                             model.TxTypeLending,
+	"borrow(uint256)":                                       model.TxTypeLending,
+	"repayBorrow(uint256)":                                  model.TxTypeLending,
+
+	"execTransaction(address,uint256,bytes,uint8,uint256,uint256,uint256,address,address,bytes)": model.TxTypeMultisig,
+}
+
+type eventRule struct {
+	Signature string
+	// Topics is the number of topics the log must have, 0 for any.
+	// It tells apart events sharing a signature, like ERC-20 and ERC-721 Transfer.
+	Topics   int
+	Category string
+}
+
+var eventRules = []eventRule{
+	{"Transfer(address,address,uint256)", 3, model.TxTypeErc20Transfer},
+	{"Approval(address,address,uint256)", 3, model.TxTypeErc20Approve},
+	{"Transfer(address,address,uint256)", 4, model.TxTypeNftTransfer},
+	{"TransferSingle(address,address,address,uint256,uint256)", 0, model.TxTypeNftTransfer},
+	{"TransferBatch(address,address,address,uint256[],uint256[])", 0, model.TxTypeNftTransfer},
+
+	// Uniswap V2 pair
+	{"Swap(address,uint256,uint256,uint256,uint256,address)", 0, model.TxTypeSwap},
+	{"Mint(address,uint256,uint256)", 2, model.TxTypeLiquidityAdd},
+	{"Burn(address,uint256,uint256,address)", 0, model.TxTypeLiquidityRemove},
+	// Uniswap V3 pool
+	{"Swap(address,address,int256,int256,uint160,uint128,int24)", 0, model.TxTypeSwap},
+	{"Mint(address,address,int24,int24,uint128,uint256,uint256)", 0, model.TxTypeLiquidityAdd},
+	{"Burn(address,int24,int24,uint128,uint256,uint256)", 0, model.TxTypeLiquidityRemove},
+	// Balancer
+	{"LOG_SWAP(address,address,address,uint256,uint256)", 0, model.TxTypeSwap},
+	{"LOG_JOIN(address,address,uint256)", 0, model.TxTypeLiquidityAdd},
+	{"LOG_EXIT(address,address,uint256)", 0, model.TxTypeLiquidityRemove},
+
+	// bridges
+	{"TransactionDeposited(address,address,uint256,bytes)", 0, model.TxTypeBridge},
+	{"SentMessage(address,address,bytes,uint256,uint256)", 0, model.TxTypeBridge},
+	{"MessageDelivered(uint256,bytes32,address,uint8,address,bytes32,uint256,uint64)", 0, model.TxTypeBridge},
+	{"DepositInitiated(address,address,address,uint256,uint256)", 0, model.TxTypeBridge},
+	{"ETHDepositInitiated(address,address,uint256,bytes)", 0, model.TxTypeBridge},
+	{"ERC20DepositInitiated(address,address,address,address,uint256,bytes)", 0, model.TxTypeBridge},
+
+	// Aave
+	{"LiquidationCall(address,address,address,uint256,uint256,address,bool)", 0, model.TxTypeLending},
+	{"Deposit(address,address,address,uint256,uint16)", 0, model.TxTypeLending},
+	{"Supply(address,address,address,uint256,uint16)", 0, model.TxTypeLending},
+	{"Withdraw(address,address,address,uint256)", 0, model.TxTypeLending},
+	{"Borrow(address,address,address,uint256,uint256,uint256,uint16)", 0, model.TxTypeLending},
+	{"Borrow(address,address,address,uint256,uint8,uint256,uint16)", 0, model.TxTypeLending},
+	{"Repay(address,address,address,uint256)", 0, model.TxTypeLending},
+	{"Repay(address,address,address,uint256,bool)", 0, model.TxTypeLending},
+	// Compound
+	{"LiquidateBorrow(address,address,uint256,address,uint256)", 0, model.TxTypeLending},
+	{"Mint(address,uint256,uint256)", 1, model.TxTypeLending},
+	{"Redeem(address,uint256,uint256)", 0, model.TxTypeLending},
+	{"Borrow(address,uint256,uint256,uint256)", 0, model.TxTypeLending},
+	{"RepayBorrow(address,address,uint256,uint256,uint256)", 0, model.TxTypeLending},
+
+	// Gnosis safe
+	{"ExecutionSuccess(bytes32,uint256)", 0, model.TxTypeMultisig},
+	{"ExecutionFailure(bytes32,uint256)", 0, model.TxTypeMultisig},
+}
@@ -0,0 +1,276 @@
+// Package client calls the etherxray HTTP API. The methods mirror the routes documented in /openapi.json.
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/latifrons/etherxray/rpc"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Error is an answer other than 200 OK
+type Error struct {
+	Status int
+	Body   string
+	// RetryAfter is set when the call was rate limited
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("etherxray: %d %s", e.Status, http.StatusText(e.Status))
+}
+
+// Client calls the API at BaseUrl, such as http://127.0.0.1:9999
+type Client struct {
+	BaseUrl string
+	// ApiKey is sent in the X-Api-Key header when set
+	ApiKey     string
+	HttpClient *http.Client
+}
+
+func (c *Client) InitDefault() {
+	c.BaseUrl = strings.TrimSuffix(c.BaseUrl, "/")
+	if c.HttpClient == nil {
+		c.HttpClient = &http.Client{Timeout: time.Minute}
+	}
+}
+
+// Range selects the blocks of a query. Nil bounds take the route defaults: To is the head, From a span before To.
+type Range struct {
+	From *uint64
+	To   *uint64
+	// Limit caps the records of the index queries, zero for the route default
+	Limit int
+}
+
+// Height is a bound of a Range, such as Range{From: client.Height(0)} from genesis
+func Height(height uint64) *uint64 {
+	return &height
+}
+
+func (r Range) query() url.Values {
+	q := url.Values{}
+	if r.From != nil {
+		q.Set("from", strconv.FormatUint(*r.From, 10))
+	}
+	if r.To != nil {
+		q.Set("to", strconv.FormatUint(*r.To, 10))
+	}
+	if r.Limit != 0 {
+		q.Set("limit", strconv.Itoa(r.Limit))
+	}
+	return q
+}
+
+// atHeight queries the state at the height, the head when zero
+func atHeight(height uint64) url.Values {
+	q := url.Values{}
+	if height != 0 {
+		q.Set("height", strconv.FormatUint(height, 10))
+	}
+	return q
+}
+
+func (c *Client) Health() (health rpc.RpcHealth, err error) {
+	err = c.get("/health", nil, &health)
+	return
+}
+
+// Ready fails with a 503 Error when the node is not ready, health still tells why
+func (c *Client) Ready() (health rpc.RpcHealth, err error) {
+	err = c.get("/ready", nil, &health)
+	return
+}
+
+// Block returns the transactions of the block. A non empty category keeps the transactions with this tag or MEV role.
+func (c *Client) Block(height uint64, category string) (txs []rpc.RpcTx, err error) {
+	q := url.Values{}
+	if category != "" {
+		q.Set("type", category)
+	}
+	err = c.get(fmt.Sprintf("/block/%d", height), q, &txs)
+	return
+}
+
+func (c *Client) TxTrace(hash common.Hash) (frame *rpc.RpcCallFrame, err error) {
+	err = c.get("/tx/"+hash.Hex()+"/trace", nil, &frame)
+	return
+}
+
+func (c *Client) TxStateDiff(hash common.Hash) (changes []rpc.RpcAccountChange, err error) {
+	err = c.get("/tx/"+hash.Hex()+"/statediff", nil, &changes)
+	return
+}
+
+func (c *Client) Address(address common.Address, r Range) (activity rpc.RpcAddressActivity, err error) {
+	err = c.get("/address/"+address.Hex(), r.query(), &activity)
+	return
+}
+
+func (c *Client) NftHistory(contract common.Address, id *big.Int, r Range) (history rpc.RpcNftHistory, err error) {
+	err = c.get("/nft/"+contract.Hex()+"/"+id.String(), r.query(), &history)
+	return
+}
+
+func (c *Client) TokenPools(token common.Address) (pools []rpc.RpcPair, err error) {
+	err = c.get("/token/"+token.Hex()+"/pools", nil, &pools)
+	return
+}
+
+func (c *Client) TokenTxs(token common.Address, r Range) (txs []rpc.RpcTx, err error) {
+	err = c.get("/token/"+token.Hex()+"/txs", r.query(), &txs)
+	return
+}
+
+func (c *Client) SelectorTxs(selector [4]byte, r Range) (txs []rpc.RpcTx, err error) {
+	err = c.get("/selector/"+hexutil.Encode(selector[:])+"/txs", r.query(), &txs)
+	return
+}
+
+// Logs queries the indexed logs of the emitter and/or topic0, zero values do not filter
+func (c *Client) Logs(address common.Address, topic0 common.Hash, r Range) (logs []rpc.RpcEventLog, err error) {
+	q := r.query()
+	if address != (common.Address{}) {
+		q.Set("address", address.Hex())
+	}
+	if topic0 != (common.Hash{}) {
+		q.Set("topic0", topic0.Hex())
+	}
+	err = c.get("/logs", q, &logs)
+	return
+}
+
+// PoolHistory samples the pair every step blocks, or at every Sync event when mode is rpc.PoolHistoryModeSync
+func (c *Client) PoolHistory(pool common.Address, r Range, mode string, step uint64) (history rpc.RpcPoolHistory, err error) {
+	q := r.query()
+	if mode != "" {
+		q.Set("mode", mode)
+	}
+	if step != 0 {
+		q.Set("step", strconv.FormatUint(step, 10))
+	}
+	err = c.get("/pool/"+pool.Hex()+"/history", q, &history)
+	return
+}
+
+func (c *Client) PoolV3(pool common.Address, height uint64) (state rpc.RpcV3Pool, err error) {
+	err = c.get("/pool/"+pool.Hex()+"/v3", atHeight(height), &state)
+	return
+}
+
+func (c *Client) BalancerPool(pool common.Address, height uint64) (state rpc.RpcBalancerPool, err error) {
+	err = c.get("/pool/balancer/"+pool.Hex(), atHeight(height), &state)
+	return
+}
+
+// PriceOracles override the oracles configured on the server, zero addresses keep them
+type PriceOracles struct {
+	Weth       common.Address
+	Stablecoin common.Address
+	EthUsdPool common.Address
+}
+
+func (c *Client) TokenPrice(token common.Address, height uint64, oracles PriceOracles) (price rpc.RpcTokenPrice, err error) {
+	q := atHeight(height)
+	for name, address := range map[string]common.Address{
+		"weth":         oracles.Weth,
+		"stablecoin":   oracles.Stablecoin,
+		"eth_usd_pool": oracles.EthUsdPool,
+	} {
+		if address != (common.Address{}) {
+			q.Set(name, address.Hex())
+		}
+	}
+	err = c.get("/price/"+token.Hex(), q, &price)
+	return
+}
+
+func (c *Client) BlockMev(height uint64) (mev rpc.RpcBlockMev, err error) {
+	err = c.get(fmt.Sprintf("/mev/block/%d", height), nil, &mev)
+	return
+}
+
+func (c *Client) IndexerStatus() (status rpc.RpcIndexerStatus, err error) {
+	err = c.get("/status/indexer", nil, &status)
+	return
+}
+
+func (c *Client) Watches() (watches []rpc.RpcWatch, err error) {
+	err = c.get("/watchlist", nil, &watches)
+	return
+}
+
+func (c *Client) AddWatch(address common.Address, label string) (watch rpc.RpcWatch, err error) {
+	err = c.do(http.MethodPost, "/watchlist", nil, rpc.RpcWatchRequest{Address: address.Hex(), Label: label}, &watch)
+	return
+}
+
+func (c *Client) RemoveWatch(address common.Address) (err error) {
+	var ok string
+	err = c.do(http.MethodDelete, "/watchlist/"+address.Hex(), nil, nil, &ok)
+	return
+}
+
+// OpenApi returns the OpenAPI document of the server
+func (c *Client) OpenApi() (doc map[string]interface{}, err error) {
+	err = c.get("/openapi.json", nil, &doc)
+	return
+}
+
+func (c *Client) get(path string, query url.Values, result interface{}) error {
+	return c.do(http.MethodGet, path, query, nil, result)
+}
+
+func (c *Client) do(method string, path string, query url.Values, body interface{}, result interface{}) (err error) {
+	target := c.BaseUrl + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	var payload []byte
+	if body != nil {
+		if payload, err = json.Marshal(body); err != nil {
+			return
+		}
+	}
+	req, err := http.NewRequest(method, target, bytes.NewReader(payload))
+	if err != nil {
+		return
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.ApiKey != "" {
+		req.Header.Set("X-Api-Key", c.ApiKey)
+	}
+	resp, err := c.HttpClient.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return
+	}
+	if resp.StatusCode != http.StatusOK {
+		apiErr := &Error{
+			Status: resp.StatusCode,
+			Body:   string(content),
+		}
+		if seconds, er := strconv.Atoi(resp.Header.Get("Retry-After")); er == nil {
+			apiErr.RetryAfter = time.Duration(seconds) * time.Second
+		}
+		// health and readiness describe the failure in the body
+		_ = json.Unmarshal(content, result)
+		return apiErr
+	}
+	return json.Unmarshal(content, result)
+}
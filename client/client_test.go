@@ -0,0 +1,124 @@
+package client
+
+import (
+	"encoding/json"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/latifrons/etherxray/rpc"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRangeQuery(t *testing.T) {
+	tests := []struct {
+		r     Range
+		query string
+	}{
+		{Range{}, ""},
+		{Range{From: Height(0)}, "from=0"},
+		{Range{From: Height(0), To: Height(100)}, "from=0&to=100"},
+		{Range{To: Height(0)}, "to=0"},
+		{Range{To: Height(5), Limit: 10}, "limit=10&to=5"},
+	}
+	for _, test := range tests {
+		if query := test.r.query().Encode(); query != test.query {
+			t.Errorf("%+v: query %q, want %q", test.r, query, test.query)
+		}
+	}
+}
+
+// testServer answers every request with the handler and records the last request
+func testServer(handler http.HandlerFunc) (*Client, *http.Request, func()) {
+	last := &http.Request{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*last = *r.Clone(r.Context())
+		handler(w, r)
+	}))
+	c := &Client{BaseUrl: server.URL + "/", ApiKey: "secret"}
+	c.InitDefault()
+	return c, last, server.Close
+}
+
+func TestClientGet(t *testing.T) {
+	address := common.HexToAddress("0x00000000000000000000000000000000000000a1")
+	c, last, stop := testServer(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(rpc.RpcAddressActivity{Address: address.Hex(), From: 0, To: 10})
+	})
+	defer stop()
+
+	activity, err := c.Address(address, Range{From: Height(0), To: Height(10)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if activity.Address != address.Hex() || activity.To != 10 {
+		t.Errorf("activity %+v", activity)
+	}
+	if last.URL.Path != "/address/"+address.Hex() {
+		t.Errorf("path %s", last.URL.Path)
+	}
+	if last.URL.RawQuery != "from=0&to=10" {
+		t.Errorf("query %s, want from=0&to=10", last.URL.RawQuery)
+	}
+	if last.Header.Get("X-Api-Key") != "secret" {
+		t.Errorf("api key header %q, want secret", last.Header.Get("X-Api-Key"))
+	}
+	if last.URL.Query().Get("api_key") != "" {
+		t.Error("the api key is sent in the query")
+	}
+}
+
+func TestClientPost(t *testing.T) {
+	address := common.HexToAddress("0x00000000000000000000000000000000000000a1")
+	var body rpc.RpcWatchRequest
+	c, last, stop := testServer(func(w http.ResponseWriter, r *http.Request) {
+		content, _ := ioutil.ReadAll(r.Body)
+		_ = json.Unmarshal(content, &body)
+		_ = json.NewEncoder(w).Encode(rpc.RpcWatch{Address: body.Address, Label: body.Label})
+	})
+	defer stop()
+
+	watch, err := c.AddWatch(address, "treasury")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last.Method != http.MethodPost || last.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("%s with content type %q", last.Method, last.Header.Get("Content-Type"))
+	}
+	if body.Address != address.Hex() || watch.Label != "treasury" {
+		t.Errorf("sent %+v, got %+v", body, watch)
+	}
+}
+
+func TestClientError(t *testing.T) {
+	c, _, stop := testServer(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ready" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_ = json.NewEncoder(w).Encode(rpc.RpcHealth{Reasons: []string{"node is syncing"}})
+			return
+		}
+		w.Header().Set("Retry-After", "3")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_ = json.NewEncoder(w).Encode(rpc.RpcError{Error: "rate limit exceeded"})
+	})
+	defer stop()
+
+	_, err := c.BlockMev(1)
+	apiErr, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("error %v, want an *Error", err)
+	}
+	if apiErr.Status != http.StatusTooManyRequests || apiErr.RetryAfter != 3*time.Second {
+		t.Errorf("status %d retry after %v, want 429 and 3s", apiErr.Status, apiErr.RetryAfter)
+	}
+
+	// readiness explains the failure in the body
+	health, err := c.Ready()
+	if apiErr, ok := err.(*Error); !ok || apiErr.Status != http.StatusServiceUnavailable {
+		t.Errorf("error %v, want a 503", err)
+	}
+	if len(health.Reasons) != 1 {
+		t.Errorf("reasons %v, want the reason of the body", health.Reasons)
+	}
+}
@@ -1,17 +1,38 @@
 package core
 
 import (
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/latifrons/etherxray/alert"
+	"github.com/latifrons/etherxray/auth"
+	"github.com/latifrons/etherxray/classifier"
+	"github.com/latifrons/etherxray/decoder"
+	"github.com/latifrons/etherxray/dex"
 	"github.com/latifrons/etherxray/ethnode"
+	"github.com/latifrons/etherxray/health"
+	"github.com/latifrons/etherxray/indexer"
+	"github.com/latifrons/etherxray/metrics"
+	"github.com/latifrons/etherxray/mev"
 	"github.com/latifrons/etherxray/middleware"
+	"github.com/latifrons/etherxray/notify"
+	"github.com/latifrons/etherxray/pricing"
+	"github.com/latifrons/etherxray/rating"
 	"github.com/latifrons/etherxray/rpc"
+	"github.com/latifrons/etherxray/store"
+	"github.com/latifrons/etherxray/token"
+	"github.com/latifrons/etherxray/watch"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 	"math/big"
+	"net"
+	"path"
+	"strings"
+	"time"
 )
 
 type Node struct {
-	DataFolder string
+	DataFolder    string
+	PrivateFolder string
 
 	components []Component
 }
@@ -22,21 +43,420 @@ func (n *Node) Setup() {
 	rpcWrapper := &middleware.RpcWrapper{
 		RpcAddress: viper.GetString("node.address"),
 	}
+	if viper.GetBool("metrics.enabled") {
+		rpcWrapper.Observer = metrics.ObserveRpc
+	}
+
+	registry := &decoder.Registry{
+		AbiFolder: viper.GetString("decoder.abi_folder"),
+	}
+	registry.InitDefault()
+
+	txClassifier := &classifier.Classifier{}
+	txClassifier.InitDefault()
+
+	rater := n.setupRating()
+
+	tokens := &token.Cache{
+		RpcWrapper: rpcWrapper,
+		File:       path.Join(n.DataFolder, "tokens.json"),
+	}
+	tokens.InitDefault()
+	// stopped last, after every component reading tokens
+	n.components = append(n.components, tokens)
+
+	pairs := &dex.PairStore{
+		File: path.Join(n.DataFolder, "pairs.json"),
+	}
+	pairs.InitDefault()
+
+	pools := &dex.Pools{
+		RpcWrapper: rpcWrapper,
+		Pairs:      pairs,
+	}
+	pools.InitDefault()
+
+	swaps := &dex.SwapDecoder{
+		Pools:  pools,
+		Tokens: tokens,
+	}
+
+	prices := n.setupPricing(rpcWrapper, tokens, pairs)
 
 	ethNode := &ethnode.EthNode{
 		RpcWrapper: rpcWrapper,
 		Signer:     signer,
+		Registry:   registry,
+		Classifier: txClassifier,
+		Rater:      rater,
+		Tokens:     tokens,
+		Swaps:      swaps,
+
+		TraceApi:          viper.GetString("node.trace_api"),
+		InternalTransfers: viper.GetBool("enrich.internal_transfers"),
+		FailureReasons:    viper.GetBool("enrich.failure_reasons"),
+	}
+	if viper.GetBool("pricing.enabled") {
+		ethNode.Pricing = prices
+	}
+	// the watch list also pins the addresses kept by the retention policy
+	var watchlist *watch.Watchlist
+	if viper.GetBool("watch.enabled") || viper.GetBool("store.enabled") && viper.GetBool("store.retention.enabled") {
+		watchlist = NewWatchlist(n.DataFolder)
+	}
+
+	var repository store.Repository
+	if viper.GetBool("store.enabled") {
+		levelRepository := NewRepository(n.DataFolder)
+		// first in, last stopped
+		n.components = append(n.components, levelRepository)
+		repository = levelRepository
+		ethNode.Repository = repository
+		if viper.GetBool("store.retention.enabled") {
+			n.components = append(n.components, NewPruner(repository, watchlist))
+		}
+	}
+	ethNode.Mev = &mev.Analyzer{
+		RpcWrapper: rpcWrapper,
+		Tokens:     tokens,
+		Pricing:    ethNode.Pricing,
 	}
 
+	poolHistory := &dex.PoolHistory{
+		RpcWrapper: rpcWrapper,
+		Tokens:     tokens,
+		Pools:      pools,
+	}
+
+	v3Reader := &dex.V3Reader{
+		Pools:  pools,
+		Tokens: tokens,
+	}
+
+	balancer := &dex.BalancerInspector{
+		RpcWrapper: rpcWrapper,
+		Tokens:     tokens,
+	}
+
+	var blockIndexer *indexer.Indexer
+	if repository != nil && viper.GetBool("indexer.enabled") {
+		blockIndexer = &indexer.Indexer{
+			EthNode:       ethNode,
+			Repository:    repository,
+			From:          viper.GetUint64("indexer.from"),
+			To:            viper.GetUint64("indexer.to"),
+			Confirmations: viper.GetUint64("indexer.confirmations"),
+			Workers:       viper.GetInt("indexer.workers"),
+			MaxReorgDepth: viper.GetUint64("indexer.max_reorg_depth"),
+			Interval:      time.Second * time.Duration(viper.GetInt("indexer.interval_seconds")),
+		}
+		blockIndexer.InitDefault()
+		if viper.GetBool("metrics.enabled") {
+			metrics.WatchIndexer(blockIndexer.Status)
+		}
+	}
+
+	nodeHealth := &health.Checker{
+		RpcWrapper: rpcWrapper,
+		MaxHeadAge: time.Second * time.Duration(viper.GetInt("health.max_head_age_seconds")),
+		MaxSyncLag: viper.GetUint64("health.max_sync_lag_blocks"),
+		CacheFor:   time.Second * time.Duration(viper.GetInt("health.cache_seconds")),
+	}
+	if blockIndexer != nil {
+		nodeHealth.Indexer = blockIndexer.Status
+		nodeHealth.MaxIndexerLag = viper.GetUint64("health.max_indexer_lag_blocks")
+	}
+	nodeHealth.InitDefault()
+
 	rpcServer := &rpc.RpcServer{
 		C: &rpc.RpcController{
-			EthNode: ethNode,
+			EthNode:  ethNode,
+			Pairs:    pairs,
+			History:  poolHistory,
+			V3:       v3Reader,
+			Balancer: balancer,
+			Pricing:  prices,
+			Index:    repository,
+			Indexer:  blockIndexer,
+
+			Watchlist:  watchlist,
+			NodeHealth: nodeHealth,
+			Metrics:    viper.GetBool("metrics.enabled"),
+
+			Guard:           n.setupGuard(),
+			CorsOrigins:     viper.GetStringSlice("rpc.cors.origins"),
+			CorsCredentials: viper.GetBool("rpc.cors.credentials"),
 		},
-		Port: viper.GetString("rpc.port"),
+		Port:      viper.GetString("rpc.port"),
+		AdminPort: viper.GetString("rpc.admin_port"),
+	}
+	if socket := viper.GetString("rpc.socket"); socket != "" {
+		rpcServer.Socket = inFolder(n.DataFolder, socket)
+	}
+	if viper.GetBool("rpc.tls.enabled") {
+		rpcServer.CertFile = inFolder(n.PrivateFolder, viper.GetString("rpc.tls.cert_file"))
+		rpcServer.KeyFile = inFolder(n.PrivateFolder, viper.GetString("rpc.tls.key_file"))
+		rpcServer.ReloadInterval = time.Second * time.Duration(viper.GetInt("rpc.tls.reload_seconds"))
 	}
 	rpcServer.InitDefault()
 
 	n.components = append(n.components, rpcServer)
+	if viper.GetBool("metrics.enabled") {
+		collector := &metrics.NodeCollector{
+			RpcWrapper: rpcWrapper,
+			Interval:   time.Second * time.Duration(viper.GetInt("metrics.node_interval_seconds")),
+		}
+		collector.InitDefault()
+		n.components = append(n.components, collector)
+	}
+	if blockIndexer != nil {
+		n.components = append(n.components, blockIndexer)
+	}
+	var handlers []ethnode.BlockHandler
+	if viper.GetBool("watch.enabled") {
+		webhook := n.newWebhook("watch", "watch_deadletter.jsonl")
+		n.components = append(n.components, webhook)
+		handlers = append(handlers, &watch.Watcher{
+			EthNode: ethNode,
+			List:    watchlist,
+			Webhook: webhook,
+		})
+	}
+	if viper.GetBool("alerts.enabled") {
+		handlers = append(handlers, n.setupAlerts(ethNode))
+	}
+	if len(handlers) > 0 {
+		follower := &ethnode.Follower{
+			EthNode:       ethNode,
+			Handlers:      handlers,
+			Interval:      time.Second * time.Duration(viper.GetInt("follower.interval_seconds")),
+			Confirmations: viper.GetUint64("follower.confirmations"),
+			MaxReorgDepth: viper.GetInt("follower.max_reorg_depth"),
+			CursorFile:    path.Join(n.DataFolder, "follower.json"),
+		}
+		follower.InitDefault()
+		// the follower stops before the webhooks it sends to
+		n.components = append(n.components, follower)
+	}
+
+	if viper.GetBool("crawler.enabled") {
+		for _, factory := range viper.GetStringSlice("crawler.factories") {
+			crawler := &dex.PairCrawler{
+				RpcWrapper: rpcWrapper,
+				Tokens:     tokens,
+				Store:      pairs,
+				Factory:    common.HexToAddress(factory),
+				Interval:   time.Second * time.Duration(viper.GetInt("crawler.interval_seconds")),
+			}
+			crawler.InitDefault()
+			n.components = append(n.components, crawler)
+		}
+		for _, factory := range viper.GetStringSlice("crawler.v3_factories") {
+			crawler := &dex.PoolCrawler{
+				RpcWrapper: rpcWrapper,
+				Tokens:     tokens,
+				Store:      pairs,
+				Factory:    common.HexToAddress(factory),
+				StartBlock: viper.GetUint64("crawler.v3_start_block"),
+				Interval:   time.Second * time.Duration(viper.GetInt("crawler.interval_seconds")),
+			}
+			crawler.InitDefault()
+			n.components = append(n.components, crawler)
+		}
+	}
+}
+
+// NewRepository opens the local index in the data folder
+func NewRepository(dataFolder string) *store.LevelRepository {
+	repository := &store.LevelRepository{
+		Path: path.Join(dataFolder, viper.GetString("store.folder")),
+	}
+	repository.InitDefault()
+	return repository
+}
+
+// NewWatchlist loads the watch list of the data folder
+func NewWatchlist(dataFolder string) *watch.Watchlist {
+	watchlist := &watch.Watchlist{
+		File: path.Join(dataFolder, "watchlist.json"),
+	}
+	watchlist.InitDefault()
+	return watchlist
+}
+
+// NewPruner builds the pruner from the [store.retention] section, pinning the addresses of the watch list
+func NewPruner(repository store.Repository, watchlist *watch.Watchlist) *store.Pruner {
+	pruner := &store.Pruner{
+		Repository:    repository,
+		KeepLogBlocks: viper.GetUint64("store.retention.log_blocks"),
+		Pinned:        watchlist.Addresses,
+		Interval:      time.Minute * time.Duration(viper.GetInt("store.retention.interval_minutes")),
+	}
+	pruner.InitDefault()
+	return pruner
+}
+
+// newWebhook builds the webhook sink configured in the section, with its dead letter log in the data folder
+func (n *Node) newWebhook(section string, deadLetterFile string) *notify.Webhook {
+	webhook := &notify.Webhook{
+		Urls:           viper.GetStringSlice(section + ".webhooks"),
+		Secret:         viper.GetString(section + ".secret"),
+		MaxAttempts:    viper.GetInt(section + ".max_attempts"),
+		Backoff:        time.Second * time.Duration(viper.GetInt(section+".backoff_seconds")),
+		MaxBackoff:     time.Second * time.Duration(viper.GetInt(section+".max_backoff_seconds")),
+		Timeout:        time.Second * time.Duration(viper.GetInt(section+".timeout_seconds")),
+		DeadLetterFile: path.Join(n.DataFolder, deadLetterFile),
+	}
+	webhook.InitDefault()
+	return webhook
+}
+
+// inFolder resolves a configured path relative to the folder
+func inFolder(folder string, file string) string {
+	if path.IsAbs(file) {
+		return file
+	}
+	return path.Join(folder, file)
+}
+
+// setupGuard builds the API guard from [rpc.auth] and [rpc.limits]. Keys are the [rpc.auth.keys.<name>] sections of private.toml.
+func (n *Node) setupGuard() *auth.Guard {
+	guard := &auth.Guard{
+		Required:        viper.GetBool("rpc.auth.required"),
+		AnonymousScopes: viper.GetStringSlice("rpc.auth.anonymous_scopes"),
+		KeyRate:         viper.GetFloat64("rpc.limits.key_rate"),
+		KeyBurst:        viper.GetFloat64("rpc.limits.key_burst"),
+		IpRate:          viper.GetFloat64("rpc.limits.ip_rate"),
+		IpBurst:         viper.GetFloat64("rpc.limits.ip_burst"),
+		Costs:           make(map[string]float64),
+	}
+	for _, proxy := range viper.GetStringSlice("rpc.trusted_proxies") {
+		if !strings.Contains(proxy, "/") {
+			if strings.Contains(proxy, ":") {
+				proxy += "/128"
+			} else {
+				proxy += "/32"
+			}
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			logrus.WithError(err).WithField("proxy", proxy).Fatal("bad trusted proxy")
+		}
+		guard.TrustedProxies = append(guard.TrustedProxies, network)
+	}
+	for route := range viper.GetStringMap("rpc.limits.costs") {
+		guard.Costs[route] = viper.GetFloat64("rpc.limits.costs." + route)
+	}
+	for name := range viper.GetStringMap("rpc.auth.keys") {
+		params := viper.Sub("rpc.auth.keys." + name)
+		if params == nil || params.GetString("secret") == "" {
+			logrus.WithField("key", name).Fatal("api key has no secret")
+		}
+		guard.Keys = append(guard.Keys, &auth.Key{
+			Name:   name,
+			Secret: params.GetString("secret"),
+			Scopes: params.GetStringSlice("scopes"),
+			Rate:   params.GetFloat64("rate"),
+			Burst:  params.GetFloat64("burst"),
+		})
+	}
+	if guard.Required && len(guard.Keys) == 0 {
+		logrus.Fatal("rpc.auth.required is set but no api key is declared")
+	}
+	guard.InitDefault()
+	return guard
+}
+
+// setupAlerts builds the alert engine from the [alerts.rules.<name>] sections
+func (n *Node) setupAlerts(ethNode *ethnode.EthNode) *alert.Engine {
+	engine := &alert.Engine{
+		EthNode: ethNode,
+		Sinks: map[string]notify.Sink{
+			"stdout": &notify.Stdout{},
+		},
+	}
+	if file := viper.GetString("alerts.file"); file != "" {
+		sink := &notify.File{
+			Path: path.Join(n.DataFolder, file),
+		}
+		sink.InitDefault()
+		n.components = append(n.components, sink)
+		engine.Sinks["file"] = sink
+	}
+	if len(viper.GetStringSlice("alerts.webhooks")) > 0 {
+		webhook := n.newWebhook("alerts", "alerts_deadletter.jsonl")
+		n.components = append(n.components, webhook)
+		engine.Sinks["webhook"] = webhook
+	}
+
+	for name := range viper.GetStringMap("alerts.rules") {
+		params := viper.Sub("alerts.rules." + name)
+		if params == nil {
+			logrus.WithField("rule", name).Warn("alert rule has no config section")
+			continue
+		}
+		rule := &alert.Rule{
+			Name:        name,
+			Description: params.GetString("description"),
+			Source:      params.GetString("expr"),
+			Sinks:       params.GetStringSlice("sinks"),
+			RateLimit:   params.GetInt("rate_limit"),
+			RateWindow:  time.Second * time.Duration(params.GetInt("rate_window_seconds")),
+			Dedup:       params.GetString("dedup"),
+			DedupWindow: time.Second * time.Duration(params.GetInt("dedup_seconds")),
+		}
+		if err := engine.AddRule(rule); err != nil {
+			logrus.WithError(err).Fatal("failed to init alert rule")
+		}
+	}
+	return engine
+}
+
+func (n *Node) setupPricing(rpcWrapper *middleware.RpcWrapper, tokens *token.Cache, pairs *dex.PairStore) *pricing.Service {
+	service := &pricing.Service{
+		RpcWrapper: rpcWrapper,
+		Tokens:     tokens,
+		Pairs:      pairs,
+		Oracles: pricing.Oracles{
+			Weth:       common.HexToAddress(viper.GetString("pricing.weth")),
+			Stablecoin: common.HexToAddress(viper.GetString("pricing.stablecoin")),
+			EthUsdPool: common.HexToAddress(viper.GetString("pricing.eth_usd_pool")),
+		},
+	}
+	for _, connector := range viper.GetStringSlice("pricing.connectors") {
+		service.Connectors = append(service.Connectors, common.HexToAddress(connector))
+	}
+	service.InitDefault()
+	return service
+}
+
+// setupRating builds the rating engine from the [rating.scorers.<name>] sections.
+// Without configuration only the gas scorer is used.
+func (n *Node) setupRating() *rating.Engine {
+	engine := &rating.Engine{
+		Max: viper.GetUint64("rating.max"),
+	}
+	engine.InitDefault()
+
+	scorers := viper.GetStringMap("rating.scorers")
+	if len(scorers) == 0 {
+		scorer, _ := rating.NewScorer("gas", nil)
+		engine.AddScorer(scorer, float64(engine.Max))
+		return engine
+	}
+	for name := range scorers {
+		params := viper.Sub("rating.scorers." + name)
+		if params == nil {
+			logrus.WithField("scorer", name).Warn("scorer has no config section")
+			continue
+		}
+		scorer, err := rating.NewScorer(name, params)
+		if err != nil {
+			logrus.WithError(err).WithField("scorer", name).Fatal("failed to init scorer")
+		}
+		engine.AddScorer(scorer, params.GetFloat64("weight"))
+	}
+	return engine
 }
 
 func (n *Node) Start() {
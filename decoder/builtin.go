@@ -0,0 +1,134 @@
+package decoder
+
+// builtinMethods are well known function signatures which can be decoded without any ABI file
+var builtinMethods = []string{
+	// ERC-20
+	"transfer(address,uint256)",
+	"transferFrom(address,address,uint256)",
+	"approve(address,uint256)",
+	"balanceOf(address)",
+	"allowance(address,address)",
+	"totalSupply()",
+	"name()",
+	"symbol()",
+	"decimals()",
+	// WETH
+	"deposit()",
+	"withdraw(uint256)",
+	// ERC-721 / ERC-1155
+	"safeTransferFrom(address,address,uint256)",
+	"safeTransferFrom(address,address,uint256,bytes)",
+	"safeTransferFrom(address,address,uint256,uint256,bytes)",
+	"safeBatchTransferFrom(address,address,uint256[],uint256[],bytes)",
+	"setApprovalForAll(address,bool)",
+	"supportsInterface(bytes4)",
+	"ownerOf(uint256)",
+	// Uniswap V2 router
+	"swapExactTokensForTokens(uint256,uint256,address[],address,uint256)",
+	"swapTokensForExactTokens(uint256,uint256,address[],address,uint256)",
+	"swapExactETHForTokens(uint256,address[],address,uint256)",
+	"swapTokensForExactETH(uint256,uint256,address[],address,uint256)",
+	"swapExactTokensForETH(uint256,uint256,address[],address,uint256)",
+	"swapETHForExactTokens(uint256,address[],address,uint256)",
+	"swapExactTokensForTokensSupportingFeeOnTransferTokens(uint256,uint256,address[],address,uint256)",
+	"swapExactETHForTokensSupportingFeeOnTransferTokens(uint256,address[],address,uint256)",
+	"swapExactTokensForETHSupportingFeeOnTransferTokens(uint256,uint256,address[],address,uint256)",
+	"addLiquidity(address,address,uint256,uint256,uint256,uint256,address,uint256)",
+	"addLiquidityETH(address,uint256,uint256,uint256,address,uint256)",
+	"removeLiquidity(address,address,uint256,uint256,uint256,address,uint256)",
+	"removeLiquidityETH(address,uint256,uint256,uint256,address,uint256)",
+	// Uniswap V2 pair / factory
+	"swap(uint256,uint256,address,bytes)",
+	"mint(address)",
+	"burn(address)",
+	"sync()",
+	"skim(address)",
+	"getReserves()",
+	"token0()",
+	"token1()",
+	"getPair(address,address)",
+	"allPairs(uint256)",
+	"allPairsLength()",
+	// Uniswap V3
+	"multicall(bytes[])",
+	"multicall(uint256,bytes[])",
+	"exactInput((bytes,address,uint256,uint256,uint256))",
+	"exactOutput((bytes,address,uint256,uint256,uint256))",
+	"slot0()",
+	"liquidity()",
+	"tickSpacing()",
+	"fee()",
+	// Balancer V1 pool
+	"getCurrentTokens()",
+	"getBalance(address)",
+	"getDenormalizedWeight(address)",
+	"getTotalDenormalizedWeight()",
+	"getSwapFee()",
+	// Gnosis safe
+	"execTransaction(address,uint256,bytes,uint8,uint256,uint256,uint256,address,address,bytes)",
+	// Aave / Compound
+	"liquidationCall(address,address,address,uint256,bool)",
+	"liquidateBorrow(address,uint256,address)",
+	"deposit(address,uint256,address,uint16)",
+	"borrow(address,uint256,uint256,uint16,address)",
+	"repay(address,uint256,uint256,address)",
+	"mint(uint256)",
+	"redeem(uint256)",
+	"redeemUnderlying(uint256)",
+	"borrow(uint256)",
+	"repayBorrow(uint256)",
+}
+
+// builtinEvents are well known event signatures
+var builtinEvents = []string{
+	"Transfer(address,address,uint256)",
+	"Approval(address,address,uint256)",
+	"ApprovalForAll(address,address,bool)",
+	"TransferSingle(address,address,address,uint256,uint256)",
+	"TransferBatch(address,address,address,uint256[],uint256[])",
+	"Deposit(address,uint256)",
+	"Withdrawal(address,uint256)",
+	"Sync(uint112,uint112)",
+	"Swap(address,uint256,uint256,uint256,uint256,address)",
+	"Mint(address,uint256,uint256)",
+	"Burn(address,uint256,uint256,address)",
+	"PairCreated(address,address,address,uint256)",
+	"Swap(address,address,int256,int256,uint160,uint128,int24)",
+	"Mint(address,address,int24,int24,uint128,uint256,uint256)",
+	"Burn(address,int24,int24,uint128,uint256,uint256)",
+	"Collect(address,address,int24,int24,uint128,uint128)",
+	"PoolCreated(address,address,uint24,int24,address)",
+	"LiquidationCall(address,address,address,uint256,uint256,address,bool)",
+	"LiquidateBorrow(address,address,uint256,address,uint256)",
+	"ExecutionSuccess(bytes32,uint256)",
+	"ExecutionFailure(bytes32,uint256)",
+}
+
+// builtinErrors are well known custom error signatures
+var builtinErrors = []string{
+	"Error(string)",
+	"Panic(uint256)",
+	// OpenZeppelin 5
+	"ERC20InsufficientBalance(address,uint256,uint256)",
+	"ERC20InsufficientAllowance(address,uint256,uint256)",
+	"ERC20InvalidSender(address)",
+	"ERC20InvalidReceiver(address)",
+	"ERC721NonexistentToken(uint256)",
+	"ERC721IncorrectOwner(address,uint256,address)",
+	"OwnableUnauthorizedAccount(address)",
+	"ReentrancyGuardReentrantCall()",
+	"SafeERC20FailedOperation(address)",
+	"AddressEmptyCode(address)",
+	"FailedInnerCall()",
+	// Uniswap universal router and permit2
+	"V2TooLittleReceived()",
+	"V2TooMuchRequested()",
+	"V3TooLittleReceived()",
+	"V3TooMuchRequested()",
+	"TransactionDeadlinePassed()",
+	"ExecutionFailed(uint256,bytes)",
+	"InsufficientAllowance(uint256)",
+	"AllowanceExpired(uint256)",
+	"SignatureExpired(uint256)",
+	"InvalidNonce()",
+}
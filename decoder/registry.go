@@ -0,0 +1,204 @@
+package decoder
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/sirupsen/logrus"
+	"io/ioutil"
+	"path"
+	"strings"
+)
+
+// Signature is a known function, event or error signature
+type Signature struct {
+	Name   string
+	Text   string
+	Inputs abi.Arguments
+}
+
+// Registry resolves method selectors, event topics and error selectors to their signatures.
+// Builtin signatures are always loaded. Additional ones are read from *.json ABI files in AbiFolder.
+type Registry struct {
+	AbiFolder string
+
+	methods map[string]*Signature
+	events  map[common.Hash]*Signature
+	errors  map[string]*Signature
+}
+
+func (r *Registry) InitDefault() {
+	r.methods = make(map[string]*Signature)
+	r.events = make(map[common.Hash]*Signature)
+	r.errors = make(map[string]*Signature)
+
+	for _, text := range builtinMethods {
+		r.addText(text, r.addMethod)
+	}
+	for _, text := range builtinEvents {
+		r.addText(text, r.addEvent)
+	}
+	for _, text := range builtinErrors {
+		r.addText(text, r.addError)
+	}
+
+	if r.AbiFolder != "" {
+		err := r.LoadAbiFolder(r.AbiFolder)
+		if err != nil {
+			logrus.WithError(err).WithField("folder", r.AbiFolder).Warn("failed to load abi folder")
+		}
+	}
+}
+
+// LoadAbiFolder loads every *.json ABI file in the folder
+func (r *Registry) LoadAbiFolder(folder string) error {
+	infos, err := ioutil.ReadDir(folder)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".json") {
+			continue
+		}
+		err = r.LoadAbiFile(path.Join(folder, info.Name()))
+		if err != nil {
+			logrus.WithError(err).WithField("file", info.Name()).Warn("failed to load abi file")
+		}
+	}
+	return nil
+}
+
+// LoadAbiFile loads functions, events and custom errors from a solidity ABI json file
+func (r *Registry) LoadAbiFile(file string) error {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	var fields []struct {
+		Type   string
+		Name   string
+		Inputs abi.Arguments
+	}
+	err = json.Unmarshal(content, &fields)
+	if err != nil {
+		return err
+	}
+	for _, field := range fields {
+		sig := &Signature{
+			Name:   field.Name,
+			Text:   signatureText(field.Name, field.Inputs),
+			Inputs: field.Inputs,
+		}
+		switch field.Type {
+		case "function", "":
+			r.addMethod(sig)
+		case "event":
+			r.addEvent(sig)
+		case "error":
+			r.addError(sig)
+		}
+	}
+	return nil
+}
+
+// Method returns the signature of the function called by the given input
+func (r *Registry) Method(input []byte) (*Signature, bool) {
+	if len(input) < 4 {
+		return nil, false
+	}
+	sig, ok := r.methods[hex.EncodeToString(input[:4])]
+	return sig, ok
+}
+
+// MethodName returns the function signature text, or an empty string if the selector is unknown
+func (r *Registry) MethodName(input []byte) string {
+	sig, ok := r.Method(input)
+	if !ok {
+		return ""
+	}
+	return sig.Text
+}
+
+// Event returns the signature of the event identified by topic0
+func (r *Registry) Event(topic common.Hash) (*Signature, bool) {
+	sig, ok := r.events[topic]
+	return sig, ok
+}
+
+// Error returns the signature of the custom error encoded in the revert data
+func (r *Registry) Error(data []byte) (*Signature, bool) {
+	if len(data) < 4 {
+		return nil, false
+	}
+	sig, ok := r.errors[hex.EncodeToString(data[:4])]
+	return sig, ok
+}
+
+func (r *Registry) addMethod(sig *Signature) {
+	r.methods[hex.EncodeToString(Selector(sig.Text))] = sig
+}
+
+func (r *Registry) addEvent(sig *Signature) {
+	r.events[Topic(sig.Text)] = sig
+}
+
+func (r *Registry) addError(sig *Signature) {
+	r.errors[hex.EncodeToString(Selector(sig.Text))] = sig
+}
+
+func (r *Registry) addText(text string, add func(sig *Signature)) {
+	sig, err := parseSignature(text)
+	if err != nil {
+		logrus.WithError(err).WithField("sig", text).Error("bad builtin signature")
+		return
+	}
+	add(sig)
+}
+
+// Selector returns the 4 bytes selector of a signature text like transfer(address,uint256)
+func Selector(text string) []byte {
+	return crypto.Keccak256([]byte(text))[:4]
+}
+
+// Topic returns the topic0 of an event signature text like Transfer(address,address,uint256)
+func Topic(text string) common.Hash {
+	return crypto.Keccak256Hash([]byte(text))
+}
+
+func signatureText(name string, inputs abi.Arguments) string {
+	types := make([]string, len(inputs))
+	for i, input := range inputs {
+		types[i] = input.Type.String()
+	}
+	return fmt.Sprintf("%s(%s)", name, strings.Join(types, ","))
+}
+
+// parseSignature parses a signature text made of elementary types only
+func parseSignature(text string) (sig *Signature, err error) {
+	open := strings.Index(text, "(")
+	if open <= 0 || !strings.HasSuffix(text, ")") {
+		err = fmt.Errorf("bad signature %s", text)
+		return
+	}
+	sig = &Signature{
+		Name: text[:open],
+		Text: text,
+	}
+	params := text[open+1 : len(text)-1]
+	if params == "" || strings.Contains(params, "(") {
+		// tuple parameters are kept as text only
+		return
+	}
+	for _, param := range strings.Split(params, ",") {
+		ty, er := abi.NewType(param, "", nil)
+		if er != nil {
+			err = er
+			return
+		}
+		sig.Inputs = append(sig.Inputs, abi.Argument{Type: ty})
+	}
+	return
+}
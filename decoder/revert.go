@@ -0,0 +1,107 @@
+package decoder
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"math/big"
+	"strings"
+)
+
+const (
+	FailureRevert      = "revert"
+	FailurePanic       = "panic"
+	FailureCustomError = "custom_error"
+	FailureOutOfGas    = "out_of_gas"
+	FailureUnknown     = "unknown"
+)
+
+var errorStringSelector = Selector("Error(string)")
+var panicSelector = Selector("Panic(uint256)")
+
+var errorStringArgs = func() abi.Arguments {
+	ty, _ := abi.NewType("string", "", nil)
+	return abi.Arguments{{Type: ty}}
+}()
+
+var panicArgs = func() abi.Arguments {
+	ty, _ := abi.NewType("uint256", "", nil)
+	return abi.Arguments{{Type: ty}}
+}()
+
+// PanicCodes are the meanings of the solidity Panic(uint256) codes
+var PanicCodes = map[uint64]string{
+	0x00: "generic compiler inserted panic",
+	0x01: "assertion failed",
+	0x11: "arithmetic overflow or underflow",
+	0x12: "division or modulo by zero",
+	0x21: "invalid enum conversion",
+	0x22: "invalid storage byte array encoding",
+	0x31: "pop on empty array",
+	0x32: "array index out of bounds",
+	0x41: "too much memory allocated",
+	0x51: "call to zero initialized internal function",
+}
+
+// DecodeRevert extracts the reason string from revert data encoded as Error(string).
+// An empty string is returned if the data is not a reason string.
+func (r *Registry) DecodeRevert(data []byte) string {
+	if len(data) < 4 || !bytes.Equal(data[:4], errorStringSelector) {
+		return ""
+	}
+	values, err := errorStringArgs.UnpackValues(data[4:])
+	if err != nil || len(values) == 0 {
+		return ""
+	}
+	reason, _ := values[0].(string)
+	return reason
+}
+
+// DecodeFailure decodes revert data as Error(string), Panic(uint256) or a custom error known to the registry
+func (r *Registry) DecodeFailure(data []byte) (kind string, message string) {
+	if len(data) == 0 {
+		return FailureRevert, ""
+	}
+	if len(data) < 4 {
+		return FailureUnknown, hexutil.Encode(data)
+	}
+	if bytes.Equal(data[:4], errorStringSelector) {
+		return FailureRevert, r.DecodeRevert(data)
+	}
+	if bytes.Equal(data[:4], panicSelector) {
+		values, err := panicArgs.UnpackValues(data[4:])
+		if err != nil || len(values) == 0 {
+			return FailurePanic, hexutil.Encode(data)
+		}
+		code, _ := values[0].(*big.Int)
+		if code == nil {
+			return FailurePanic, hexutil.Encode(data)
+		}
+		meaning, ok := PanicCodes[code.Uint64()]
+		if !ok || !code.IsUint64() {
+			meaning = "unknown panic code"
+		}
+		return FailurePanic, fmt.Sprintf("0x%02x: %s", code, meaning)
+	}
+	if sig, ok := r.Error(data); ok {
+		return FailureCustomError, formatCall(sig, data[4:])
+	}
+	return FailureCustomError, hexutil.Encode(data[:4])
+}
+
+// formatCall renders a decoded call like Name(arg1, arg2). Arguments are omitted if they can not be decoded.
+func formatCall(sig *Signature, data []byte) string {
+	if len(sig.Inputs) == 0 {
+		return sig.Text
+	}
+	values, err := sig.Inputs.UnpackValues(data)
+	if err != nil {
+		return sig.Text
+	}
+	args := make([]string, len(values))
+	for i, value := range values {
+		args[i] = fmt.Sprint(value)
+	}
+	return fmt.Sprintf("%s(%s)", sig.Name, strings.Join(args, ", "))
+}
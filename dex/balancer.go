@@ -0,0 +1,67 @@
+package dex
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/latifrons/etherxray/middleware"
+	"github.com/latifrons/etherxray/model"
+	"github.com/latifrons/etherxray/token"
+	"github.com/latifrons/etherxray/tools"
+	"math/big"
+)
+
+// BalancerOne is the fixed point unit of Balancer V1 fees
+var BalancerOne = big.NewInt(1e18)
+
+// BalancerInspector reads Balancer V1 pools
+type BalancerInspector struct {
+	RpcWrapper *middleware.RpcWrapper
+	Tokens     *token.Cache
+}
+
+// PoolState reads the tokens, balances, weights and swap fee of the pool at the given height
+func (b *BalancerInspector) PoolState(pool common.Address, height uint64) (state *model.BalancerPool, err error) {
+	resp, err := b.RpcWrapper.GetBalancerPool(tools.GetContextDefault(), pool, big.NewInt(0).SetUint64(height))
+	if err != nil {
+		return
+	}
+	state = &model.BalancerPool{
+		Address: pool,
+		Height:  height,
+		SwapFee: big.NewRat(1, 1).SetFrac(resp.SwapFee, BalancerOne),
+	}
+	for i, address := range resp.Tokens {
+		info := b.Tokens.Get(address)
+		item := model.BalancerToken{
+			Token:        address,
+			Symbol:       info.Symbol,
+			Decimals:     info.Decimals,
+			Balance:      resp.Balances[i],
+			DenormWeight: resp.Weights[i],
+		}
+		if resp.TotalWeight.Sign() != 0 {
+			item.Weight = big.NewRat(1, 1).SetFrac(resp.Weights[i], resp.TotalWeight)
+		}
+		state.Tokens = append(state.Tokens, item)
+	}
+	return
+}
+
+// BalancerSpotPrice returns the price of tokenOut in tokenIn, in token units, with the weighted pool formula
+// (balanceIn / weightIn) / (balanceOut / weightOut) / (1 - swapFee). Nil when a balance or weight is empty.
+func BalancerSpotPrice(in model.BalancerToken, out model.BalancerToken, swapFee *big.Rat) *big.Rat {
+	if in.DenormWeight.Sign() == 0 || out.DenormWeight.Sign() == 0 || out.Balance.Sign() == 0 {
+		return nil
+	}
+	price := tools.FromDecimals(in.Balance, in.Decimals)
+	price.Quo(price, big.NewRat(1, 1).SetInt(in.DenormWeight))
+	price.Quo(price, tools.FromDecimals(out.Balance, out.Decimals))
+	price.Mul(price, big.NewRat(1, 1).SetInt(out.DenormWeight))
+	if swapFee != nil {
+		scale := big.NewRat(1, 1).Sub(big.NewRat(1, 1), swapFee)
+		if scale.Sign() <= 0 {
+			return nil
+		}
+		price.Quo(price, scale)
+	}
+	return price
+}
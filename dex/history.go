@@ -0,0 +1,144 @@
+package dex
+
+import (
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/latifrons/etherxray/decoder"
+	"github.com/latifrons/etherxray/middleware"
+	"github.com/latifrons/etherxray/model"
+	"github.com/latifrons/etherxray/token"
+	"github.com/latifrons/etherxray/tools"
+	"math/big"
+)
+
+const (
+	// MaxHistorySamples limits the size of a pool history series, each sample costs up to two calls to the node
+	MaxHistorySamples = 500
+	// MaxSyncScanBlocks limits the range scanned for Sync events
+	MaxSyncScanBlocks = 50 * LogChunkBlocks
+)
+
+var SyncTopic = decoder.Topic("Sync(uint112,uint112)")
+
+// PoolHistory builds reserve and price series of V2 pools from getReserves or Sync events
+type PoolHistory struct {
+	RpcWrapper *middleware.RpcWrapper
+	Tokens     *token.Cache
+	Pools      *Pools
+}
+
+// PoolTokens returns the metadata of the two tokens of a pool
+func (h *PoolHistory) PoolTokens(pool common.Address) (token0 *model.TokenInfo, token1 *model.TokenInfo, err error) {
+	address0, address1, err := h.Pools.Tokens(pool)
+	if err != nil {
+		return
+	}
+	token0 = h.Tokens.Get(address0)
+	token1 = h.Tokens.Get(address1)
+	return
+}
+
+// SampleInterval reads getReserves every step blocks in [from, to]
+func (h *PoolHistory) SampleInterval(pool common.Address, from uint64, to uint64, step uint64) (samples []*model.PoolSample, err error) {
+	if step == 0 {
+		step = 1
+	}
+	if to < from || (to-from)/step >= MaxHistorySamples {
+		err = fmt.Errorf("at most %d samples per query", MaxHistorySamples)
+		return
+	}
+	token0, token1, err := h.PoolTokens(pool)
+	if err != nil {
+		return
+	}
+	for height := from; height <= to; height += step {
+		reserves, er := h.RpcWrapper.GetUniswapLiquidities(tools.GetContextDefault(), pool, big.NewInt(0).SetUint64(height))
+		if er != nil {
+			err = er
+			return
+		}
+		sample := &model.PoolSample{
+			Height:   height,
+			Reserve0: reserves.Reserve0,
+			Reserve1: reserves.Reserve1,
+		}
+		h.fillPrices(sample, token0, token1)
+		samples = append(samples, sample)
+	}
+	err = h.fillTimestamps(samples)
+	return
+}
+
+// SampleSyncs collects the reserves after every Sync event of the pool in [from, to]
+func (h *PoolHistory) SampleSyncs(pool common.Address, from uint64, to uint64) (samples []*model.PoolSample, err error) {
+	if to < from {
+		err = fmt.Errorf("bad block range")
+		return
+	}
+	if to-from >= MaxSyncScanBlocks {
+		err = fmt.Errorf("at most %d blocks per query", MaxSyncScanBlocks)
+		return
+	}
+	token0, token1, err := h.PoolTokens(pool)
+	if err != nil {
+		return
+	}
+	for start := from; start <= to; start += LogChunkBlocks {
+		end := start + LogChunkBlocks - 1
+		if end > to {
+			end = to
+		}
+		logs, er := h.RpcWrapper.GetTradeLogFromTo(tools.GetContext(30), start, end, SyncTopic, []common.Address{pool})
+		if er != nil {
+			err = er
+			return
+		}
+		for _, log := range logs {
+			if len(log.Data) != 2*common.HashLength {
+				continue
+			}
+			sample := &model.PoolSample{
+				Height:   log.BlockNumber,
+				TxHash:   log.TxHash,
+				LogIndex: log.Index,
+				Reserve0: big.NewInt(0).SetBytes(log.Data[:common.HashLength]),
+				Reserve1: big.NewInt(0).SetBytes(log.Data[common.HashLength:]),
+			}
+			h.fillPrices(sample, token0, token1)
+			samples = append(samples, sample)
+			if len(samples) > MaxHistorySamples {
+				err = fmt.Errorf("more than %d sync events, narrow the block range", MaxHistorySamples)
+				return
+			}
+		}
+	}
+	err = h.fillTimestamps(samples)
+	return
+}
+
+func (h *PoolHistory) fillPrices(sample *model.PoolSample, token0 *model.TokenInfo, token1 *model.TokenInfo) {
+	if sample.Reserve0.Sign() == 0 || sample.Reserve1.Sign() == 0 {
+		return
+	}
+	amount0 := tools.FromDecimals(sample.Reserve0, token0.Decimals)
+	amount1 := tools.FromDecimals(sample.Reserve1, token1.Decimals)
+	sample.Price0 = big.NewRat(1, 1).Quo(amount1, amount0)
+	sample.Price1 = big.NewRat(1, 1).Quo(amount0, amount1)
+}
+
+func (h *PoolHistory) fillTimestamps(samples []*model.PoolSample) error {
+	timestamps := make(map[uint64]uint64)
+	for _, sample := range samples {
+		timestamp, ok := timestamps[sample.Height]
+		if !ok {
+			header, err := h.RpcWrapper.BlockHeader(tools.GetContextDefault(), sample.Height)
+			if err != nil {
+				return err
+			}
+			timestamp = header.Time
+			timestamps[sample.Height] = timestamp
+		}
+		sample.Timestamp = timestamp
+	}
+	return nil
+}
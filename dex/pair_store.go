@@ -0,0 +1,143 @@
+package dex
+
+import (
+	"encoding/json"
+	"github.com/annchain/commongo/files"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/latifrons/etherxray/model"
+	"github.com/sirupsen/logrus"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+type pairStoreFile struct {
+	Pairs []*model.Pair `json:"pairs"`
+	// Checkpoints is the last block scanned for PairCreated events, per factory
+	Checkpoints map[common.Address]uint64 `json:"checkpoints"`
+}
+
+// PairStore keeps the crawled pairs on disk and indexes them by token
+type PairStore struct {
+	File string
+
+	mu          sync.RWMutex
+	saveMu      sync.Mutex
+	pairs       map[common.Address]*model.Pair
+	byToken     map[common.Address][]*model.Pair
+	counts      map[common.Address]uint64
+	checkpoints map[common.Address]uint64
+}
+
+func (s *PairStore) InitDefault() {
+	s.pairs = make(map[common.Address]*model.Pair)
+	s.byToken = make(map[common.Address][]*model.Pair)
+	s.counts = make(map[common.Address]uint64)
+	s.checkpoints = make(map[common.Address]uint64)
+
+	if s.File == "" || !files.FileExists(s.File) {
+		return
+	}
+	content, err := ioutil.ReadFile(s.File)
+	if err != nil {
+		logrus.WithError(err).WithField("file", s.File).Warn("failed to read pair store")
+		return
+	}
+	var stored pairStoreFile
+	err = json.Unmarshal(content, &stored)
+	if err != nil {
+		logrus.WithError(err).WithField("file", s.File).Warn("failed to parse pair store")
+		return
+	}
+	for _, pair := range stored.Pairs {
+		s.add(pair)
+	}
+	for factory, height := range stored.Checkpoints {
+		s.checkpoints[factory] = height
+	}
+}
+
+// Add stores a pair. Pairs already known are ignored.
+func (s *PairStore) Add(pair *model.Pair) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.add(pair)
+}
+
+func (s *PairStore) add(pair *model.Pair) {
+	if _, ok := s.pairs[pair.Address]; ok {
+		return
+	}
+	s.pairs[pair.Address] = pair
+	s.byToken[pair.Token0] = append(s.byToken[pair.Token0], pair)
+	s.byToken[pair.Token1] = append(s.byToken[pair.Token1], pair)
+	if pair.Index+1 > s.counts[pair.Factory] {
+		s.counts[pair.Factory] = pair.Index + 1
+	}
+}
+
+// Get returns the pair at the address
+func (s *PairStore) Get(address common.Address) (pair *model.Pair, ok bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	pair, ok = s.pairs[address]
+	return
+}
+
+// ByToken returns every pair in which the token is traded
+func (s *PairStore) ByToken(token common.Address) []*model.Pair {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]*model.Pair{}, s.byToken[token]...)
+}
+
+// Count returns the highest known pair index of the factory plus one
+func (s *PairStore) Count(factory common.Address) uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.counts[factory]
+}
+
+func (s *PairStore) Checkpoint(factory common.Address) uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.checkpoints[factory]
+}
+
+func (s *PairStore) SetCheckpoint(factory common.Address, height uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checkpoints[factory] = height
+}
+
+// Save writes the store to disk
+func (s *PairStore) Save() {
+	if s.File == "" {
+		return
+	}
+	s.mu.RLock()
+	stored := pairStoreFile{
+		Pairs:       make([]*model.Pair, 0, len(s.pairs)),
+		Checkpoints: s.checkpoints,
+	}
+	for _, pair := range s.pairs {
+		stored.Pairs = append(stored.Pairs, pair)
+	}
+	content, err := json.Marshal(stored)
+	s.mu.RUnlock()
+	if err != nil {
+		logrus.WithError(err).Warn("failed to marshal pair store")
+		return
+	}
+
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+	tmp := s.File + ".tmp"
+	err = ioutil.WriteFile(tmp, content, 0644)
+	if err == nil {
+		err = os.Rename(tmp, s.File)
+	}
+	if err != nil {
+		logrus.WithError(err).WithField("file", s.File).Warn("failed to write pair store")
+	}
+}
@@ -0,0 +1,47 @@
+package dex
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/latifrons/etherxray/middleware"
+	"github.com/latifrons/etherxray/tools"
+	"sync"
+)
+
+// Pools resolves the token pair of V2 and V3 pools and keeps it in memory
+type Pools struct {
+	RpcWrapper *middleware.RpcWrapper
+	Pairs      *PairStore
+
+	mu     sync.RWMutex
+	tokens map[common.Address][2]common.Address
+}
+
+func (p *Pools) InitDefault() {
+	p.tokens = make(map[common.Address][2]common.Address)
+}
+
+// Tokens returns token0 and token1 of the pool, from the pair store if the pool was crawled
+func (p *Pools) Tokens(pool common.Address) (token0 common.Address, token1 common.Address, err error) {
+	if pair, ok := p.Pairs.Get(pool); ok {
+		return pair.Token0, pair.Token1, nil
+	}
+	p.mu.RLock()
+	tokens, ok := p.tokens[pool]
+	p.mu.RUnlock()
+	if ok {
+		return tokens[0], tokens[1], nil
+	}
+
+	token0, err = p.RpcWrapper.GetValueRetAddress(tools.GetContextDefault(), pool, "token0")
+	if err != nil {
+		return
+	}
+	token1, err = p.RpcWrapper.GetValueRetAddress(tools.GetContextDefault(), pool, "token1")
+	if err != nil {
+		return
+	}
+	p.mu.Lock()
+	p.tokens[pool] = [2]common.Address{token0, token1}
+	p.mu.Unlock()
+	return
+}
@@ -0,0 +1,235 @@
+package dex
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/latifrons/etherxray/decoder"
+	"github.com/latifrons/etherxray/model"
+	"github.com/latifrons/etherxray/token"
+	"github.com/latifrons/etherxray/tools"
+	"github.com/sirupsen/logrus"
+	"math/big"
+)
+
+var (
+	SwapV2Topic    = decoder.Topic("Swap(address,uint256,uint256,uint256,uint256,address)")
+	SwapV3Topic    = decoder.Topic("Swap(address,address,int256,int256,uint160,uint128,int24)")
+	MintV3Topic    = decoder.Topic("Mint(address,address,int24,int24,uint128,uint256,uint256)")
+	BurnV3Topic    = decoder.Topic("Burn(address,int24,int24,uint128,uint256,uint256)")
+	CollectV3Topic = decoder.Topic("Collect(address,address,int24,int24,uint128,uint128)")
+)
+
+// SwapDecoder decodes V2 and V3 swaps and V3 liquidity events from receipts
+type SwapDecoder struct {
+	Pools  *Pools
+	Tokens *token.Cache
+}
+
+// BlockSwaps keeps the last known V3 price of each pool while the transactions of a block are decoded in order,
+// so the price impact of a swap is measured against the previous swap in the same block
+type BlockSwaps struct {
+	Height     uint64
+	sqrtPrices map[common.Address]*big.Int
+}
+
+func (d *SwapDecoder) NewBlock(height uint64) *BlockSwaps {
+	return &BlockSwaps{
+		Height:     height,
+		sqrtPrices: make(map[common.Address]*big.Int),
+	}
+}
+
+// Decode extracts the swaps and liquidity events of a receipt. Receipts of a block must be decoded in order.
+func (d *SwapDecoder) Decode(block *BlockSwaps, receipt *types.Receipt) (swaps []model.Swap, events []model.LiquidityEvent) {
+	for i, log := range receipt.Logs {
+		if len(log.Topics) == 0 {
+			continue
+		}
+		switch log.Topics[0] {
+		case SwapV2Topic:
+			var sync *types.Log
+			if i > 0 && receipt.Logs[i-1].Address == log.Address && len(receipt.Logs[i-1].Topics) > 0 && receipt.Logs[i-1].Topics[0] == SyncTopic {
+				sync = receipt.Logs[i-1]
+			}
+			if swap, ok := d.decodeV2Swap(log, sync); ok {
+				swaps = append(swaps, swap)
+			}
+		case SwapV3Topic:
+			if swap, ok := d.decodeV3Swap(block, log); ok {
+				swaps = append(swaps, swap)
+			}
+		case MintV3Topic, BurnV3Topic, CollectV3Topic:
+			if event, ok := decodeV3Liquidity(log); ok {
+				events = append(events, event)
+			}
+		}
+	}
+	return
+}
+
+func (d *SwapDecoder) decodeV2Swap(log *types.Log, sync *types.Log) (swap model.Swap, ok bool) {
+	if len(log.Topics) != 3 || len(log.Data) != 4*common.HashLength {
+		return
+	}
+	token0, token1, err := d.Pools.Tokens(log.Address)
+	if err != nil {
+		logrus.WithError(err).WithField("pool", log.Address.Hex()).Debug("not a V2 pool")
+		return
+	}
+	amount0In := wordAt(log.Data, 0)
+	amount1In := wordAt(log.Data, 1)
+	amount0Out := wordAt(log.Data, 2)
+	amount1Out := wordAt(log.Data, 3)
+
+	swap = model.Swap{
+		Protocol:  model.ProtocolUniswapV2,
+		Pool:      log.Address,
+		Sender:    common.BytesToAddress(log.Topics[1].Bytes()),
+		Recipient: common.BytesToAddress(log.Topics[2].Bytes()),
+		LogIndex:  log.Index,
+	}
+	zeroForOne := amount0In.Cmp(amount1In) >= 0
+	if zeroForOne {
+		swap.TokenIn, swap.TokenOut = token0, token1
+		swap.AmountIn, swap.AmountOut = amount0In, amount1Out
+	} else {
+		swap.TokenIn, swap.TokenOut = token1, token0
+		swap.AmountIn, swap.AmountOut = amount1In, amount0Out
+	}
+	d.fillTokens(&swap)
+
+	if sync != nil && len(sync.Data) == 2*common.HashLength {
+		// reserves after the swap come from the Sync event emitted right before it
+		reserveIn, reserveOut := wordAt(sync.Data, 0), wordAt(sync.Data, 1)
+		if !zeroForOne {
+			reserveIn, reserveOut = reserveOut, reserveIn
+		}
+		swap.PriceImpact = v2PriceImpact(reserveIn, reserveOut, swap.AmountIn, swap.AmountOut)
+	}
+	ok = true
+	return
+}
+
+// v2PriceImpact compares the price of the input token before and after the swap given the reserves after it
+func v2PriceImpact(reserveIn *big.Int, reserveOut *big.Int, amountIn *big.Int, amountOut *big.Int) *big.Rat {
+	beforeIn := big.NewInt(0).Sub(reserveIn, amountIn)
+	beforeOut := big.NewInt(0).Add(reserveOut, amountOut)
+	if beforeIn.Sign() <= 0 || reserveIn.Sign() == 0 {
+		return nil
+	}
+	before := big.NewRat(1, 1).SetFrac(beforeOut, beforeIn)
+	after := big.NewRat(1, 1).SetFrac(reserveOut, reserveIn)
+	if before.Sign() == 0 {
+		return nil
+	}
+	return big.NewRat(1, 1).Sub(big.NewRat(1, 1), after.Quo(after, before))
+}
+
+func (d *SwapDecoder) decodeV3Swap(block *BlockSwaps, log *types.Log) (swap model.Swap, ok bool) {
+	if len(log.Topics) != 3 || len(log.Data) != 5*common.HashLength {
+		return
+	}
+	token0, token1, err := d.Pools.Tokens(log.Address)
+	if err != nil {
+		logrus.WithError(err).WithField("pool", log.Address.Hex()).Debug("not a V3 pool")
+		return
+	}
+	amount0 := math.S256(wordAt(log.Data, 0))
+	amount1 := math.S256(wordAt(log.Data, 1))
+	sqrtPriceAfter := wordAt(log.Data, 2)
+
+	swap = model.Swap{
+		Protocol:  model.ProtocolUniswapV3,
+		Pool:      log.Address,
+		Sender:    common.BytesToAddress(log.Topics[1].Bytes()),
+		Recipient: common.BytesToAddress(log.Topics[2].Bytes()),
+		LogIndex:  log.Index,
+	}
+	// positive amounts are paid into the pool
+	zeroForOne := amount0.Sign() > 0
+	if zeroForOne {
+		swap.TokenIn, swap.TokenOut = token0, token1
+		swap.AmountIn, swap.AmountOut = amount0, big.NewInt(0).Neg(amount1)
+	} else {
+		swap.TokenIn, swap.TokenOut = token1, token0
+		swap.AmountIn, swap.AmountOut = amount1, big.NewInt(0).Neg(amount0)
+	}
+	d.fillTokens(&swap)
+
+	sqrtPriceBefore, found := block.sqrtPrices[log.Address]
+	if !found && block.Height > 0 {
+		slot0, err := d.Pools.RpcWrapper.GetUniswapV3Slot0(tools.GetContextDefault(), log.Address, big.NewInt(0).SetUint64(block.Height-1))
+		if err == nil {
+			sqrtPriceBefore = slot0.SqrtPriceX96
+		}
+	}
+	if sqrtPriceBefore != nil && sqrtPriceBefore.Sign() != 0 && sqrtPriceAfter.Sign() != 0 {
+		// price of token0 in token1 is proportional to sqrtPrice^2
+		ratio := big.NewRat(1, 1).SetFrac(
+			big.NewInt(0).Mul(sqrtPriceAfter, sqrtPriceAfter),
+			big.NewInt(0).Mul(sqrtPriceBefore, sqrtPriceBefore))
+		if !zeroForOne {
+			ratio.Inv(ratio)
+		}
+		swap.PriceImpact = ratio.Sub(big.NewRat(1, 1), ratio)
+	}
+	block.sqrtPrices[log.Address] = sqrtPriceAfter
+	ok = true
+	return
+}
+
+func decodeV3Liquidity(log *types.Log) (event model.LiquidityEvent, ok bool) {
+	if len(log.Topics) != 4 {
+		return
+	}
+	event = model.LiquidityEvent{
+		Pool:      log.Address,
+		Owner:     common.BytesToAddress(log.Topics[1].Bytes()),
+		TickLower: math.S256(log.Topics[2].Big()).Int64(),
+		TickUpper: math.S256(log.Topics[3].Big()).Int64(),
+		LogIndex:  log.Index,
+	}
+	switch log.Topics[0] {
+	case MintV3Topic:
+		// sender, amount, amount0, amount1
+		if len(log.Data) != 4*common.HashLength {
+			return
+		}
+		event.Kind = model.LiquidityMint
+		event.Liquidity = wordAt(log.Data, 1)
+		event.Amount0 = wordAt(log.Data, 2)
+		event.Amount1 = wordAt(log.Data, 3)
+	case BurnV3Topic:
+		// amount, amount0, amount1
+		if len(log.Data) != 3*common.HashLength {
+			return
+		}
+		event.Kind = model.LiquidityBurn
+		event.Liquidity = wordAt(log.Data, 0)
+		event.Amount0 = wordAt(log.Data, 1)
+		event.Amount1 = wordAt(log.Data, 2)
+	case CollectV3Topic:
+		// recipient, amount0, amount1
+		if len(log.Data) != 3*common.HashLength {
+			return
+		}
+		event.Kind = model.LiquidityCollect
+		event.Amount0 = wordAt(log.Data, 1)
+		event.Amount1 = wordAt(log.Data, 2)
+	}
+	ok = true
+	return
+}
+
+func (d *SwapDecoder) fillTokens(swap *model.Swap) {
+	in := d.Tokens.Get(swap.TokenIn)
+	out := d.Tokens.Get(swap.TokenOut)
+	swap.SymbolIn, swap.DecimalsIn = in.Symbol, in.Decimals
+	swap.SymbolOut, swap.DecimalsOut = out.Symbol, out.Decimals
+}
+
+// wordAt returns the i-th 32 bytes word of event data as an unsigned integer
+func wordAt(data []byte, i int) *big.Int {
+	return big.NewInt(0).SetBytes(data[i*common.HashLength : (i+1)*common.HashLength])
+}
@@ -0,0 +1,202 @@
+package dex
+
+import (
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/latifrons/etherxray/decoder"
+	"github.com/latifrons/etherxray/middleware"
+	"github.com/latifrons/etherxray/model"
+	"github.com/latifrons/etherxray/token"
+	"github.com/latifrons/etherxray/tools"
+	"github.com/sirupsen/logrus"
+	"math/big"
+	"time"
+)
+
+var PairCreatedTopic = decoder.Topic("PairCreated(address,address,address,uint256)")
+
+const (
+	// LogChunkBlocks is the block range of a single eth_getLogs request
+	LogChunkBlocks = 2000
+	// saveEveryPairs is how often the store is flushed while enumerating
+	saveEveryPairs = 200
+)
+
+// PairCrawler enumerates every pair of a Uniswap V2 style factory through allPairs(uint256),
+// then keeps the store current by following PairCreated events
+type PairCrawler struct {
+	RpcWrapper *middleware.RpcWrapper
+	Tokens     *token.Cache
+	Store      *PairStore
+	Factory    common.Address
+	Interval   time.Duration
+
+	quit chan bool
+	done chan bool
+}
+
+func (c *PairCrawler) InitDefault() {
+	c.quit = make(chan bool)
+	c.done = make(chan bool)
+	if c.Interval == 0 {
+		c.Interval = time.Second * 15
+	}
+}
+
+func (c *PairCrawler) Start() {
+	go c.loop()
+}
+
+// Stop waits for the crawler to save its progress
+func (c *PairCrawler) Stop() {
+	close(c.quit)
+	<-c.done
+}
+
+func (c *PairCrawler) Name() string {
+	return fmt.Sprintf("PairCrawler for factory %s", c.Factory.Hex())
+}
+
+func (c *PairCrawler) stopped() bool {
+	select {
+	case <-c.quit:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *PairCrawler) loop() {
+	defer close(c.done)
+	ticker := time.NewTicker(c.Interval)
+	defer ticker.Stop()
+	for {
+		var err error
+		if c.Store.Checkpoint(c.Factory) == 0 {
+			err = c.enumerate()
+		} else {
+			err = c.follow()
+		}
+		if err != nil {
+			logrus.WithError(err).WithField("factory", c.Factory.Hex()).Warn("pair crawler")
+		}
+		select {
+		case <-c.quit:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// enumerate walks allPairs from the last known index up to allPairsLength
+func (c *PairCrawler) enumerate() (err error) {
+	head, err := c.RpcWrapper.BlockHeight(tools.GetContextDefault())
+	if err != nil {
+		return
+	}
+	length, err := c.RpcWrapper.GetAllPairsLength(tools.GetContextDefault(), c.Factory)
+	if err != nil {
+		return
+	}
+	total := length.Uint64()
+	logrus.WithField("factory", c.Factory.Hex()).WithField("from", c.Store.Count(c.Factory)).
+		WithField("total", total).Info("enumerating pairs")
+
+	for index := c.Store.Count(c.Factory); index < total; index++ {
+		if c.stopped() {
+			c.save()
+			return
+		}
+		var address common.Address
+		address, err = c.RpcWrapper.GetListValueByIndexRetAddress(tools.GetContextDefault(), c.Factory, "allPairs", int(index))
+		if err != nil {
+			c.save()
+			return
+		}
+		err = c.addPair(address, index)
+		if err != nil {
+			c.save()
+			return
+		}
+		if index%saveEveryPairs == 0 {
+			c.save()
+		}
+	}
+	// pairs created during the enumeration are picked up from the logs
+	c.Store.SetCheckpoint(c.Factory, head)
+	c.save()
+	return
+}
+
+// follow scans PairCreated events since the checkpoint
+func (c *PairCrawler) follow() (err error) {
+	head, err := c.RpcWrapper.BlockHeight(tools.GetContextDefault())
+	if err != nil {
+		return
+	}
+	for from := c.Store.Checkpoint(c.Factory) + 1; from <= head; from += LogChunkBlocks {
+		if c.stopped() {
+			break
+		}
+		to := from + LogChunkBlocks - 1
+		if to > head {
+			to = head
+		}
+		logs, er := c.RpcWrapper.GetTradeLogFromTo(tools.GetContext(30), from, to, PairCreatedTopic, []common.Address{c.Factory})
+		if er != nil {
+			err = er
+			break
+		}
+		for _, log := range logs {
+			// PairCreated(address indexed token0, address indexed token1, address pair, uint)
+			if len(log.Topics) != 3 || len(log.Data) != 2*common.HashLength {
+				continue
+			}
+			pair := &model.Pair{
+				Address: common.BytesToAddress(log.Data[:common.HashLength]),
+				Factory: c.Factory,
+				Index:   big.NewInt(0).SetBytes(log.Data[common.HashLength:]).Uint64() - 1,
+				Token0:  common.BytesToAddress(log.Topics[1].Bytes()),
+				Token1:  common.BytesToAddress(log.Topics[2].Bytes()),
+
+				Protocol: model.ProtocolUniswapV2,
+			}
+			c.Tokens.Get(pair.Token0)
+			c.Tokens.Get(pair.Token1)
+			c.Store.Add(pair)
+		}
+		c.Store.SetCheckpoint(c.Factory, to)
+	}
+	c.save()
+	return
+}
+
+func (c *PairCrawler) save() {
+	c.Store.Save()
+	c.Tokens.Flush()
+}
+
+func (c *PairCrawler) addPair(address common.Address, index uint64) (err error) {
+	token0, err := c.RpcWrapper.GetValueRetAddress(tools.GetContextDefault(), address, "token0")
+	if err != nil {
+		return
+	}
+	token1, err := c.RpcWrapper.GetValueRetAddress(tools.GetContextDefault(), address, "token1")
+	if err != nil {
+		return
+	}
+	// warm the token metadata cache
+	c.Tokens.Get(token0)
+	c.Tokens.Get(token1)
+
+	c.Store.Add(&model.Pair{
+		Address: address,
+		Factory: c.Factory,
+		Index:   index,
+		Token0:  token0,
+		Token1:  token1,
+
+		Protocol: model.ProtocolUniswapV2,
+	})
+	return
+}
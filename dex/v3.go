@@ -0,0 +1,58 @@
+package dex
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/latifrons/etherxray/model"
+	"github.com/latifrons/etherxray/token"
+	"github.com/latifrons/etherxray/tools"
+	"math/big"
+)
+
+// Q192 is 2^192, the scale of a squared sqrtPriceX96
+var Q192 = big.NewInt(0).Lsh(big.NewInt(1), 192)
+
+// V3Reader reads Uniswap V3 pool state
+type V3Reader struct {
+	Pools  *Pools
+	Tokens *token.Cache
+}
+
+// PoolState reads slot0, liquidity, tick spacing and fee of the pool at the given height
+func (v *V3Reader) PoolState(pool common.Address, height uint64) (state *model.V3PoolState, err error) {
+	token0, token1, err := v.Pools.Tokens(pool)
+	if err != nil {
+		return
+	}
+	resp, err := v.Pools.RpcWrapper.GetUniswapV3Pool(tools.GetContextDefault(), pool, big.NewInt(0).SetUint64(height))
+	if err != nil {
+		return
+	}
+	state = &model.V3PoolState{
+		Address:      pool,
+		Height:       height,
+		Token0:       token0,
+		Token1:       token1,
+		Fee:          resp.Fee.Uint64(),
+		TickSpacing:  resp.TickSpacing.Int64(),
+		Liquidity:    resp.Liquidity,
+		SqrtPriceX96: resp.Slot0.SqrtPriceX96,
+		Tick:         resp.Slot0.Tick.Int64(),
+	}
+	if resp.Slot0.SqrtPriceX96.Sign() != 0 {
+		state.Price0 = SqrtPriceX96ToPrice(resp.Slot0.SqrtPriceX96, v.Tokens.Get(token0).Decimals, v.Tokens.Get(token1).Decimals)
+		state.Price1 = big.NewRat(1, 1).Inv(state.Price0)
+	}
+	return
+}
+
+// SqrtPriceX96ToPrice converts a V3 sqrtPriceX96 to the price of token0 in token1, in token units
+func SqrtPriceX96ToPrice(sqrtPriceX96 *big.Int, decimals0 uint8, decimals1 uint8) *big.Rat {
+	squared := big.NewInt(0).Mul(sqrtPriceX96, sqrtPriceX96)
+	price := big.NewRat(1, 1).SetFrac(squared, Q192)
+	// raw price is token1 wei per token0 wei
+	scale := big.NewInt(0).Exp(big.NewInt(10), big.NewInt(int64(decimals0)), nil)
+	price.Mul(price, big.NewRat(1, 1).SetInt(scale))
+	scale = big.NewInt(0).Exp(big.NewInt(10), big.NewInt(int64(decimals1)), nil)
+	price.Quo(price, big.NewRat(1, 1).SetInt(scale))
+	return price
+}
@@ -0,0 +1,128 @@
+package dex
+
+import (
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/latifrons/etherxray/decoder"
+	"github.com/latifrons/etherxray/middleware"
+	"github.com/latifrons/etherxray/model"
+	"github.com/latifrons/etherxray/token"
+	"github.com/latifrons/etherxray/tools"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+var PoolCreatedTopic = decoder.Topic("PoolCreated(address,address,uint24,int24,address)")
+
+// saveEveryChunks is how often the store is flushed while scanning logs
+const saveEveryChunks = 50
+
+// PoolCrawler collects every pool of a Uniswap V3 style factory from its PoolCreated events.
+// V3 factories cannot be enumerated so the scan starts at the factory deployment block.
+type PoolCrawler struct {
+	RpcWrapper *middleware.RpcWrapper
+	Tokens     *token.Cache
+	Store      *PairStore
+	Factory    common.Address
+	StartBlock uint64
+	Interval   time.Duration
+
+	quit chan bool
+	done chan bool
+}
+
+func (c *PoolCrawler) InitDefault() {
+	c.quit = make(chan bool)
+	c.done = make(chan bool)
+	if c.Interval == 0 {
+		c.Interval = time.Second * 15
+	}
+}
+
+func (c *PoolCrawler) Start() {
+	go c.loop()
+}
+
+// Stop waits for the crawler to save its progress
+func (c *PoolCrawler) Stop() {
+	close(c.quit)
+	<-c.done
+}
+
+func (c *PoolCrawler) Name() string {
+	return fmt.Sprintf("PoolCrawler for factory %s", c.Factory.Hex())
+}
+
+func (c *PoolCrawler) loop() {
+	defer close(c.done)
+	ticker := time.NewTicker(c.Interval)
+	defer ticker.Stop()
+	for {
+		err := c.follow()
+		if err != nil {
+			logrus.WithError(err).WithField("factory", c.Factory.Hex()).Warn("pool crawler")
+		}
+		select {
+		case <-c.quit:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// follow scans PoolCreated events since the checkpoint
+func (c *PoolCrawler) follow() (err error) {
+	head, err := c.RpcWrapper.BlockHeight(tools.GetContextDefault())
+	if err != nil {
+		return
+	}
+	from := c.Store.Checkpoint(c.Factory) + 1
+	if from < c.StartBlock {
+		from = c.StartBlock
+	}
+	for chunks := 1; from <= head; from, chunks = from+LogChunkBlocks, chunks+1 {
+		select {
+		case <-c.quit:
+			c.save()
+			return
+		default:
+		}
+		to := from + LogChunkBlocks - 1
+		if to > head {
+			to = head
+		}
+		logs, er := c.RpcWrapper.GetTradeLogFromTo(tools.GetContext(30), from, to, PoolCreatedTopic, []common.Address{c.Factory})
+		if er != nil {
+			err = er
+			break
+		}
+		for _, log := range logs {
+			// PoolCreated(address indexed token0, address indexed token1, uint24 indexed fee, int24 tickSpacing, address pool)
+			if len(log.Topics) != 4 || len(log.Data) != 2*common.HashLength {
+				continue
+			}
+			pool := &model.Pair{
+				Address:  common.BytesToAddress(log.Data[common.HashLength:]),
+				Factory:  c.Factory,
+				Token0:   common.BytesToAddress(log.Topics[1].Bytes()),
+				Token1:   common.BytesToAddress(log.Topics[2].Bytes()),
+				Protocol: model.ProtocolUniswapV3,
+				Fee:      log.Topics[3].Big().Uint64(),
+			}
+			c.Tokens.Get(pool.Token0)
+			c.Tokens.Get(pool.Token1)
+			c.Store.Add(pool)
+		}
+		c.Store.SetCheckpoint(c.Factory, to)
+		if chunks%saveEveryChunks == 0 {
+			c.save()
+		}
+	}
+	c.save()
+	return
+}
+
+func (c *PoolCrawler) save() {
+	c.Store.Save()
+	c.Tokens.Flush()
+}
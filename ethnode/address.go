@@ -0,0 +1,51 @@
+package ethnode
+
+import (
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/latifrons/etherxray/model"
+)
+
+// MaxAddressScanBlocks limits how many blocks a single address query may trace
+const MaxAddressScanBlocks = 100
+
+// GetAddressTxs scans the block range [from, to] for transactions involving the address,
+// either as top level sender or receiver or through an internal transfer.
+func (n *EthNode) GetAddressTxs(address common.Address, from uint64, to uint64) (txs []model.Tx, err error) {
+	if to < from || to-from >= MaxAddressScanBlocks {
+		err = fmt.Errorf("block range must be within %d blocks", MaxAddressScanBlocks)
+		return
+	}
+	for height := from; height <= to; height++ {
+		var blockTxs []model.Tx
+		blockTxs, err = n.getBlockTxs(height, true)
+		if err != nil {
+			return
+		}
+		for _, tx := range blockTxs {
+			if Involves(tx, address) {
+				txs = append(txs, tx)
+			}
+		}
+	}
+	return
+}
+
+// Involves tells whether the address sends or receives value or calls in the transaction
+func Involves(tx model.Tx, address common.Address) bool {
+	if tx.From == address {
+		return true
+	}
+	if tx.BasicTx.To() != nil && *tx.BasicTx.To() == address {
+		return true
+	}
+	if tx.Receipt.ContractAddress == address {
+		return true
+	}
+	for _, transfer := range tx.InternalTransfers {
+		if transfer.From == address || transfer.To == address {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package ethnode
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/latifrons/etherxray/decoder"
+	"github.com/latifrons/etherxray/model"
+	"github.com/latifrons/etherxray/tools"
+	"github.com/sirupsen/logrus"
+	"math/big"
+	"strings"
+)
+
+// GetTxFailure explains why a transaction failed.
+// The call is first replayed with eth_call at the parent block. If that does not reproduce the revert,
+// because earlier transactions of the block changed the state, the trace output is used instead.
+// Without replay only running out of gas is told apart.
+func (n *EthNode) GetTxFailure(tx *types.Transaction, from common.Address, receipt *types.Receipt, replay bool) *model.Failure {
+	if receipt.GasUsed >= tx.Gas() {
+		return &model.Failure{
+			Kind:   decoder.FailureOutOfGas,
+			Reason: "all gas consumed",
+		}
+	}
+	if !replay {
+		return &model.Failure{
+			Kind: decoder.FailureUnknown,
+		}
+	}
+
+	parent := big.NewInt(0).Sub(receipt.BlockNumber, big.NewInt(1))
+	_, revertData, err := n.RpcWrapper.ReplayCall(tools.GetContextDefault(), from, tx, parent)
+	if err != nil && len(revertData) > 0 {
+		kind, reason := n.Registry.DecodeFailure(revertData)
+		return &model.Failure{
+			Kind:   kind,
+			Reason: reason,
+		}
+	}
+
+	frame, err := n.RpcWrapper.TraceTransaction(tools.GetContext(TraceTimeoutSeconds), tx.Hash())
+	if err != nil {
+		logrus.WithError(err).WithField("tx", tx.Hash().Hex()).Debug("failed to trace failed tx")
+		return &model.Failure{
+			Kind: decoder.FailureUnknown,
+		}
+	}
+	if strings.Contains(strings.ToLower(frame.Error), "out of gas") {
+		return &model.Failure{
+			Kind:   decoder.FailureOutOfGas,
+			Reason: frame.Error,
+		}
+	}
+	if len(frame.Output) > 0 {
+		kind, reason := n.Registry.DecodeFailure(frame.Output)
+		return &model.Failure{
+			Kind:   kind,
+			Reason: reason,
+		}
+	}
+	if frame.RevertReason != "" {
+		return &model.Failure{
+			Kind:   decoder.FailureRevert,
+			Reason: frame.RevertReason,
+		}
+	}
+	return &model.Failure{
+		Kind:   decoder.FailureUnknown,
+		Reason: frame.Error,
+	}
+}
@@ -0,0 +1,215 @@
+package ethnode
+
+import (
+	"encoding/json"
+	"github.com/annchain/commongo/files"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/latifrons/etherxray/model"
+	"github.com/latifrons/etherxray/tools"
+	"github.com/sirupsen/logrus"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// BlockHandler receives every new block seen by the Follower.
+// A block replaced by a reorg is handed again with its new hash.
+type BlockHandler interface {
+	Name() string
+	HandleBlock(height uint64, hash common.Hash, txs []model.Tx)
+}
+
+// Follower hands every new enriched block to the handlers.
+// It resumes after the last handled block saved in CursorFile, so that blocks produced while it was down are not missed.
+// On a reorg it steps back to the common ancestor and hands the replacing blocks.
+type Follower struct {
+	EthNode  *EthNode
+	Handlers []BlockHandler
+	Interval time.Duration
+	// Confirmations keeps the follower that many blocks behind the head
+	Confirmations uint64
+	MaxReorgDepth int
+	// CursorFile persists the last handled block. Empty starts at the head on every start.
+	CursorFile string
+
+	quit chan bool
+	done chan bool
+	// recent are the last handled blocks, the newest last, to find the common ancestor of a reorg
+	recent []model.Checkpoint
+}
+
+func (f *Follower) InitDefault() {
+	f.quit = make(chan bool)
+	f.done = make(chan bool)
+	if f.Interval == 0 {
+		f.Interval = time.Second * 5
+	}
+	if f.MaxReorgDepth <= 0 {
+		f.MaxReorgDepth = 64
+	}
+	f.load()
+}
+
+func (f *Follower) Start() {
+	go f.loop()
+}
+
+// Stop waits for the block in progress
+func (f *Follower) Stop() {
+	close(f.quit)
+	<-f.done
+}
+
+func (f *Follower) Name() string {
+	return "Follower"
+}
+
+func (f *Follower) loop() {
+	defer close(f.done)
+	ticker := time.NewTicker(f.Interval)
+	defer ticker.Stop()
+	for {
+		err := f.follow()
+		if err != nil {
+			logrus.WithError(err).Warn("follower")
+		}
+		select {
+		case <-f.quit:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// follow handles the blocks produced since the cursor, starting at the head on the first run
+func (f *Follower) follow() (err error) {
+	head, err := f.EthNode.RpcWrapper.BlockHeight(tools.GetContextDefault())
+	if err != nil {
+		return
+	}
+	if head <= f.Confirmations {
+		return
+	}
+	target := head - f.Confirmations
+	cursor := f.cursor()
+	if cursor == nil {
+		cursor = &model.Checkpoint{Height: target - 1}
+	} else if target > cursor.Height+1 {
+		logrus.WithField("from", cursor.Height+1).WithField("to", target).Info("follower catching up")
+	}
+	for next := cursor.Height + 1; next <= target; next++ {
+		select {
+		case <-f.quit:
+			return
+		default:
+		}
+		header, er := f.EthNode.RpcWrapper.BlockHeader(tools.GetContextDefault(), next)
+		if er != nil {
+			err = er
+			return
+		}
+		if cursor.Hash != (common.Hash{}) && header.ParentHash != cursor.Hash {
+			if cursor, err = f.rewind(); err != nil {
+				return
+			}
+			next = cursor.Height
+			continue
+		}
+		var txs []model.Tx
+		txs, err = f.EthNode.GetBlockTxs(next)
+		if err != nil {
+			return
+		}
+		hash := header.Hash()
+		for _, handler := range f.Handlers {
+			handler.HandleBlock(next, hash, txs)
+		}
+		cursor = &model.Checkpoint{Height: next, Hash: hash}
+		f.advance(*cursor)
+	}
+	return
+}
+
+func (f *Follower) cursor() *model.Checkpoint {
+	if len(f.recent) == 0 {
+		return nil
+	}
+	cursor := f.recent[len(f.recent)-1]
+	return &cursor
+}
+
+func (f *Follower) advance(cursor model.Checkpoint) {
+	f.recent = append(f.recent, cursor)
+	if len(f.recent) > f.MaxReorgDepth {
+		f.recent = f.recent[len(f.recent)-f.MaxReorgDepth:]
+	}
+	f.save()
+}
+
+// rewind drops the handled blocks the node no longer has, and returns the common ancestor
+func (f *Follower) rewind() (ancestor *model.Checkpoint, err error) {
+	orphaned := 0
+	lowest := f.recent[0].Height
+	for len(f.recent) > 0 {
+		last := f.recent[len(f.recent)-1]
+		header, er := f.EthNode.RpcWrapper.BlockHeader(tools.GetContextDefault(), last.Height)
+		if er != nil {
+			err = er
+			return
+		}
+		if header.Hash() == last.Hash {
+			break
+		}
+		f.recent = f.recent[:len(f.recent)-1]
+		orphaned++
+	}
+	ancestor = f.cursor()
+	if ancestor == nil {
+		// deeper than the remembered blocks, resume on the new chain below them
+		logrus.WithField("blocks", orphaned).Error("follower reorg deeper than the remembered blocks")
+		header, er := f.EthNode.RpcWrapper.BlockHeader(tools.GetContextDefault(), lowest-1)
+		if er != nil {
+			err = er
+			return
+		}
+		ancestor = &model.Checkpoint{Height: header.Number.Uint64(), Hash: header.Hash()}
+		f.recent = append(f.recent, *ancestor)
+	} else {
+		logrus.WithField("blocks", orphaned).WithField("ancestor", ancestor.Height).Warn("follower rolled back a reorg")
+	}
+	f.save()
+	return
+}
+
+func (f *Follower) load() {
+	if f.CursorFile == "" || !files.FileExists(f.CursorFile) {
+		return
+	}
+	content, err := ioutil.ReadFile(f.CursorFile)
+	if err == nil {
+		err = json.Unmarshal(content, &f.recent)
+	}
+	if err != nil {
+		logrus.WithError(err).WithField("file", f.CursorFile).Warn("failed to read follower cursor, starting at the head")
+		f.recent = nil
+	}
+}
+
+func (f *Follower) save() {
+	if f.CursorFile == "" {
+		return
+	}
+	content, err := json.Marshal(f.recent)
+	if err != nil {
+		logrus.WithError(err).Warn("failed to marshal follower cursor")
+		return
+	}
+	tmp := f.CursorFile + ".tmp"
+	err = ioutil.WriteFile(tmp, content, 0644)
+	if err == nil {
+		err = os.Rename(tmp, f.CursorFile)
+	}
+	if err != nil {
+		logrus.WithError(err).WithField("file", f.CursorFile).Warn("failed to write follower cursor")
+	}
+}
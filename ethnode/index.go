@@ -0,0 +1,66 @@
+package ethnode
+
+import (
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/latifrons/etherxray/model"
+)
+
+// IndexBlock enriches the block from the upstream and stores it in the local index
+func (n *EthNode) IndexBlock(height uint64) (indexed *model.Block, err error) {
+	indexed, txs, logs, err := n.EnrichIndexBlock(height)
+	if err != nil {
+		return
+	}
+	err = n.Repository.SaveBlock(indexed, txs, logs)
+	return
+}
+
+// EnrichIndexBlock enriches the block from the upstream and returns what the local index stores for it
+func (n *EthNode) EnrichIndexBlock(height uint64) (indexed *model.Block, txs []model.Tx, logs []model.EventLog, err error) {
+	block, txs, _, err := n.enrichBlock(height, n.InternalTransfers)
+	if err != nil {
+		return
+	}
+	indexed = ToIndexedBlock(block)
+	logs = n.EventLogs(height, txs)
+	return
+}
+
+// ToIndexedBlock summarizes the header and transaction list of a block
+func ToIndexedBlock(block *types.Block) *model.Block {
+	indexed := &model.Block{
+		Height:     block.NumberU64(),
+		Hash:       block.Hash(),
+		ParentHash: block.ParentHash(),
+		Timestamp:  block.Time(),
+		Coinbase:   block.Coinbase(),
+	}
+	for _, tx := range block.Transactions() {
+		indexed.TxHashes = append(indexed.TxHashes, tx.Hash())
+	}
+	return indexed
+}
+
+// EventLogs lists the logs of the transactions with their decoded event signature
+func (n *EthNode) EventLogs(height uint64, txs []model.Tx) (logs []model.EventLog) {
+	for _, tx := range txs {
+		for _, log := range tx.Receipt.Logs {
+			eventLog := model.EventLog{
+				Height:   height,
+				TxHash:   log.TxHash,
+				TxIndex:  log.TxIndex,
+				LogIndex: log.Index,
+				Address:  log.Address,
+				Topics:   log.Topics,
+				Data:     log.Data,
+			}
+			if len(log.Topics) > 0 {
+				if sig, ok := n.Registry.Event(log.Topics[0]); ok {
+					eventLog.Event = sig.Text
+				}
+			}
+			logs = append(logs, eventLog)
+		}
+	}
+	return
+}
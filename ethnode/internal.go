@@ -0,0 +1,141 @@
+package ethnode
+
+import (
+	"fmt"
+	"github.com/latifrons/etherxray/middleware"
+	"github.com/latifrons/etherxray/model"
+	"github.com/latifrons/etherxray/tools"
+	"math/big"
+)
+
+const (
+	TraceApiDebug  = "debug"
+	TraceApiParity = "parity"
+)
+
+// GetBlockInternalTransfers extracts the value transfers made inside contract calls,
+// indexed by transaction position in the block
+func (n *EthNode) GetBlockInternalTransfers(height uint64, txCount int) (transfers [][]model.InternalTransfer, err error) {
+	transfers = make([][]model.InternalTransfer, txCount)
+	switch n.TraceApi {
+	case TraceApiParity:
+		var traces []middleware.ParityTrace
+		traces, err = n.RpcWrapper.TraceBlockParity(tools.GetContext(TraceTimeoutSeconds), height)
+		if err != nil {
+			return
+		}
+		extractParityTransfers(traces, transfers)
+	case TraceApiDebug, "":
+		var frames []*model.CallFrame
+		frames, err = n.RpcWrapper.TraceBlock(tools.GetContext(TraceTimeoutSeconds), height)
+		if err != nil {
+			return
+		}
+		for i, frame := range frames {
+			if i >= txCount || frame == nil {
+				continue
+			}
+			transfers[i] = extractFrameTransfers(frame, nil)
+		}
+	default:
+		err = fmt.Errorf("unknown trace api %s", n.TraceApi)
+	}
+	return
+}
+
+func extractFrameTransfers(frame *model.CallFrame, transfers []model.InternalTransfer) []model.InternalTransfer {
+	if frame.Error != "" {
+		// value moved in a reverted frame never happened
+		return transfers
+	}
+	if frame.Depth > 0 && frame.Value != nil && frame.Value.Sign() > 0 {
+		switch frame.Type {
+		case "CALL", "CALLCODE", "CREATE", "CREATE2", "SELFDESTRUCT":
+			transfers = append(transfers, model.InternalTransfer{
+				Type:  frame.Type,
+				From:  frame.From,
+				To:    frame.To,
+				Value: frame.Value,
+				Depth: frame.Depth,
+			})
+		}
+	}
+	for _, call := range frame.Calls {
+		transfers = extractFrameTransfers(call, transfers)
+	}
+	return transfers
+}
+
+func extractParityTransfers(traces []middleware.ParityTrace, transfers [][]model.InternalTransfer) {
+	// errored traces revert their whole subtree
+	reverted := make(map[string]bool)
+	for _, trace := range traces {
+		if trace.TransactionPosition == nil || *trace.TransactionPosition >= len(transfers) {
+			continue
+		}
+		position := *trace.TransactionPosition
+		if isParityReverted(reverted, position, trace.TraceAddress) {
+			continue
+		}
+		if trace.Error != "" {
+			reverted[parityTraceKey(position, trace.TraceAddress)] = true
+			continue
+		}
+		if len(trace.TraceAddress) == 0 {
+			// top level call, already in the transaction itself
+			continue
+		}
+
+		transfer := model.InternalTransfer{
+			From:  trace.Action.From,
+			To:    trace.Action.To,
+			Depth: len(trace.TraceAddress),
+		}
+		var value *big.Int
+		switch trace.Type {
+		case "call":
+			if trace.Action.CallType == "delegatecall" || trace.Action.CallType == "staticcall" {
+				continue
+			}
+			transfer.Type = "CALL"
+			if trace.Action.Value != nil {
+				value = trace.Action.Value.ToInt()
+			}
+		case "create":
+			transfer.Type = "CREATE"
+			if trace.Result != nil {
+				transfer.To = trace.Result.Address
+			}
+			if trace.Action.Value != nil {
+				value = trace.Action.Value.ToInt()
+			}
+		case "suicide":
+			transfer.Type = "SELFDESTRUCT"
+			transfer.From = trace.Action.Address
+			transfer.To = trace.Action.RefundAddress
+			if trace.Action.Balance != nil {
+				value = trace.Action.Balance.ToInt()
+			}
+		default:
+			continue
+		}
+		if value == nil || value.Sign() <= 0 {
+			continue
+		}
+		transfer.Value = value
+		transfers[position] = append(transfers[position], transfer)
+	}
+}
+
+func parityTraceKey(position int, traceAddress []int) string {
+	return fmt.Sprint(position, traceAddress)
+}
+
+func isParityReverted(reverted map[string]bool, position int, traceAddress []int) bool {
+	for i := 0; i < len(traceAddress); i++ {
+		if reverted[parityTraceKey(position, traceAddress[:i])] {
+			return true
+		}
+	}
+	return false
+}
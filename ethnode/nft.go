@@ -0,0 +1,219 @@
+package ethnode
+
+import (
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/latifrons/etherxray/decoder"
+	"github.com/latifrons/etherxray/model"
+	"github.com/latifrons/etherxray/tools"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+// MaxNftScanBlocks limits the block range of a token history query
+const MaxNftScanBlocks = 100000
+
+var (
+	TransferSingleTopic = decoder.Topic("TransferSingle(address,address,address,uint256,uint256)")
+	TransferBatchTopic  = decoder.Topic("TransferBatch(address,address,address,uint256[],uint256[])")
+)
+
+var transferSingleArgs, transferBatchArgs = func() (abi.Arguments, abi.Arguments) {
+	uint256, _ := abi.NewType("uint256", "", nil)
+	uint256Arr, _ := abi.NewType("uint256[]", "", nil)
+	return abi.Arguments{{Type: uint256}, {Type: uint256}}, abi.Arguments{{Type: uint256Arr}, {Type: uint256Arr}}
+}()
+
+// GetNftTransfers decodes ERC-721 Transfer and ERC-1155 TransferSingle and TransferBatch events of a receipt
+func (n *EthNode) GetNftTransfers(receipt *types.Receipt) (transfers []model.NftTransfer) {
+	for _, log := range receipt.Logs {
+		decoded := decodeNftLog(log)
+		if len(decoded) == 0 {
+			continue
+		}
+		info := n.Tokens.GetCollection(log.Address)
+		for i := range decoded {
+			decoded[i].Collection = info.Name
+		}
+		transfers = append(transfers, decoded...)
+	}
+	return
+}
+
+func decodeNftLog(log *types.Log) (transfers []model.NftTransfer) {
+	if len(log.Topics) == 0 {
+		return
+	}
+	switch {
+	case log.Topics[0] == TransferTopic && len(log.Topics) == 4:
+		transfers = append(transfers, model.NftTransfer{
+			Contract: log.Address,
+			Standard: model.NftStandardErc721,
+			From:     common.BytesToAddress(log.Topics[1].Bytes()),
+			To:       common.BytesToAddress(log.Topics[2].Bytes()),
+			TokenId:  log.Topics[3].Big(),
+			Amount:   big.NewInt(1),
+			LogIndex: log.Index,
+		})
+	case log.Topics[0] == TransferSingleTopic && len(log.Topics) == 4:
+		values, err := transferSingleArgs.UnpackValues(log.Data)
+		if err != nil {
+			return
+		}
+		transfers = append(transfers, model.NftTransfer{
+			Contract: log.Address,
+			Standard: model.NftStandardErc1155,
+			Operator: common.BytesToAddress(log.Topics[1].Bytes()),
+			From:     common.BytesToAddress(log.Topics[2].Bytes()),
+			To:       common.BytesToAddress(log.Topics[3].Bytes()),
+			TokenId:  values[0].(*big.Int),
+			Amount:   values[1].(*big.Int),
+			LogIndex: log.Index,
+		})
+	case log.Topics[0] == TransferBatchTopic && len(log.Topics) == 4:
+		values, err := transferBatchArgs.UnpackValues(log.Data)
+		if err != nil {
+			return
+		}
+		ids := values[0].([]*big.Int)
+		amounts := values[1].([]*big.Int)
+		for i := 0; i < len(ids) && i < len(amounts); i++ {
+			transfers = append(transfers, model.NftTransfer{
+				Contract: log.Address,
+				Standard: model.NftStandardErc1155,
+				Operator: common.BytesToAddress(log.Topics[1].Bytes()),
+				From:     common.BytesToAddress(log.Topics[2].Bytes()),
+				To:       common.BytesToAddress(log.Topics[3].Bytes()),
+				TokenId:  ids[i],
+				Amount:   amounts[i],
+				LogIndex: log.Index,
+			})
+		}
+	}
+	return
+}
+
+// NftHistoryItem is one transfer of a token together with where it happened
+type NftHistoryItem struct {
+	Height   uint64
+	TxHash   common.Hash
+	Transfer model.NftTransfer
+}
+
+// NftOwner holds the token at the end of the scanned range. SinceHeight and TxHash are zero when the owner received
+// the token before the range.
+type NftOwner struct {
+	Owner       common.Address
+	Amount      *big.Int
+	SinceHeight uint64
+	TxHash      common.Hash
+}
+
+// GetNftHistory scans the block range [from, to] for the transfers of one token of a collection and reads its owners
+// at the end of the range
+func (n *EthNode) GetNftHistory(contract common.Address, tokenId *big.Int, from uint64, to uint64) (info *model.TokenInfo, items []NftHistoryItem, owners []NftOwner, err error) {
+	if to < from || to-from >= MaxNftScanBlocks {
+		err = fmt.Errorf("block range must be within %d blocks", MaxNftScanBlocks)
+		return
+	}
+	info = n.Tokens.GetCollection(contract)
+
+	var filters [][][]common.Hash
+	if info.IsErc1155 {
+		// the ERC-1155 token ids are in the data, not in a topic
+		filters = [][][]common.Hash{{{TransferSingleTopic}}, {{TransferBatchTopic}}}
+	} else {
+		filters = [][][]common.Hash{{{TransferTopic}, nil, nil, {common.BigToHash(tokenId)}}}
+	}
+	var logs []types.Log
+	for _, topics := range filters {
+		var topicLogs []types.Log
+		topicLogs, err = n.RpcWrapper.GetLogsFromTo(tools.GetContext(TraceTimeoutSeconds), from, to, topics, []common.Address{contract})
+		if err != nil {
+			return
+		}
+		logs = append(logs, topicLogs...)
+	}
+
+	for i := range logs {
+		log := logs[i]
+		for _, transfer := range decodeNftLog(&log) {
+			if transfer.TokenId.Cmp(tokenId) != 0 {
+				continue
+			}
+			transfer.Collection = info.Name
+			items = append(items, NftHistoryItem{
+				Height:   log.BlockNumber,
+				TxHash:   log.TxHash,
+				Transfer: transfer,
+			})
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Height != items[j].Height {
+			return items[i].Height < items[j].Height
+		}
+		return items[i].Transfer.LogIndex < items[j].Transfer.LogIndex
+	})
+
+	if info.IsErc1155 {
+		owners, err = n.nftHolders(contract, tokenId, items, to)
+	} else {
+		owners, err = n.nftOwner(contract, tokenId, items, to)
+	}
+	return
+}
+
+// nftOwner reads the ERC-721 owner at the height, none when the token is burned or not minted yet
+func (n *EthNode) nftOwner(contract common.Address, tokenId *big.Int, items []NftHistoryItem, height uint64) (owners []NftOwner, err error) {
+	owner, err := n.RpcWrapper.GetNftOwner(tools.GetContextDefault(), contract, tokenId, big.NewInt(0).SetUint64(height))
+	if err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "revert") {
+			err = nil
+		}
+		return
+	}
+	if owner == (common.Address{}) {
+		return
+	}
+	owners = append(owners, lastReceived(NftOwner{Owner: owner, Amount: big.NewInt(1)}, items))
+	return
+}
+
+// nftHolders reads the ERC-1155 balance at the height of every account the token moved from or to in the range.
+// Holders whose balance did not move in the range cannot be enumerated and are left out.
+func (n *EthNode) nftHolders(contract common.Address, tokenId *big.Int, items []NftHistoryItem, height uint64) (owners []NftOwner, err error) {
+	seen := make(map[common.Address]bool)
+	for _, item := range items {
+		for _, account := range []common.Address{item.Transfer.From, item.Transfer.To} {
+			if account == (common.Address{}) || seen[account] {
+				continue
+			}
+			seen[account] = true
+			var balance *big.Int
+			balance, err = n.RpcWrapper.GetNftBalance(tools.GetContextDefault(), contract, account, tokenId, big.NewInt(0).SetUint64(height))
+			if err != nil {
+				return
+			}
+			if balance.Sign() > 0 {
+				owners = append(owners, lastReceived(NftOwner{Owner: account, Amount: balance}, items))
+			}
+		}
+	}
+	return
+}
+
+// lastReceived sets where the owner last received the token in the range
+func lastReceived(owner NftOwner, items []NftHistoryItem) NftOwner {
+	for i := len(items) - 1; i >= 0; i-- {
+		if items[i].Transfer.To == owner.Owner {
+			owner.SinceHeight = items[i].Height
+			owner.TxHash = items[i].TxHash
+			break
+		}
+	}
+	return owner
+}
@@ -2,24 +2,86 @@ package ethnode
 
 import (
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/latifrons/etherxray/classifier"
+	"github.com/latifrons/etherxray/decoder"
+	"github.com/latifrons/etherxray/dex"
+	"github.com/latifrons/etherxray/metrics"
+	"github.com/latifrons/etherxray/mev"
 	"github.com/latifrons/etherxray/middleware"
 	"github.com/latifrons/etherxray/model"
+	"github.com/latifrons/etherxray/pricing"
+	"github.com/latifrons/etherxray/rating"
+	"github.com/latifrons/etherxray/store"
+	"github.com/latifrons/etherxray/token"
 	"github.com/latifrons/etherxray/tools"
+	"github.com/sirupsen/logrus"
 	"math/big"
 )
 
 type EthNode struct {
 	RpcWrapper *middleware.RpcWrapper
 	Signer     types.EIP155Signer
+	Registry   *decoder.Registry
+	Classifier *classifier.Classifier
+	Rater      *rating.Engine
+	Tokens     *token.Cache
+	Swaps      *dex.SwapDecoder
+	// Pricing is nil when USD values are disabled
+	Pricing *pricing.Service
+	Mev     *mev.Analyzer
+	// Repository is nil when the local index is disabled
+	Repository store.Repository
+
+	// TraceApi selects how internal transfers are traced: "debug" or "parity"
+	TraceApi          string
+	InternalTransfers bool
+	// FailureReasons replays failed transactions to decode why they reverted, one or two calls per failed transaction
+	FailureReasons bool
 }
 
+// GetBlockTxs returns the enriched transactions of the block, from the local index when it holds the block
 func (n *EthNode) GetBlockTxs(height uint64) (txs []model.Tx, err error) {
-	block, erro := n.RpcWrapper.BlockTxs(tools.GetContextDefault(), height)
-	if erro != nil {
-		err = erro
+	if _, txs, ok := n.indexedBlock(height); ok {
+		return txs, nil
+	}
+	return n.getBlockTxs(height, n.InternalTransfers)
+}
+
+// indexedBlock reads the block from the local index, ok is false when the index does not hold it
+func (n *EthNode) indexedBlock(height uint64) (block *model.Block, txs []model.Tx, ok bool) {
+	if n.Repository == nil {
+		return
+	}
+	block, txs, err := n.Repository.GetBlock(height)
+	metrics.Cache("index", err == nil)
+	if err != nil && err != store.ErrNotFound {
+		logrus.WithError(err).WithField("height", height).Warn("failed to read block from index")
+	}
+	ok = err == nil
+	return
+}
+
+func (n *EthNode) getBlockTxs(height uint64, withInternalTransfers bool) (txs []model.Tx, err error) {
+	_, txs, _, err = n.enrichBlock(height, withInternalTransfers)
+	return
+}
+
+// enrichBlock fetches the block from the upstream and decorates its transactions, marking their MEV roles
+// from the returned analysis
+func (n *EthNode) enrichBlock(height uint64, withInternalTransfers bool) (block *types.Block, txs []model.Tx, blockMev *model.BlockMev, err error) {
+	block, err = n.RpcWrapper.BlockTxs(tools.GetContextDefault(), height)
+	if err != nil {
 		return
 	}
-	for _, tx := range block.Transactions() {
+	var internalTransfers [][]model.InternalTransfer
+	if withInternalTransfers {
+		internalTransfers, err = n.GetBlockInternalTransfers(height, len(block.Transactions()))
+		if err != nil {
+			return
+		}
+	}
+	blockSwaps := n.Swaps.NewBlock(height)
+	for i, tx := range block.Transactions() {
 		var receipt *types.Receipt
 		receipt, err = n.RpcWrapper.BlockTxReceipts(tools.GetContextDefault(), tx.Hash())
 		if err != nil {
@@ -30,13 +92,39 @@ func (n *EthNode) GetBlockTxs(height uint64) (txs []model.Tx, err error) {
 		gasCost.Mul(gasCost, tx.GasPrice())
 		sender, _ := types.Sender(n.Signer, tx)
 
-		txs = append(txs, model.Tx{
+		mtx := model.Tx{
 			BasicTx: tx,
 			Receipt: receipt,
 			GasCost: gasCost,
 			From:    sender,
-			Rating:  receipt.GasUsed / 21000 / 5,
-		})
+
+			TokenTransfers: n.GetTokenTransfers(receipt),
+			NftTransfers:   n.GetNftTransfers(receipt),
+		}
+		mtx.Swaps, mtx.LiquidityEvents = n.Swaps.Decode(blockSwaps, receipt)
+		if withInternalTransfers {
+			mtx.InternalTransfers = internalTransfers[i]
+		}
+		if receipt.Status != types.ReceiptStatusSuccessful {
+			mtx.Failure = n.GetTxFailure(tx, sender, receipt, n.FailureReasons)
+		}
+		mtx.Tags = n.Classifier.Classify(&mtx)
+		txs = append(txs, mtx)
+	}
+	if n.Pricing != nil {
+		n.fillUsdValues(txs, height)
+	}
+	blockMev = n.Mev.Analyze(txs, height, block.Coinbase())
+	mev.Mark(txs, blockMev)
+	n.Rater.RateBlock(txs, block.Coinbase())
+	return
+}
+
+// GetBlockMev returns the MEV patterns found in the block, the block is analyzed once
+func (n *EthNode) GetBlockMev(height uint64) (blockMev *model.BlockMev, err error) {
+	if block, txs, ok := n.indexedBlock(height); ok {
+		return n.Mev.Analyze(txs, height, block.Coinbase), nil
 	}
+	_, _, blockMev, err = n.enrichBlock(height, n.InternalTransfers)
 	return
 }
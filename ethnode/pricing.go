@@ -0,0 +1,27 @@
+package ethnode
+
+import (
+	"github.com/latifrons/etherxray/model"
+	"github.com/latifrons/etherxray/tools"
+	"github.com/sirupsen/logrus"
+	"math/big"
+)
+
+// fillUsdValues prices the ETH and token amounts of the transactions of a block in USD
+func (n *EthNode) fillUsdValues(txs []model.Tx, height uint64) {
+	ethUsd, err := n.Pricing.EthUsd(height)
+	if err != nil {
+		logrus.WithError(err).WithField("height", height).Debug("failed to price ETH")
+		return
+	}
+	for i := range txs {
+		txs[i].EthUsd = ethUsd
+		for j, transfer := range txs[i].TokenTransfers {
+			price, err := n.Pricing.Quote(transfer.Token, height)
+			if err != nil || price.Usd == nil {
+				continue
+			}
+			txs[i].TokenTransfers[j].Usd = big.NewRat(1, 1).Mul(tools.FromDecimals(transfer.Amount, transfer.Decimals), price.Usd)
+		}
+	}
+}
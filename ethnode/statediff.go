@@ -0,0 +1,168 @@
+package ethnode
+
+import (
+	"bytes"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/latifrons/etherxray/decoder"
+	"github.com/latifrons/etherxray/model"
+	"github.com/latifrons/etherxray/tools"
+	"math/big"
+	"sort"
+)
+
+// MaxBalanceSlot is the highest storage slot index probed when looking for an ERC-20 balance mapping
+const MaxBalanceSlot = 20
+
+var TransferTopic = decoder.Topic("Transfer(address,address,uint256)")
+
+// GetTxStateDiff returns the per account changes of the transaction,
+// with ERC-20 balance slot changes translated into token changes where possible
+func (n *EthNode) GetTxStateDiff(hash common.Hash) (changes []*model.AccountChange, err error) {
+	diff, err := n.RpcWrapper.TraceStateDiff(tools.GetContext(TraceTimeoutSeconds), hash)
+	if err != nil {
+		return
+	}
+	receipt, err := n.RpcWrapper.BlockTxReceipts(tools.GetContextDefault(), hash)
+	if err != nil {
+		return
+	}
+
+	holders := make(map[common.Address]bool)
+	for addr := range diff.Pre {
+		holders[addr] = true
+	}
+	for addr := range diff.Post {
+		holders[addr] = true
+	}
+	for _, log := range receipt.Logs {
+		if len(log.Topics) == 3 && log.Topics[0] == TransferTopic {
+			holders[common.BytesToAddress(log.Topics[1].Bytes())] = true
+			holders[common.BytesToAddress(log.Topics[2].Bytes())] = true
+		}
+	}
+
+	byAddress := make(map[common.Address]*model.AccountChange)
+	var tokenChanges []model.TokenChange
+	for addr := range holders {
+		pre, hasPre := diff.Pre[addr]
+		post, hasPost := diff.Post[addr]
+		if !hasPre && !hasPost {
+			continue
+		}
+		if pre == nil {
+			pre = &model.AccountState{Balance: big.NewInt(0)}
+		}
+		if post == nil {
+			// destructed
+			post = &model.AccountState{Balance: big.NewInt(0)}
+		}
+		change := &model.AccountChange{
+			Address:        addr,
+			BalanceBefore:  pre.Balance,
+			BalanceAfter:   post.Balance,
+			NonceBefore:    pre.Nonce,
+			NonceAfter:     post.Nonce,
+			CodeChanged:    !bytes.Equal(pre.Code, post.Code),
+			StorageChanges: storageChanges(pre, post),
+		}
+		tokenChanges = append(tokenChanges, n.tokenChanges(addr, change.StorageChanges, holders)...)
+		byAddress[addr] = change
+	}
+
+	// token changes are reported on the holder rather than on the token contract
+	for _, tokenChange := range tokenChanges {
+		holder, ok := byAddress[tokenChange.Holder]
+		if !ok {
+			holder = &model.AccountChange{
+				Address:       tokenChange.Holder,
+				BalanceBefore: big.NewInt(0),
+				BalanceAfter:  big.NewInt(0),
+			}
+			byAddress[tokenChange.Holder] = holder
+		}
+		holder.TokenChanges = append(holder.TokenChanges, tokenChange)
+	}
+	for _, change := range byAddress {
+		changes = append(changes, change)
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return bytes.Compare(changes[i].Address.Bytes(), changes[j].Address.Bytes()) < 0
+	})
+	return
+}
+
+func storageChanges(pre *model.AccountState, post *model.AccountState) (changes []model.StorageChange) {
+	slots := make(map[common.Hash]bool)
+	for slot := range pre.Storage {
+		slots[slot] = true
+	}
+	for slot := range post.Storage {
+		slots[slot] = true
+	}
+	for slot := range slots {
+		// slots cleared to zero are omitted from post
+		before := pre.Storage[slot]
+		after := post.Storage[slot]
+		if before == after {
+			continue
+		}
+		changes = append(changes, model.StorageChange{
+			Slot:   slot,
+			Before: before,
+			After:  after,
+		})
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return bytes.Compare(changes[i].Slot.Bytes(), changes[j].Slot.Bytes()) < 0
+	})
+	return
+}
+
+// tokenChanges matches changed slots of a contract against the balance mapping slots of known holders.
+// Both solidity (keccak(holder . slot)) and vyper (keccak(slot . holder)) layouts are probed.
+func (n *EthNode) tokenChanges(contract common.Address, storage []model.StorageChange, holders map[common.Address]bool) (changes []model.TokenChange) {
+	if len(storage) == 0 {
+		return
+	}
+	changed := make(map[common.Hash]model.StorageChange)
+	for _, change := range storage {
+		changed[change.Slot] = change
+	}
+
+	for holder := range holders {
+		for i := 0; i <= MaxBalanceSlot; i++ {
+			index := common.BigToHash(big.NewInt(int64(i)))
+			key := common.LeftPadBytes(holder.Bytes(), common.HashLength)
+			candidates := []common.Hash{
+				crypto.Keccak256Hash(key, index.Bytes()),
+				crypto.Keccak256Hash(index.Bytes(), key),
+			}
+			for _, candidate := range candidates {
+				change, ok := changed[candidate]
+				if !ok {
+					continue
+				}
+				changes = append(changes, model.TokenChange{
+					Token:  contract,
+					Holder: holder,
+					Before: change.Before.Big(),
+					After:  change.After.Big(),
+				})
+			}
+		}
+	}
+	if len(changes) == 0 {
+		return
+	}
+
+	info := n.Tokens.Get(contract)
+	if !info.IsErc20 {
+		return nil
+	}
+	for i := range changes {
+		changes[i].Decimals = info.Decimals
+		changes[i].Symbol = info.Symbol
+	}
+	return
+}
@@ -0,0 +1,29 @@
+package ethnode
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/latifrons/etherxray/model"
+	"github.com/latifrons/etherxray/tools"
+)
+
+const TraceTimeoutSeconds = 60
+
+// GetTxTrace returns the call tree of the transaction with method names and revert reasons decoded
+func (n *EthNode) GetTxTrace(hash common.Hash) (frame *model.CallFrame, err error) {
+	frame, err = n.RpcWrapper.TraceTransaction(tools.GetContext(TraceTimeoutSeconds), hash)
+	if err != nil {
+		return
+	}
+	n.annotateFrame(frame)
+	return
+}
+
+func (n *EthNode) annotateFrame(frame *model.CallFrame) {
+	frame.Method = n.Registry.MethodName(frame.Input)
+	if frame.Error != "" && frame.RevertReason == "" && len(frame.Output) > 0 {
+		_, frame.RevertReason = n.Registry.DecodeFailure(frame.Output)
+	}
+	for _, call := range frame.Calls {
+		n.annotateFrame(call)
+	}
+}
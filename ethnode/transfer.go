@@ -0,0 +1,29 @@
+package ethnode
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/latifrons/etherxray/model"
+	"math/big"
+)
+
+// GetTokenTransfers extracts the ERC-20 Transfer events of a receipt with amounts in token units.
+// ERC-721 Transfer events share the signature but index the token id, so they have 4 topics.
+func (n *EthNode) GetTokenTransfers(receipt *types.Receipt) (transfers []model.TokenTransfer) {
+	for _, log := range receipt.Logs {
+		if len(log.Topics) != 3 || log.Topics[0] != TransferTopic || len(log.Data) != common.HashLength {
+			continue
+		}
+		info := n.Tokens.Get(log.Address)
+		transfers = append(transfers, model.TokenTransfer{
+			Token:    log.Address,
+			Symbol:   info.Symbol,
+			Decimals: info.Decimals,
+			From:     common.BytesToAddress(log.Topics[1].Bytes()),
+			To:       common.BytesToAddress(log.Topics[2].Bytes()),
+			Amount:   big.NewInt(0).SetBytes(log.Data),
+			LogIndex: log.Index,
+		})
+	}
+	return
+}
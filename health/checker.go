@@ -0,0 +1,132 @@
+package health
+
+import (
+	"fmt"
+	"github.com/latifrons/etherxray/middleware"
+	"github.com/latifrons/etherxray/model"
+	"github.com/latifrons/etherxray/tools"
+	"sync"
+	"time"
+)
+
+const (
+	// archiveProbeDepth is how far below the head the state is probed, beyond the 128 blocks full nodes keep
+	archiveProbeDepth = 10000
+	// archiveProbeInterval is how often the archive probe is repeated
+	archiveProbeInterval = time.Hour
+)
+
+// Checker reports the health of the upstream node. Results are cached for CacheFor so probes do not load the node.
+type Checker struct {
+	RpcWrapper *middleware.RpcWrapper
+	// MaxHeadAge is the oldest head block a ready node may have
+	MaxHeadAge time.Duration
+	// MaxSyncLag is the most blocks a syncing node may be behind and still be ready
+	MaxSyncLag uint64
+	// Indexer is nil when the indexer is disabled
+	Indexer func() model.IndexerStatus
+	// MaxIndexerLag is the most blocks the indexer may be behind the head, zero leaves the indexer out of readiness
+	MaxIndexerLag uint64
+	CacheFor      time.Duration
+
+	mu       sync.Mutex
+	last     *model.NodeHealth
+	archive  *bool
+	probedAt time.Time
+}
+
+func (c *Checker) InitDefault() {
+	if c.MaxHeadAge == 0 {
+		c.MaxHeadAge = time.Minute * 2
+	}
+	if c.CacheFor == 0 {
+		c.CacheFor = time.Second * 5
+	}
+}
+
+// Check returns the health of the node, from the cache when recent
+func (c *Checker) Check() model.NodeHealth {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.last != nil && time.Since(c.last.CheckedAt) < c.CacheFor {
+		return *c.last
+	}
+	h := c.check()
+	c.last = &h
+	return h
+}
+
+func (c *Checker) check() (h model.NodeHealth) {
+	h.CheckedAt = time.Now()
+	ctx := tools.GetContextDefault()
+	head, err := c.RpcWrapper.BlockHeight(ctx)
+	if err != nil {
+		h.Error = err.Error()
+		h.Reasons = append(h.Reasons, "node unreachable")
+		return
+	}
+	h.Reachable = true
+	h.Head = head
+
+	header, err := c.RpcWrapper.BlockHeader(ctx, head)
+	if err != nil {
+		h.Error = err.Error()
+		h.Reasons = append(h.Reasons, "head block unavailable")
+		return
+	}
+	h.HeadTime = time.Unix(int64(header.Time), 0)
+	h.HeadAge = h.CheckedAt.Sub(h.HeadTime)
+	if h.HeadAge > c.MaxHeadAge {
+		h.Reasons = append(h.Reasons, fmt.Sprintf("head is %s old", h.HeadAge.Truncate(time.Second)))
+	}
+
+	// the remaining calls are informational, some nodes do not serve them
+	if progress, er := c.RpcWrapper.GetSyncProgress(ctx); er == nil && progress != nil {
+		h.Syncing = true
+		h.CurrentBlock = progress.CurrentBlock
+		h.HighestBlock = progress.HighestBlock
+		if progress.HighestBlock > progress.CurrentBlock+c.MaxSyncLag {
+			h.Reasons = append(h.Reasons, fmt.Sprintf("syncing, %d blocks behind", progress.HighestBlock-progress.CurrentBlock))
+		}
+	}
+	if peers, er := c.RpcWrapper.GetPeerCount(ctx); er == nil {
+		h.PeerCount = peers
+	}
+	if version, er := c.RpcWrapper.GetClientVersion(ctx); er == nil {
+		h.ClientVersion = version
+	}
+	h.Archive = c.probeArchive(head)
+	c.checkIndexer(&h)
+	h.Ready = len(h.Reasons) == 0
+	return
+}
+
+// checkIndexer reports an indexer following the head that fell too far behind it.
+// An indexer with a fixed upper bound never catches up with the head and is left out.
+func (c *Checker) checkIndexer(h *model.NodeHealth) {
+	if c.Indexer == nil {
+		return
+	}
+	status := c.Indexer()
+	h.IndexedBlock = status.Indexed
+	if c.MaxIndexerLag == 0 || status.To != 0 {
+		return
+	}
+	if h.Head > status.Indexed+c.MaxIndexerLag {
+		h.Reasons = append(h.Reasons, fmt.Sprintf("indexer is %d blocks behind", h.Head-status.Indexed))
+	}
+}
+
+// probeArchive reads old state to tell archive nodes from full nodes
+func (c *Checker) probeArchive(head uint64) *bool {
+	if c.archive != nil && time.Since(c.probedAt) < archiveProbeInterval {
+		return c.archive
+	}
+	if head <= archiveProbeDepth {
+		return nil
+	}
+	archive := c.RpcWrapper.ProbeState(tools.GetContextDefault(), head-archiveProbeDepth) == nil
+	c.archive = &archive
+	c.probedAt = time.Now()
+	return c.archive
+}
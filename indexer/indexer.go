@@ -0,0 +1,299 @@
+package indexer
+
+import (
+	"errors"
+	"fmt"
+	"github.com/latifrons/etherxray/ethnode"
+	"github.com/latifrons/etherxray/model"
+	"github.com/latifrons/etherxray/store"
+	"github.com/latifrons/etherxray/tools"
+	"github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+// checkpointName is the key the indexer checkpoint is stored under
+const checkpointName = "indexer"
+
+// throughputWindow is the period the commit rate is measured over
+const throughputWindow = time.Minute
+
+// Indexer backfills the blocks from From into the local index, then follows the head.
+// Blocks are enriched concurrently and committed in order. A block whose parent is not the last committed block
+// reveals a reorg, the orphaned blocks are deleted back to the common ancestor.
+type Indexer struct {
+	EthNode    *ethnode.EthNode
+	Repository store.Repository
+	From       uint64
+	// To stops the indexer at that height. Zero follows the head.
+	To uint64
+	// Confirmations keeps the indexer that many blocks behind the head
+	Confirmations uint64
+	Workers       int
+	MaxReorgDepth uint64
+	Interval      time.Duration
+
+	quit       chan bool
+	done       chan bool
+	checkpoint *model.Checkpoint
+
+	mu      sync.RWMutex
+	status  model.IndexerStatus
+	commits []time.Time
+}
+
+type fetched struct {
+	block *model.Block
+	txs   []model.Tx
+	logs  []model.EventLog
+	err   error
+}
+
+func (x *Indexer) InitDefault() {
+	x.quit = make(chan bool)
+	x.done = make(chan bool)
+	if x.Workers <= 0 {
+		x.Workers = 4
+	}
+	if x.MaxReorgDepth == 0 {
+		x.MaxReorgDepth = 64
+	}
+	if x.Interval == 0 {
+		x.Interval = time.Second * 5
+	}
+	checkpoint, err := x.Repository.GetCheckpoint(checkpointName)
+	switch {
+	case err == nil:
+		x.checkpoint = checkpoint
+	case err != store.ErrNotFound:
+		logrus.WithError(err).Fatal("failed to read indexer checkpoint")
+	}
+	x.status = model.IndexerStatus{
+		From: x.From,
+		To:   x.To,
+	}
+	if x.checkpoint != nil {
+		x.status.Indexed = x.checkpoint.Height
+	}
+}
+
+func (x *Indexer) Start() {
+	x.mu.Lock()
+	x.status.StartedAt = time.Now()
+	x.mu.Unlock()
+	go x.loop()
+}
+
+// Stop waits for the blocks being committed
+func (x *Indexer) Stop() {
+	close(x.quit)
+	<-x.done
+}
+
+func (x *Indexer) Name() string {
+	return "Indexer"
+}
+
+// Status returns a snapshot of the indexer progress
+func (x *Indexer) Status() model.IndexerStatus {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.trimCommits(time.Now())
+	status := x.status
+	window := throughputWindow
+	if since := time.Since(status.StartedAt); since < window {
+		window = since
+	}
+	if window > 0 {
+		status.BlocksPerSecond = float64(len(x.commits)) / window.Seconds()
+	}
+	return status
+}
+
+func (x *Indexer) loop() {
+	defer close(x.done)
+	ticker := time.NewTicker(x.Interval)
+	defer ticker.Stop()
+	for {
+		err := x.sync()
+		x.mu.Lock()
+		x.status.LastError = ""
+		if err != nil {
+			x.status.LastError = err.Error()
+		}
+		x.mu.Unlock()
+		if err != nil {
+			logrus.WithError(err).Warn("indexer")
+		}
+		select {
+		case <-x.quit:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sync indexes the blocks after the checkpoint up to the target height
+func (x *Indexer) sync() (err error) {
+	head, err := x.EthNode.RpcWrapper.BlockHeight(tools.GetContextDefault())
+	if err != nil {
+		return
+	}
+	target := x.target(head)
+	x.mu.Lock()
+	x.status.Head = head
+	x.mu.Unlock()
+
+	for next := x.next(); next <= target; next = x.next() {
+		select {
+		case <-x.quit:
+			return
+		default:
+		}
+		last := next + uint64(x.Workers) - 1
+		if last > target {
+			last = target
+		}
+		results := x.fetch(next, last)
+		for _, result := range results {
+			if result.err != nil {
+				err = result.err
+				return
+			}
+			if x.checkpoint != nil && result.block.ParentHash != x.checkpoint.Hash {
+				if err = x.rollback(); err != nil {
+					return
+				}
+				// refetch from the common ancestor
+				break
+			}
+			if err = x.commit(result); err != nil {
+				return
+			}
+		}
+	}
+	x.mu.Lock()
+	x.status.Following = x.To == 0 && x.next() > target
+	x.mu.Unlock()
+	return
+}
+
+// target is the highest height to index with the given head
+func (x *Indexer) target(head uint64) uint64 {
+	target := uint64(0)
+	if head > x.Confirmations {
+		target = head - x.Confirmations
+	}
+	if x.To != 0 && target > x.To {
+		target = x.To
+	}
+	return target
+}
+
+func (x *Indexer) next() uint64 {
+	if x.checkpoint == nil {
+		return x.From
+	}
+	return x.checkpoint.Height + 1
+}
+
+// fetch enriches the blocks in [from, to] concurrently, one block per worker
+func (x *Indexer) fetch(from uint64, to uint64) []fetched {
+	results := make([]fetched, to-from+1)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			result := &results[i]
+			height := from + uint64(i)
+			result.block, result.txs, result.logs, result.err = x.EthNode.EnrichIndexBlock(height)
+			if result.err != nil {
+				result.err = fmt.Errorf("block %d: %v", height, result.err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	return results
+}
+
+// commit stores the block then moves the checkpoint to it.
+// A crash in between leaves the block stored past the checkpoint, it is replaced on restart.
+func (x *Indexer) commit(result fetched) (err error) {
+	if err = x.Repository.SaveBlock(result.block, result.txs, result.logs); err != nil {
+		return
+	}
+	checkpoint := &model.Checkpoint{
+		Height: result.block.Height,
+		Hash:   result.block.Hash,
+	}
+	if err = x.Repository.SaveCheckpoint(checkpointName, checkpoint); err != nil {
+		return
+	}
+	x.checkpoint = checkpoint
+
+	now := time.Now()
+	x.mu.Lock()
+	x.status.Indexed = checkpoint.Height
+	x.commits = append(x.commits, now)
+	x.trimCommits(now)
+	x.mu.Unlock()
+	return
+}
+
+// rollback deletes the committed blocks the upstream no longer has, back to the common ancestor
+func (x *Indexer) rollback() (err error) {
+	var orphaned uint64
+	for x.checkpoint != nil {
+		header, er := x.EthNode.RpcWrapper.BlockHeader(tools.GetContextDefault(), x.checkpoint.Height)
+		if er != nil {
+			err = er
+			return
+		}
+		if header.Hash() == x.checkpoint.Hash {
+			break
+		}
+		if orphaned >= x.MaxReorgDepth {
+			err = fmt.Errorf("reorg deeper than %d blocks", x.MaxReorgDepth)
+			return
+		}
+		if x.checkpoint.Height <= x.From {
+			err = errors.New("reorg reaches the first indexed block")
+			return
+		}
+		if err = x.Repository.DeleteBlock(x.checkpoint.Height); err != nil {
+			return
+		}
+		orphaned++
+		parent, er := x.Repository.GetHeader(x.checkpoint.Height - 1)
+		if er != nil {
+			err = er
+			return
+		}
+		checkpoint := &model.Checkpoint{
+			Height: parent.Height,
+			Hash:   parent.Hash,
+		}
+		if err = x.Repository.SaveCheckpoint(checkpointName, checkpoint); err != nil {
+			return
+		}
+		x.checkpoint = checkpoint
+	}
+	if orphaned == 0 {
+		return
+	}
+	logrus.WithField("blocks", orphaned).WithField("ancestor", x.checkpoint.Height).Warn("indexer rolled back a reorg")
+	x.mu.Lock()
+	x.status.Indexed = x.checkpoint.Height
+	x.status.Reorgs++
+	x.mu.Unlock()
+	return
+}
+
+func (x *Indexer) trimCommits(now time.Time) {
+	i := 0
+	for i < len(x.commits) && now.Sub(x.commits[i]) > throughputWindow {
+		i++
+	}
+	x.commits = x.commits[i:]
+}
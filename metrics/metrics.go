@@ -0,0 +1,128 @@
+package metrics
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/latifrons/etherxray/model"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// registry holds the etherxray metrics next to the Go runtime and process collectors
+var registry = prometheus.NewRegistry()
+
+var (
+	rpcRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "etherxray_upstream_requests_total",
+		Help: "JSON-RPC calls to the node by method and status.",
+	}, []string{"method", "status"})
+	rpcDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "etherxray_upstream_request_duration_seconds",
+		Help:    "Latency of the JSON-RPC calls to the node by method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method"})
+
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "etherxray_http_requests_total",
+		Help: "HTTP requests served by route and status code.",
+	}, []string{"method", "route", "code"})
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "etherxray_http_request_duration_seconds",
+		Help:    "Latency of the HTTP requests by route.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	httpRejected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "etherxray_http_rejected_total",
+		Help: "HTTP requests rejected by the API key and rate limit checks, by route and reason.",
+	}, []string{"route", "reason"})
+
+	cacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "etherxray_cache_requests_total",
+		Help: "Cache lookups by cache and result, hit or miss.",
+	}, []string{"cache", "result"})
+)
+
+func init() {
+	registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		rpcRequests, rpcDuration,
+		httpRequests, httpDuration, httpRejected,
+		cacheRequests,
+		nodeUp, nodeHead, nodeHeadTimestamp, nodeBaseFee, nodeTxPool,
+	)
+}
+
+// Handler serves every registered metric
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRpc records one JSON-RPC call to the node
+func ObserveRpc(method string, duration time.Duration, err error) {
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+	rpcRequests.WithLabelValues(method, status).Inc()
+	rpcDuration.WithLabelValues(method).Observe(duration.Seconds())
+}
+
+// Rejected records one request refused by the API guard
+func Rejected(route string, reason string) {
+	httpRejected.WithLabelValues(route, reason).Inc()
+}
+
+// Cache records one cache lookup
+func Cache(name string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	cacheRequests.WithLabelValues(name, result).Inc()
+}
+
+// GinMiddleware records the HTTP requests by route pattern, so path parameters do not multiply the series
+func GinMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		httpRequests.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		httpDuration.WithLabelValues(c.Request.Method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// WatchIndexer exports the progress of the indexer
+func WatchIndexer(status func() model.IndexerStatus) {
+	registry.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "etherxray_indexer_height",
+			Help: "Last block committed by the indexer.",
+		}, func() float64 {
+			return float64(status().Indexed)
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "etherxray_indexer_lag_blocks",
+			Help: "Blocks between the head and the last block committed by the indexer.",
+		}, func() float64 {
+			s := status()
+			if s.Head < s.Indexed {
+				return 0
+			}
+			return float64(s.Head - s.Indexed)
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "etherxray_indexer_blocks_per_second",
+			Help: "Indexer commit rate over the last minute.",
+		}, func() float64 {
+			return status().BlocksPerSecond
+		}),
+	)
+}
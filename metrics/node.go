@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"github.com/latifrons/etherxray/middleware"
+	"github.com/latifrons/etherxray/tools"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+var (
+	nodeUp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "etherxray_node_up",
+		Help: "Whether the last poll of the node succeeded.",
+	})
+	nodeHead = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "etherxray_node_head_height",
+		Help: "Head block height of the node.",
+	})
+	nodeHeadTimestamp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "etherxray_node_head_timestamp_seconds",
+		Help: "Timestamp of the head block of the node.",
+	})
+	nodeBaseFee = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "etherxray_node_base_fee_gwei",
+		Help: "Base fee of the head block, zero before London.",
+	})
+	nodeTxPool = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "etherxray_node_txpool_transactions",
+		Help: "Transactions in the txpool of the node, pending or queued.",
+	}, []string{"state"})
+)
+
+// NodeCollector polls the node for the head, base fee and txpool gauges, so scrapes do not call the node
+type NodeCollector struct {
+	RpcWrapper *middleware.RpcWrapper
+	Interval   time.Duration
+
+	quit chan bool
+	done chan bool
+}
+
+func (c *NodeCollector) InitDefault() {
+	c.quit = make(chan bool)
+	c.done = make(chan bool)
+	if c.Interval == 0 {
+		c.Interval = time.Second * 15
+	}
+}
+
+func (c *NodeCollector) Start() {
+	go c.loop()
+}
+
+// Stop waits for the poll in progress
+func (c *NodeCollector) Stop() {
+	close(c.quit)
+	<-c.done
+}
+
+func (c *NodeCollector) Name() string {
+	return "NodeCollector"
+}
+
+func (c *NodeCollector) loop() {
+	defer close(c.done)
+	ticker := time.NewTicker(c.Interval)
+	defer ticker.Stop()
+	for {
+		err := c.collect()
+		if err != nil {
+			logrus.WithError(err).Debug("failed to collect node metrics")
+			nodeUp.Set(0)
+		} else {
+			nodeUp.Set(1)
+		}
+		select {
+		case <-c.quit:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *NodeCollector) collect() (err error) {
+	height, err := c.RpcWrapper.BlockHeight(tools.GetContextDefault())
+	if err != nil {
+		return
+	}
+	header, err := c.RpcWrapper.BlockHeader(tools.GetContextDefault(), height)
+	if err != nil {
+		return
+	}
+	nodeHead.Set(float64(height))
+	nodeHeadTimestamp.Set(float64(header.Time))
+
+	baseFee, err := c.RpcWrapper.GetBaseFee(tools.GetContextDefault(), height)
+	if err != nil {
+		return
+	}
+	gwei, _ := tools.FromDecimals(baseFee, 9).Float64()
+	nodeBaseFee.Set(gwei)
+
+	// txpool_status is not served by every node
+	pending, queued, er := c.RpcWrapper.GetTxPoolStatus(tools.GetContextDefault())
+	if er == nil {
+		nodeTxPool.WithLabelValues("pending").Set(float64(pending))
+		nodeTxPool.WithLabelValues("queued").Set(float64(queued))
+	}
+	return
+}
@@ -0,0 +1,71 @@
+package mev
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/latifrons/etherxray/middleware"
+	"github.com/latifrons/etherxray/model"
+	"github.com/latifrons/etherxray/pricing"
+	"github.com/latifrons/etherxray/token"
+	"github.com/latifrons/etherxray/tools"
+	"math/big"
+)
+
+// Analyzer finds MEV patterns in the ordered transactions of an enriched block
+type Analyzer struct {
+	RpcWrapper *middleware.RpcWrapper
+	Tokens     *token.Cache
+	// Pricing values profits and losses in ETH. Without it only token amounts are reported.
+	Pricing *pricing.Service
+}
+
+// Analyze scans the block for MEV patterns
+func (a *Analyzer) Analyze(txs []model.Tx, height uint64, coinbase common.Address) *model.BlockMev {
+	fees := &blockFees{
+		height:   height,
+		coinbase: coinbase,
+	}
+	return &model.BlockMev{
+		Height:       height,
+		Sandwiches:   a.findSandwiches(txs, height),
+		Arbitrages:   a.findArbitrages(txs, fees),
+		Liquidations: a.findLiquidations(txs, fees),
+	}
+}
+
+// Mark records the role of every transaction involved in the detected patterns
+func Mark(txs []model.Tx, blockMev *model.BlockMev) {
+	index := make(map[common.Hash]int)
+	for i, tx := range txs {
+		index[tx.BasicTx.Hash()] = i
+	}
+	mark := func(hash common.Hash, role string) {
+		if i, ok := index[hash]; ok && !txs[i].HasMev(role) {
+			txs[i].Mev = append(txs[i].Mev, role)
+		}
+	}
+	for _, sandwich := range blockMev.Sandwiches {
+		mark(sandwich.FrontTx, model.MevSandwichFront)
+		mark(sandwich.BackTx, model.MevSandwichBack)
+		for _, victim := range sandwich.Victims {
+			mark(victim.Tx, model.MevSandwichVictim)
+		}
+	}
+	for _, arbitrage := range blockMev.Arbitrages {
+		mark(arbitrage.Tx, model.MevArbitrage)
+	}
+	for _, liquidation := range blockMev.Liquidations {
+		mark(liquidation.Tx, model.MevLiquidation)
+	}
+}
+
+// toEth values an amount of token in ETH at the height, nil when it cannot be priced
+func (a *Analyzer) toEth(token common.Address, amount *big.Int, decimals uint8, height uint64) *big.Rat {
+	if a.Pricing == nil || amount == nil {
+		return nil
+	}
+	price, err := a.Pricing.Quote(token, height)
+	if err != nil {
+		return nil
+	}
+	return big.NewRat(1, 1).Mul(tools.FromDecimals(amount, decimals), price.Eth)
+}
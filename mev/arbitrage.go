@@ -0,0 +1,54 @@
+package mev
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/latifrons/etherxray/model"
+	"math/big"
+)
+
+// findArbitrages looks for transactions whose swaps, in log order, form a chain across at least
+// two pools that starts and ends in the same token with more tokens out than in
+func (a *Analyzer) findArbitrages(txs []model.Tx, fees *blockFees) (arbitrages []model.Arbitrage) {
+	for i := range txs {
+		tx := &txs[i]
+		swaps := tx.Swaps
+		if len(swaps) < 2 || !isCycle(swaps) {
+			continue
+		}
+		first, last := swaps[0], swaps[len(swaps)-1]
+		profit := big.NewInt(0).Sub(last.AmountOut, first.AmountIn)
+		if profit.Sign() <= 0 {
+			continue
+		}
+		arbitrage := model.Arbitrage{
+			Tx:       tx.BasicTx.Hash(),
+			Searcher: tx.From,
+			Token:    first.TokenIn,
+			Symbol:   first.SymbolIn,
+			Decimals: first.DecimalsIn,
+			Profit:   profit,
+		}
+		if to := tx.BasicTx.To(); to != nil {
+			arbitrage.Contract = *to
+		}
+		for _, swap := range swaps {
+			arbitrage.Pools = append(arbitrage.Pools, swap.Pool)
+		}
+		arbitrage.ProfitEth = a.toEth(arbitrage.Token, profit, arbitrage.Decimals, fees.height)
+		arbitrage.Cost = a.searcherCost(tx, fees)
+		arbitrage.NetProfitEth = netProfit(arbitrage.ProfitEth, arbitrage.Cost)
+		arbitrages = append(arbitrages, arbitrage)
+	}
+	return
+}
+
+func isCycle(swaps []model.Swap) bool {
+	pools := make(map[common.Address]bool)
+	for i, swap := range swaps {
+		pools[swap.Pool] = true
+		if i > 0 && swaps[i-1].TokenOut != swap.TokenIn {
+			return false
+		}
+	}
+	return len(pools) >= 2 && swaps[len(swaps)-1].TokenOut == swaps[0].TokenIn
+}
@@ -0,0 +1,221 @@
+package mev
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/latifrons/etherxray/model"
+	"math/big"
+	"testing"
+)
+
+var (
+	testPool2    = common.HexToAddress("0x00000000000000000000000000000000000000e2")
+	testCoinbase = common.HexToAddress("0x00000000000000000000000000000000000000f1")
+)
+
+func testSwapIn(pool common.Address, tokenIn common.Address, amountIn int64, tokenOut common.Address, amountOut int64) model.Swap {
+	swap := testSwap(tokenIn, amountIn, tokenOut, amountOut)
+	swap.Pool = pool
+	return swap
+}
+
+// testFees are the fees of a block whose base fee is already known, so no call is made to the node
+func testFees(baseFee int64) *blockFees {
+	return &blockFees{
+		height:   1,
+		coinbase: testCoinbase,
+		baseFee:  big.NewInt(baseFee),
+	}
+}
+
+func TestFindArbitrages(t *testing.T) {
+	tests := []struct {
+		name   string
+		swaps  []model.Swap
+		found  bool
+		profit int64
+	}{
+		{
+			name: "cycle across two pools",
+			swaps: []model.Swap{
+				testSwapIn(testPool, testWeth, 1000, testToken, 10000),
+				testSwapIn(testPool2, testToken, 10000, testWeth, 1050),
+			},
+			found: true, profit: 50,
+		},
+		{
+			name: "cycle at a loss",
+			swaps: []model.Swap{
+				testSwapIn(testPool, testWeth, 1000, testToken, 10000),
+				testSwapIn(testPool2, testToken, 10000, testWeth, 990),
+			},
+		},
+		{
+			name: "round trip in one pool",
+			swaps: []model.Swap{
+				testSwapIn(testPool, testWeth, 1000, testToken, 10000),
+				testSwapIn(testPool, testToken, 10000, testWeth, 1050),
+			},
+		},
+		{
+			name: "broken chain",
+			swaps: []model.Swap{
+				testSwapIn(testPool, testWeth, 1000, testToken, 10000),
+				testSwapIn(testPool2, testVictim, 10000, testWeth, 1050),
+			},
+		},
+		{
+			name: "single swap",
+			swaps: []model.Swap{
+				testSwapIn(testPool, testWeth, 1000, testToken, 10000),
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tx := testTx(0, testAttacker)
+			tx.Swaps = test.swaps
+			tx.Receipt = &types.Receipt{GasUsed: 100000}
+			tx.InternalTransfers = []model.InternalTransfer{}
+
+			a := &Analyzer{}
+			arbitrages := a.findArbitrages([]model.Tx{tx}, testFees(0))
+			if !test.found {
+				if len(arbitrages) != 0 {
+					t.Fatalf("found %d arbitrages, want none", len(arbitrages))
+				}
+				return
+			}
+			if len(arbitrages) != 1 {
+				t.Fatalf("found %d arbitrages, want 1", len(arbitrages))
+			}
+			arbitrage := arbitrages[0]
+			if arbitrage.Profit.Int64() != test.profit {
+				t.Errorf("profit %s, want %d", arbitrage.Profit, test.profit)
+			}
+			if arbitrage.Token != testWeth || arbitrage.Searcher != testAttacker {
+				t.Errorf("token %s searcher %s, want %s and %s", arbitrage.Token.Hex(), arbitrage.Searcher.Hex(), testWeth.Hex(), testAttacker.Hex())
+			}
+			if len(arbitrage.Pools) != len(test.swaps) {
+				t.Errorf("%d pools, want %d", len(arbitrage.Pools), len(test.swaps))
+			}
+			if arbitrage.ProfitEth != nil || arbitrage.NetProfitEth != nil {
+				t.Errorf("profit in ETH %v net %v without pricing, want nil", arbitrage.ProfitEth, arbitrage.NetProfitEth)
+			}
+		})
+	}
+}
+
+func TestSearcherCost(t *testing.T) {
+	tests := []struct {
+		name      string
+		to        common.Address
+		value     int64
+		transfers []model.InternalTransfer
+		baseFee   int64
+		coinbase  int64
+		tip       int64
+	}{
+		{
+			name:    "priority fee only",
+			to:      testPool,
+			baseFee: 30,
+			tip:     100000 * 20,
+		},
+		{
+			name:    "gas price under the base fee tips nothing",
+			to:      testPool,
+			baseFee: 60,
+		},
+		{
+			name:     "direct payment to the coinbase",
+			to:       testCoinbase,
+			value:    7,
+			baseFee:  50,
+			coinbase: 7,
+			tip:      7,
+		},
+		{
+			name: "payment from the searcher contract",
+			to:   testPool,
+			transfers: []model.InternalTransfer{
+				{To: testCoinbase, Value: big.NewInt(5), Depth: 1},
+				{To: testVictim, Value: big.NewInt(9), Depth: 1},
+			},
+			baseFee:  50,
+			coinbase: 5,
+			tip:      5,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tx := model.Tx{
+				BasicTx:           types.NewTransaction(0, test.to, big.NewInt(test.value), 200000, big.NewInt(50), nil),
+				Receipt:           &types.Receipt{GasUsed: 100000},
+				GasCost:           big.NewInt(100000 * 50),
+				InternalTransfers: test.transfers,
+			}
+			if tx.InternalTransfers == nil {
+				tx.InternalTransfers = []model.InternalTransfer{}
+			}
+			a := &Analyzer{}
+			cost := a.searcherCost(&tx, testFees(test.baseFee))
+			if cost.GasCost.Int64() != 100000*50 {
+				t.Errorf("gas cost %s, want %d", cost.GasCost, 100000*50)
+			}
+			if cost.CoinbaseTransfer.Int64() != test.coinbase {
+				t.Errorf("coinbase transfer %s, want %d", cost.CoinbaseTransfer, test.coinbase)
+			}
+			if cost.BuilderTip.Int64() != test.tip {
+				t.Errorf("builder tip %s, want %d", cost.BuilderTip, test.tip)
+			}
+		})
+	}
+}
+
+func TestFramePayments(t *testing.T) {
+	frame := &model.CallFrame{
+		To:    testCoinbase,
+		Value: big.NewInt(100),
+		Calls: []*model.CallFrame{
+			{To: testCoinbase, Value: big.NewInt(3), Depth: 1},
+			{
+				To:    testPool,
+				Value: big.NewInt(0),
+				Depth: 1,
+				Calls: []*model.CallFrame{
+					{To: testCoinbase, Value: big.NewInt(4), Depth: 2},
+				},
+			},
+			{
+				To:    testPool2,
+				Value: big.NewInt(0),
+				Depth: 1,
+				Error: "execution reverted",
+				Calls: []*model.CallFrame{
+					{To: testCoinbase, Value: big.NewInt(50), Depth: 2},
+				},
+			},
+		},
+	}
+	paid := big.NewInt(0)
+	framePayments(frame, testCoinbase, paid)
+	// the top level value is counted from the transaction, reverted frames paid nothing
+	if paid.Int64() != 7 {
+		t.Errorf("paid %s, want 7", paid)
+	}
+}
+
+func TestNetProfit(t *testing.T) {
+	cost := model.SearcherCost{
+		GasCost:          big.NewInt(2e17),
+		CoinbaseTransfer: big.NewInt(1e17),
+	}
+	net := netProfit(big.NewRat(1, 2), cost)
+	if net.Cmp(big.NewRat(1, 5)) != 0 {
+		t.Errorf("net profit %s, want 0.2", net.FloatString(4))
+	}
+	if netProfit(nil, cost) != nil {
+		t.Error("net profit without a price, want nil")
+	}
+}
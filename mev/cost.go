@@ -0,0 +1,95 @@
+package mev
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/latifrons/etherxray/model"
+	"github.com/latifrons/etherxray/tools"
+	"github.com/sirupsen/logrus"
+	"math/big"
+)
+
+// blockFees lazily reads the base fee of the analyzed block
+type blockFees struct {
+	height   uint64
+	coinbase common.Address
+	baseFee  *big.Int
+}
+
+func (a *Analyzer) baseFee(fees *blockFees) *big.Int {
+	if fees.baseFee == nil {
+		baseFee, err := a.RpcWrapper.GetBaseFee(tools.GetContextDefault(), fees.height)
+		if err != nil {
+			logrus.WithError(err).WithField("height", fees.height).Warn("failed to read base fee")
+			baseFee = big.NewInt(0)
+		}
+		fees.baseFee = baseFee
+	}
+	return fees.baseFee
+}
+
+// searcherCost splits what the transaction paid into gas, the builder tip and direct coinbase payments
+func (a *Analyzer) searcherCost(tx *model.Tx, fees *blockFees) (cost model.SearcherCost) {
+	gasUsed := big.NewInt(0).SetUint64(tx.Receipt.GasUsed)
+	price := tx.BasicTx.GasPrice()
+	cost.GasCost = tx.GasCost
+	if cost.GasCost == nil {
+		cost.GasCost = big.NewInt(0).Mul(gasUsed, price)
+	}
+	cost.CoinbaseTransfer = a.coinbasePayments(tx, fees.coinbase)
+
+	priorityFee := big.NewInt(0).Sub(price, a.baseFee(fees))
+	if priorityFee.Sign() < 0 {
+		priorityFee.SetInt64(0)
+	}
+	cost.BuilderTip = priorityFee.Mul(priorityFee, gasUsed)
+	cost.BuilderTip.Add(cost.BuilderTip, cost.CoinbaseTransfer)
+	return
+}
+
+// coinbasePayments sums the ETH the transaction sent to the coinbase. Searchers usually pay from their
+// contract, so without the internal transfers of the enrichment the transaction is traced.
+func (a *Analyzer) coinbasePayments(tx *model.Tx, coinbase common.Address) (paid *big.Int) {
+	paid = big.NewInt(0)
+	if to := tx.BasicTx.To(); to != nil && *to == coinbase {
+		paid.Add(paid, tx.BasicTx.Value())
+	}
+	if tx.InternalTransfers != nil {
+		for _, transfer := range tx.InternalTransfers {
+			if transfer.To == coinbase {
+				paid.Add(paid, transfer.Value)
+			}
+		}
+		return
+	}
+	frame, err := a.RpcWrapper.TraceTransaction(tools.GetContextDefault(), tx.BasicTx.Hash())
+	if err != nil {
+		logrus.WithError(err).WithField("tx", tx.BasicTx.Hash().Hex()).Warn("failed to trace for coinbase payments")
+		return
+	}
+	framePayments(frame, coinbase, paid)
+	return
+}
+
+// framePayments adds the value of the calls to the coinbase below the top level frame to paid
+func framePayments(frame *model.CallFrame, coinbase common.Address, paid *big.Int) {
+	if frame.Error != "" {
+		// value moved in a reverted frame never happened
+		return
+	}
+	if frame.Depth > 0 && frame.To == coinbase && frame.Value != nil {
+		paid.Add(paid, frame.Value)
+	}
+	for _, call := range frame.Calls {
+		framePayments(call, coinbase, paid)
+	}
+}
+
+// netProfit deducts the gas cost and the coinbase transfer from a profit in ETH.
+// The priority fee part of the builder tip is already in the gas cost.
+func netProfit(profitEth *big.Rat, cost model.SearcherCost) *big.Rat {
+	if profitEth == nil {
+		return nil
+	}
+	paid := big.NewInt(0).Add(cost.GasCost, cost.CoinbaseTransfer)
+	return big.NewRat(1, 1).Sub(profitEth, tools.FromWei(paid))
+}
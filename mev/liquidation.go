@@ -0,0 +1,144 @@
+package mev
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/latifrons/etherxray/decoder"
+	"github.com/latifrons/etherxray/model"
+	"github.com/latifrons/etherxray/tools"
+	"math/big"
+)
+
+var (
+	// LiquidationCallTopic is the Aave V2 and V3 liquidation event
+	LiquidationCallTopic = decoder.Topic("LiquidationCall(address,address,address,uint256,uint256,address,bool)")
+	// LiquidateBorrowTopic is emitted by the Compound cToken whose debt is repaid
+	LiquidateBorrowTopic = decoder.Topic("LiquidateBorrow(address,address,uint256,address,uint256)")
+)
+
+// compoundExpScale is the scale of Compound exchange rates
+var compoundExpScale = big.NewInt(1e18)
+
+func (a *Analyzer) findLiquidations(txs []model.Tx, fees *blockFees) (liquidations []model.Liquidation) {
+	for i := range txs {
+		tx := &txs[i]
+		var found []model.Liquidation
+		for _, log := range tx.Receipt.Logs {
+			if len(log.Topics) == 0 {
+				continue
+			}
+			switch log.Topics[0] {
+			case LiquidationCallTopic:
+				if liquidation, ok := a.decodeAave(log); ok {
+					found = append(found, liquidation)
+				}
+			case LiquidateBorrowTopic:
+				if liquidation, ok := a.decodeCompound(log, fees.height); ok {
+					found = append(found, liquidation)
+				}
+			}
+		}
+		if len(found) == 0 {
+			continue
+		}
+		// the transaction cost is shared by its liquidations
+		cost := a.searcherCost(tx, fees)
+		for _, liquidation := range found {
+			liquidation.Tx = tx.BasicTx.Hash()
+			liquidation.Cost = cost
+			collateralEth := a.toEth(liquidation.CollateralToken, liquidation.CollateralSeized, liquidation.CollateralDecimals, fees.height)
+			debtEth := a.toEth(liquidation.DebtToken, liquidation.DebtRepaid, liquidation.DebtDecimals, fees.height)
+			if collateralEth != nil && debtEth != nil {
+				liquidation.ProfitEth = collateralEth.Sub(collateralEth, debtEth)
+				if len(found) == 1 {
+					liquidation.NetProfitEth = netProfit(liquidation.ProfitEth, cost)
+				}
+			}
+			liquidations = append(liquidations, liquidation)
+		}
+	}
+	return
+}
+
+// decodeAave reads LiquidationCall(address indexed collateralAsset, address indexed debtAsset, address indexed user,
+// uint256 debtToCover, uint256 liquidatedCollateralAmount, address liquidator, bool receiveAToken)
+func (a *Analyzer) decodeAave(log *types.Log) (liquidation model.Liquidation, ok bool) {
+	if len(log.Topics) != 4 || len(log.Data) != 4*common.HashLength {
+		return
+	}
+	collateral := a.Tokens.Get(common.BytesToAddress(log.Topics[1].Bytes()))
+	debt := a.Tokens.Get(common.BytesToAddress(log.Topics[2].Bytes()))
+	liquidation = model.Liquidation{
+		Protocol:           model.LendingAave,
+		Liquidator:         common.BytesToAddress(word(log.Data, 2)),
+		Borrower:           common.BytesToAddress(log.Topics[3].Bytes()),
+		DebtToken:          debt.Address,
+		DebtSymbol:         debt.Symbol,
+		DebtDecimals:       debt.Decimals,
+		DebtRepaid:         big.NewInt(0).SetBytes(word(log.Data, 0)),
+		CollateralToken:    collateral.Address,
+		CollateralSymbol:   collateral.Symbol,
+		CollateralDecimals: collateral.Decimals,
+		CollateralSeized:   big.NewInt(0).SetBytes(word(log.Data, 1)),
+		LogIndex:           log.Index,
+	}
+	ok = true
+	return
+}
+
+// decodeCompound reads LiquidateBorrow(address liquidator, address borrower, uint repayAmount,
+// address cTokenCollateral, uint seizeTokens). Seized cTokens are converted to the underlying
+// with the exchange rate of the collateral market.
+func (a *Analyzer) decodeCompound(log *types.Log, height uint64) (liquidation model.Liquidation, ok bool) {
+	if len(log.Topics) != 1 || len(log.Data) != 5*common.HashLength {
+		return
+	}
+	cTokenCollateral := common.BytesToAddress(word(log.Data, 3))
+	exchangeRate, err := a.RpcWrapper.GetValueRetUint(tools.GetContextDefault(), cTokenCollateral, "exchangeRateStored", big.NewInt(0).SetUint64(height))
+	if err != nil {
+		return
+	}
+	debt := a.underlying(log.Address)
+	collateral := a.underlying(cTokenCollateral)
+	seized := big.NewInt(0).SetBytes(word(log.Data, 4))
+	seized.Mul(seized, exchangeRate)
+	seized.Quo(seized, compoundExpScale)
+
+	liquidation = model.Liquidation{
+		Protocol:           model.LendingCompound,
+		Liquidator:         common.BytesToAddress(word(log.Data, 0)),
+		Borrower:           common.BytesToAddress(word(log.Data, 1)),
+		DebtToken:          debt.Address,
+		DebtSymbol:         debt.Symbol,
+		DebtDecimals:       debt.Decimals,
+		DebtRepaid:         big.NewInt(0).SetBytes(word(log.Data, 2)),
+		CollateralToken:    collateral.Address,
+		CollateralSymbol:   collateral.Symbol,
+		CollateralDecimals: collateral.Decimals,
+		CollateralSeized:   seized,
+		LogIndex:           log.Index,
+	}
+	ok = true
+	return
+}
+
+// underlying returns the token a cToken lends. cETH has no underlying() and lends ETH, priced as WETH.
+func (a *Analyzer) underlying(cToken common.Address) *model.TokenInfo {
+	address, err := a.RpcWrapper.GetValueRetAddress(tools.GetContextDefault(), cToken, "underlying")
+	if err == nil && address != (common.Address{}) {
+		return a.Tokens.Get(address)
+	}
+	eth := &model.TokenInfo{
+		Symbol:   "ETH",
+		Decimals: 18,
+		IsErc20:  true,
+	}
+	if a.Pricing != nil {
+		eth.Address = a.Pricing.Oracles.Weth
+	}
+	return eth
+}
+
+func word(data []byte, i int) []byte {
+	return data[i*common.HashLength : (i+1)*common.HashLength]
+}
@@ -0,0 +1,128 @@
+package mev
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/latifrons/etherxray/model"
+	"math/big"
+)
+
+// legTolerance is how far, as a fraction of the tokens bought, the back leg may sell a different amount.
+// Fee-on-transfer tokens and partial exits keep the legs from matching exactly.
+var legTolerance = big.NewRat(1, 10)
+
+type swapRef struct {
+	tx   int
+	swap *model.Swap
+}
+
+// findSandwiches looks in every pool for a swap followed, in a later transaction of the same actor,
+// by a swap selling back about the tokens bought, with swaps of other senders in the front direction in between
+func (a *Analyzer) findSandwiches(txs []model.Tx, height uint64) (sandwiches []model.Sandwich) {
+	var pools []common.Address
+	byPool := make(map[common.Address][]swapRef)
+	for i := range txs {
+		for j := range txs[i].Swaps {
+			swap := &txs[i].Swaps[j]
+			if _, ok := byPool[swap.Pool]; !ok {
+				pools = append(pools, swap.Pool)
+			}
+			byPool[swap.Pool] = append(byPool[swap.Pool], swapRef{tx: i, swap: swap})
+		}
+	}
+
+	for _, pool := range pools {
+		refs := byPool[pool]
+		used := make(map[int]bool)
+		for f, front := range refs {
+			if used[front.tx] {
+				continue
+			}
+			for b := f + 1; b < len(refs); b++ {
+				back := refs[b]
+				if back.tx == front.tx || used[back.tx] ||
+					!legsMatch(front.swap, back.swap) || !sameActor(txs, front, back) {
+					continue
+				}
+				victims := a.victims(txs, refs[f+1:b], front, height)
+				if len(victims) == 0 {
+					continue
+				}
+				sandwiches = append(sandwiches, a.sandwich(txs, front, back, victims, height))
+				used[front.tx] = true
+				used[back.tx] = true
+				break
+			}
+		}
+	}
+	return
+}
+
+// sameActor matches legs sent by the same account, or legs where the contract receiving the
+// bought tokens is the one selling them back
+func sameActor(txs []model.Tx, front swapRef, back swapRef) bool {
+	return txs[front.tx].From == txs[back.tx].From || front.swap.Recipient == back.swap.Sender
+}
+
+// legsMatch requires the back leg to sell the token bought by the front leg, in an amount within legTolerance
+func legsMatch(front *model.Swap, back *model.Swap) bool {
+	if back.TokenIn != front.TokenOut || front.AmountOut == nil || back.AmountIn == nil ||
+		front.AmountOut.Sign() <= 0 || back.AmountIn.Sign() <= 0 {
+		return false
+	}
+	diff := big.NewRat(1, 1).SetInt(big.NewInt(0).Sub(back.AmountIn, front.AmountOut))
+	diff.Abs(diff)
+	bound := big.NewRat(1, 1).Mul(big.NewRat(1, 1).SetInt(front.AmountOut), legTolerance)
+	return diff.Cmp(bound) <= 0
+}
+
+// sandwichProfit is what the back leg returns for the tokens the front leg bought, less the front leg input.
+// The back leg output is scaled by the ratio of the amounts when it did not sell exactly what was bought.
+func sandwichProfit(front *model.Swap, back *model.Swap) *big.Int {
+	returned := big.NewInt(0).Mul(back.AmountOut, front.AmountOut)
+	returned.Quo(returned, back.AmountIn)
+	return returned.Sub(returned, front.AmountIn)
+}
+
+func (a *Analyzer) victims(txs []model.Tx, between []swapRef, front swapRef, height uint64) (victims []model.SandwichVictim) {
+	attacker := txs[front.tx].From
+	for _, ref := range between {
+		if ref.tx == front.tx || txs[ref.tx].From == attacker || ref.swap.TokenIn != front.swap.TokenIn {
+			continue
+		}
+		victim := model.SandwichVictim{
+			Tx:       txs[ref.tx].BasicTx.Hash(),
+			From:     txs[ref.tx].From,
+			Token:    ref.swap.TokenOut,
+			Symbol:   ref.swap.SymbolOut,
+			Decimals: ref.swap.DecimalsOut,
+		}
+		// the victim would have bought amountOut / (1 - impact) without the front leg
+		if impact := front.swap.PriceImpact; impact != nil && impact.Sign() > 0 && impact.Cmp(big.NewRat(1, 1)) < 0 {
+			loss := big.NewRat(1, 1).SetInt(ref.swap.AmountOut)
+			loss.Mul(loss, impact)
+			loss.Quo(loss, big.NewRat(1, 1).Sub(big.NewRat(1, 1), impact))
+			victim.Loss = big.NewInt(0).Quo(loss.Num(), loss.Denom())
+			victim.LossEth = a.toEth(victim.Token, victim.Loss, victim.Decimals, height)
+		}
+		victims = append(victims, victim)
+	}
+	return
+}
+
+func (a *Analyzer) sandwich(txs []model.Tx, front swapRef, back swapRef, victims []model.SandwichVictim, height uint64) model.Sandwich {
+	sandwich := model.Sandwich{
+		Pool:     front.swap.Pool,
+		Protocol: front.swap.Protocol,
+		Attacker: txs[front.tx].From,
+		FrontTx:  txs[front.tx].BasicTx.Hash(),
+		BackTx:   txs[back.tx].BasicTx.Hash(),
+		Token:    front.swap.TokenIn,
+		Symbol:   front.swap.SymbolIn,
+		Decimals: front.swap.DecimalsIn,
+		Profit:   sandwichProfit(front.swap, back.swap),
+		GasCost:  big.NewInt(0).Add(txs[front.tx].GasCost, txs[back.tx].GasCost),
+		Victims:  victims,
+	}
+	sandwich.ProfitEth = a.toEth(sandwich.Token, sandwich.Profit, sandwich.Decimals, height)
+	return sandwich
+}
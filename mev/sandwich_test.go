@@ -0,0 +1,161 @@
+package mev
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/latifrons/etherxray/model"
+	"math/big"
+	"testing"
+)
+
+var (
+	testPool     = common.HexToAddress("0x00000000000000000000000000000000000000e0")
+	testWeth     = common.HexToAddress("0x00000000000000000000000000000000000000a1")
+	testToken    = common.HexToAddress("0x00000000000000000000000000000000000000b1")
+	testAttacker = common.HexToAddress("0x00000000000000000000000000000000000000c1")
+	testVictim   = common.HexToAddress("0x00000000000000000000000000000000000000d1")
+)
+
+// testTx is a transaction of from swapping directly with the pool
+func testTx(nonce uint64, from common.Address, swaps ...model.Swap) model.Tx {
+	for i := range swaps {
+		swaps[i].Sender = from
+		swaps[i].Recipient = from
+	}
+	return model.Tx{
+		BasicTx: types.NewTransaction(nonce, testPool, big.NewInt(0), 100000, big.NewInt(1), nil),
+		From:    from,
+		GasCost: big.NewInt(100000),
+		Swaps:   swaps,
+	}
+}
+
+func testSwap(tokenIn common.Address, amountIn int64, tokenOut common.Address, amountOut int64) model.Swap {
+	return model.Swap{
+		Pool:      testPool,
+		TokenIn:   tokenIn,
+		TokenOut:  tokenOut,
+		AmountIn:  big.NewInt(amountIn),
+		AmountOut: big.NewInt(amountOut),
+	}
+}
+
+func TestFindSandwiches(t *testing.T) {
+	front := testSwap(testWeth, 1000, testToken, 10000)
+	front.PriceImpact = big.NewRat(1, 10)
+	victim := testSwap(testWeth, 500, testToken, 4500)
+
+	tests := []struct {
+		name    string
+		txs     []model.Tx
+		found   bool
+		profit  int64
+		victims int
+	}{
+		{
+			name: "back leg sells what was bought",
+			txs: []model.Tx{
+				testTx(0, testAttacker, front),
+				testTx(1, testVictim, victim),
+				testTx(2, testAttacker, testSwap(testToken, 10000, testWeth, 1100)),
+			},
+			found: true, profit: 100, victims: 1,
+		},
+		{
+			name: "back leg within tolerance is scaled to the amount bought",
+			txs: []model.Tx{
+				testTx(0, testAttacker, front),
+				testTx(1, testVictim, victim),
+				testTx(2, testAttacker, testSwap(testToken, 10500, testWeth, 1155)),
+			},
+			found: true, profit: 100, victims: 1,
+		},
+		{
+			name: "back leg selling half is not a sandwich",
+			txs: []model.Tx{
+				testTx(0, testAttacker, front),
+				testTx(1, testVictim, victim),
+				testTx(2, testAttacker, testSwap(testToken, 5000, testWeth, 580)),
+			},
+		},
+		{
+			name: "back leg selling another token",
+			txs: []model.Tx{
+				testTx(0, testAttacker, front),
+				testTx(1, testVictim, victim),
+				testTx(2, testAttacker, testSwap(testWeth, 10000, testToken, 1100)),
+			},
+		},
+		{
+			name: "no victim in between",
+			txs: []model.Tx{
+				testTx(0, testAttacker, front),
+				testTx(1, testAttacker, testSwap(testToken, 10000, testWeth, 1100)),
+			},
+		},
+		{
+			name: "legs of different actors",
+			txs: []model.Tx{
+				testTx(0, testAttacker, front),
+				testTx(1, testVictim, victim),
+				testTx(2, common.HexToAddress("0xe1"), testSwap(testToken, 10000, testWeth, 1100)),
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a := &Analyzer{}
+			sandwiches := a.findSandwiches(test.txs, 1)
+			if !test.found {
+				if len(sandwiches) != 0 {
+					t.Fatalf("found %d sandwiches, want none", len(sandwiches))
+				}
+				return
+			}
+			if len(sandwiches) != 1 {
+				t.Fatalf("found %d sandwiches, want 1", len(sandwiches))
+			}
+			sandwich := sandwiches[0]
+			if sandwich.Profit.Int64() != test.profit {
+				t.Errorf("profit %s, want %d", sandwich.Profit, test.profit)
+			}
+			if sandwich.Token != testWeth {
+				t.Errorf("profit token %s, want %s", sandwich.Token.Hex(), testWeth.Hex())
+			}
+			if len(sandwich.Victims) != test.victims {
+				t.Fatalf("%d victims, want %d", len(sandwich.Victims), test.victims)
+			}
+			if sandwich.Victims[0].From != testVictim {
+				t.Errorf("victim %s, want %s", sandwich.Victims[0].From.Hex(), testVictim.Hex())
+			}
+			// 4500 bought at a 10% impact would have been 5000
+			if loss := sandwich.Victims[0].Loss; loss == nil || loss.Int64() != 500 {
+				t.Errorf("victim loss %v, want 500", loss)
+			}
+			if sandwich.ProfitEth != nil {
+				t.Errorf("profit in ETH %v without pricing, want nil", sandwich.ProfitEth)
+			}
+		})
+	}
+}
+
+func TestLegsMatch(t *testing.T) {
+	front := testSwap(testWeth, 1000, testToken, 10000)
+	tests := []struct {
+		amountIn int64
+		match    bool
+	}{
+		{10000, true},
+		{9000, true},
+		{11000, true},
+		{8999, false},
+		{11001, false},
+		{0, false},
+	}
+	for _, test := range tests {
+		back := testSwap(testToken, test.amountIn, testWeth, 1100)
+		if got := legsMatch(&front, &back); got != test.match {
+			t.Errorf("back leg selling %d: match %v, want %v", test.amountIn, got, test.match)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"math/big"
+	"time"
+)
+
+// GetBaseFee returns the EIP-1559 base fee of the block, zero before London
+func (r *RpcWrapper) GetBaseFee(ctx context.Context, height uint64) (baseFee *big.Int, err error) {
+	defer r.observe("eth_getBlockByNumber", time.Now(), &err)
+	c, err := r.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	var response struct {
+		BaseFeePerGas *hexutil.Big `json:"baseFeePerGas"`
+	}
+	err = c.CallContext(ctx, &response, "eth_getBlockByNumber", hexutil.EncodeUint64(height), false)
+	if err != nil {
+		return
+	}
+	baseFee = big.NewInt(0)
+	if response.BaseFeePerGas != nil {
+		baseFee = response.BaseFeePerGas.ToInt()
+	}
+	return
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"time"
+)
+
+// SyncProgress is the eth_syncing answer of a syncing node
+type SyncProgress struct {
+	CurrentBlock uint64
+	HighestBlock uint64
+}
+
+// GetClientVersion returns web3_clientVersion
+func (r *RpcWrapper) GetClientVersion(ctx context.Context) (version string, err error) {
+	defer r.observe("web3_clientVersion", time.Now(), &err)
+	c, err := r.dial(ctx)
+	if err != nil {
+		return
+	}
+	defer c.Close()
+	err = c.CallContext(ctx, &version, "web3_clientVersion")
+	return
+}
+
+// GetPeerCount returns net_peerCount
+func (r *RpcWrapper) GetPeerCount(ctx context.Context) (peers uint64, err error) {
+	defer r.observe("net_peerCount", time.Now(), &err)
+	c, err := r.dial(ctx)
+	if err != nil {
+		return
+	}
+	defer c.Close()
+	var response hexutil.Uint64
+	err = c.CallContext(ctx, &response, "net_peerCount")
+	peers = uint64(response)
+	return
+}
+
+// GetSyncProgress returns nil when the node is not syncing
+func (r *RpcWrapper) GetSyncProgress(ctx context.Context) (progress *SyncProgress, err error) {
+	defer r.observe("eth_syncing", time.Now(), &err)
+	c, err := r.dial(ctx)
+	if err != nil {
+		return
+	}
+	defer c.Close()
+	var raw json.RawMessage
+	if err = c.CallContext(ctx, &raw, "eth_syncing"); err != nil {
+		return
+	}
+	var syncing bool
+	if json.Unmarshal(raw, &syncing) == nil {
+		return
+	}
+	var response struct {
+		CurrentBlock hexutil.Uint64 `json:"currentBlock"`
+		HighestBlock hexutil.Uint64 `json:"highestBlock"`
+	}
+	if err = json.Unmarshal(raw, &response); err != nil {
+		return
+	}
+	progress = &SyncProgress{
+		CurrentBlock: uint64(response.CurrentBlock),
+		HighestBlock: uint64(response.HighestBlock),
+	}
+	return
+}
+
+// ProbeState reads an account balance at the height. Full nodes fail for heights whose state was pruned.
+func (r *RpcWrapper) ProbeState(ctx context.Context, height uint64) (err error) {
+	defer r.observe("eth_getBalance", time.Now(), &err)
+	c, err := r.dial(ctx)
+	if err != nil {
+		return
+	}
+	defer c.Close()
+	var balance hexutil.Big
+	return c.CallContext(ctx, &balance, "eth_getBalance", common.Address{}, hexutil.EncodeUint64(height))
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"time"
+)
+
+// Observer is told about every JSON-RPC call made to the node
+type Observer func(method string, duration time.Duration, err error)
+
+func (r *RpcWrapper) dial(ctx context.Context) (*rpc.Client, error) {
+	return rpc.DialContext(ctx, r.RpcAddress)
+}
+
+func (r *RpcWrapper) dialEth(ctx context.Context) (*ethclient.Client, error) {
+	c, err := r.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ethclient.NewClient(c), nil
+}
+
+// observe reports a call started at start to the Observer, whatever the transport.
+// Deferred right before dialing, so a failed websocket or IPC dial counts as a failed call.
+func (r *RpcWrapper) observe(method string, start time.Time, err *error) {
+	if r.Observer != nil {
+		r.Observer(method, time.Since(start), *err)
+	}
+}
@@ -0,0 +1,195 @@
+package middleware
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/latifrons/etherxray/model"
+	"math/big"
+	"time"
+)
+
+type callFrameJson struct {
+	Type         string           `json:"type"`
+	From         common.Address   `json:"from"`
+	To           common.Address   `json:"to"`
+	Value        *hexutil.Big     `json:"value"`
+	Gas          hexutil.Uint64   `json:"gas"`
+	GasUsed      hexutil.Uint64   `json:"gasUsed"`
+	Input        hexutil.Bytes    `json:"input"`
+	Output       hexutil.Bytes    `json:"output"`
+	Error        string           `json:"error"`
+	RevertReason string           `json:"revertReason"`
+	Calls        []*callFrameJson `json:"calls"`
+}
+
+func (f *callFrameJson) toModel(depth int) *model.CallFrame {
+	frame := &model.CallFrame{
+		Type:         f.Type,
+		From:         f.From,
+		To:           f.To,
+		Value:        big.NewInt(0),
+		Gas:          uint64(f.Gas),
+		GasUsed:      uint64(f.GasUsed),
+		Input:        f.Input,
+		Output:       f.Output,
+		Error:        f.Error,
+		RevertReason: f.RevertReason,
+		Depth:        depth,
+	}
+	if f.Value != nil {
+		frame.Value = f.Value.ToInt()
+	}
+	for _, call := range f.Calls {
+		frame.Calls = append(frame.Calls, call.toModel(depth+1))
+	}
+	return frame
+}
+
+// TraceTransaction replays the transaction with the callTracer and returns the call tree
+func (r *RpcWrapper) TraceTransaction(ctx context.Context, hash common.Hash) (frame *model.CallFrame, err error) {
+	defer r.observe("debug_traceTransaction", time.Now(), &err)
+	c, err := r.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	var response callFrameJson
+	err = c.CallContext(ctx, &response, "debug_traceTransaction", hash, map[string]interface{}{
+		"tracer": "callTracer",
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response.toModel(0), nil
+}
+
+type accountStateJson struct {
+	Balance *hexutil.Big                `json:"balance"`
+	Nonce   *uint64                     `json:"nonce"`
+	Code    hexutil.Bytes               `json:"code"`
+	Storage map[common.Hash]common.Hash `json:"storage"`
+}
+
+// toModel converts the json state. Fields missing in a post state are unchanged and taken from pre.
+func (s *accountStateJson) toModel(pre *model.AccountState) *model.AccountState {
+	state := &model.AccountState{
+		Balance: big.NewInt(0),
+		Code:    s.Code,
+		Storage: s.Storage,
+	}
+	if pre != nil {
+		state.Balance = pre.Balance
+		state.Nonce = pre.Nonce
+		if state.Code == nil {
+			state.Code = pre.Code
+		}
+	}
+	if s.Balance != nil {
+		state.Balance = s.Balance.ToInt()
+	}
+	if s.Nonce != nil {
+		state.Nonce = *s.Nonce
+	}
+	return state
+}
+
+// TraceStateDiff replays the transaction with the prestateTracer in diff mode
+func (r *RpcWrapper) TraceStateDiff(ctx context.Context, hash common.Hash) (diff *model.StateDiff, err error) {
+	defer r.observe("debug_traceTransaction", time.Now(), &err)
+	c, err := r.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	var response struct {
+		Pre  map[common.Address]*accountStateJson `json:"pre"`
+		Post map[common.Address]*accountStateJson `json:"post"`
+	}
+	err = c.CallContext(ctx, &response, "debug_traceTransaction", hash, map[string]interface{}{
+		"tracer": "prestateTracer",
+		"tracerConfig": map[string]interface{}{
+			"diffMode": true,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	diff = &model.StateDiff{
+		Pre:  make(map[common.Address]*model.AccountState),
+		Post: make(map[common.Address]*model.AccountState),
+	}
+	for addr, state := range response.Pre {
+		diff.Pre[addr] = state.toModel(nil)
+	}
+	for addr, state := range response.Post {
+		diff.Post[addr] = state.toModel(diff.Pre[addr])
+	}
+	return
+}
+
+// TraceBlock traces every transaction of the block with the callTracer.
+// The call trees are returned in transaction order.
+func (r *RpcWrapper) TraceBlock(ctx context.Context, height uint64) (frames []*model.CallFrame, err error) {
+	defer r.observe("debug_traceBlockByNumber", time.Now(), &err)
+	c, err := r.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	var response []struct {
+		Result *callFrameJson `json:"result"`
+		Error  string         `json:"error"`
+	}
+	err = c.CallContext(ctx, &response, "debug_traceBlockByNumber", hexutil.EncodeUint64(height), map[string]interface{}{
+		"tracer": "callTracer",
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range response {
+		if item.Result == nil {
+			frames = append(frames, nil)
+			continue
+		}
+		frames = append(frames, item.Result.toModel(0))
+	}
+	return
+}
+
+// ParityTrace is one entry of the trace_block output of Erigon, Nethermind or OpenEthereum
+type ParityTrace struct {
+	Action struct {
+		CallType      string         `json:"callType"`
+		From          common.Address `json:"from"`
+		To            common.Address `json:"to"`
+		Value         *hexutil.Big   `json:"value"`
+		Address       common.Address `json:"address"`
+		RefundAddress common.Address `json:"refundAddress"`
+		Balance       *hexutil.Big   `json:"balance"`
+	} `json:"action"`
+	Result *struct {
+		Address common.Address `json:"address"`
+	} `json:"result"`
+	Error               string       `json:"error"`
+	TraceAddress        []int        `json:"traceAddress"`
+	TransactionHash     *common.Hash `json:"transactionHash"`
+	TransactionPosition *int         `json:"transactionPosition"`
+	Type                string       `json:"type"`
+}
+
+// TraceBlockParity calls trace_block on upstreams which support the parity style trace module
+func (r *RpcWrapper) TraceBlockParity(ctx context.Context, height uint64) (traces []ParityTrace, err error) {
+	defer r.observe("trace_block", time.Now(), &err)
+	c, err := r.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	err = c.CallContext(ctx, &traces, "trace_block", hexutil.EncodeUint64(height))
+	return
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// GetQueuedNonces returns the sorted nonces of the transactions of the account waiting in the txpool queue.
+// Geth queues a transaction when its nonce leaves a gap after the pending ones.
+func (r *RpcWrapper) GetQueuedNonces(ctx context.Context, address common.Address) (nonces []uint64, err error) {
+	defer r.observe("txpool_content", time.Now(), &err)
+	c, err := r.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	var response struct {
+		Queued map[common.Address]map[string]json.RawMessage `json:"queued"`
+	}
+	err = c.CallContext(ctx, &response, "txpool_content")
+	if err != nil {
+		return
+	}
+	for nonceS := range response.Queued[address] {
+		nonce, er := strconv.ParseUint(nonceS, 10, 64)
+		if er != nil {
+			continue
+		}
+		nonces = append(nonces, nonce)
+	}
+	sort.Slice(nonces, func(i, j int) bool {
+		return nonces[i] < nonces[j]
+	})
+	return
+}
+
+// GetTxPoolStatus returns the number of pending and queued transactions in the txpool
+func (r *RpcWrapper) GetTxPoolStatus(ctx context.Context) (pending uint64, queued uint64, err error) {
+	defer r.observe("txpool_status", time.Now(), &err)
+	c, err := r.dial(ctx)
+	if err != nil {
+		return
+	}
+	defer c.Close()
+
+	var response struct {
+		Pending hexutil.Uint64 `json:"pending"`
+		Queued  hexutil.Uint64 `json:"queued"`
+	}
+	err = c.CallContext(ctx, &response, "txpool_status")
+	if err != nil {
+		return
+	}
+	return uint64(response.Pending), uint64(response.Queued), nil
+}
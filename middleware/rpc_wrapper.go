@@ -8,14 +8,15 @@ import (
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/sirupsen/logrus"
 	"github.com/valyala/fastjson"
 	"math/big"
 	"strings"
+	"time"
 )
 
 var Zero = big.NewInt(0)
@@ -31,10 +32,16 @@ var (
 	TyBytes, _      = abi.NewType("bytes", "", nil)
 	TyByteArr, _    = abi.NewType("bytes1[]", "", nil)
 	TyBytes32, _    = abi.NewType("bytes32", "", nil)
+	TyBytes4, _     = abi.NewType("bytes4", "", nil)
 	TyAddress, _    = abi.NewType("address", "", nil)
 	TyUint64Arr, _  = abi.NewType("uint64[]", "", nil)
 	TyAddressArr, _ = abi.NewType("address[]", "", nil)
 	TyInt8, _       = abi.NewType("int8", "", nil)
+	TyUint8, _      = abi.NewType("uint8", "", nil)
+	TyUint24, _     = abi.NewType("uint24", "", nil)
+	TyInt24, _      = abi.NewType("int24", "", nil)
+	TyUint128, _    = abi.NewType("uint128", "", nil)
+	TyUint160, _    = abi.NewType("uint160", "", nil)
 	// Special types for testing
 	TyUint32Arr2, _       = abi.NewType("uint32[2]", "", nil)
 	TyUint64Arr2, _       = abi.NewType("uint64[2]", "", nil)
@@ -105,10 +112,57 @@ var methods = map[string]abi.Method{
 	),
 	"getSwapFee": abi.NewMethod("getSwapFee", "getSwapFee", abi.Function, "", false, false,
 		// input
-		[]abi.Argument{{"token", TyAddress, false}},
+		[]abi.Argument{},
 		// output
 		[]abi.Argument{{"swapFee", TyUint256, false}},
 	),
+	"balanceOf": abi.NewMethod("balanceOf", "balanceOf", abi.Function, "", false, false,
+		[]abi.Argument{{Name: "owner", Type: TyAddress}},
+		[]abi.Argument{{Name: "balance", Type: TyUint256}},
+	),
+	"getCurrentTokens": abi.NewMethod("getCurrentTokens", "getCurrentTokens", abi.Function, "", false, false,
+		[]abi.Argument{},
+		[]abi.Argument{{Name: "tokens", Type: TyAddressArr}},
+	),
+	"getTotalDenormalizedWeight": abi.NewMethod("getTotalDenormalizedWeight", "getTotalDenormalizedWeight", abi.Function, "", false, false,
+		[]abi.Argument{},
+		[]abi.Argument{{Name: "weight", Type: TyUint256}},
+	),
+	"slot0": abi.NewMethod("slot0", "slot0", abi.Function, "", false, false,
+		[]abi.Argument{},
+		[]abi.Argument{
+			{Name: "sqrtPriceX96", Type: TyUint160},
+			{Name: "tick", Type: TyInt24},
+			{Name: "observationIndex", Type: TyUint16},
+			{Name: "observationCardinality", Type: TyUint16},
+			{Name: "observationCardinalityNext", Type: TyUint16},
+			{Name: "feeProtocol", Type: TyUint8},
+			{Name: "unlocked", Type: TyBool},
+		}),
+	"liquidity": abi.NewMethod("liquidity", "liquidity", abi.Function, "", false, false,
+		[]abi.Argument{},
+		[]abi.Argument{{Name: "liquidity", Type: TyUint128}}),
+	"tickSpacing": abi.NewMethod("tickSpacing", "tickSpacing", abi.Function, "", false, false,
+		[]abi.Argument{},
+		[]abi.Argument{{Name: "tickSpacing", Type: TyInt24}}),
+	"fee": abi.NewMethod("fee", "fee", abi.Function, "", false, false,
+		[]abi.Argument{},
+		[]abi.Argument{{Name: "fee", Type: TyUint24}}),
+	"supportsInterface": abi.NewMethod("supportsInterface", "supportsInterface", abi.Function, "", false, false,
+		// input
+		[]abi.Argument{{Name: "interfaceId", Type: TyBytes4}},
+		// output
+		[]abi.Argument{{Name: "supported", Type: TyBool}},
+	),
+	"ownerOf": abi.NewMethod("ownerOf", "ownerOf", abi.Function, "", false, false,
+		[]abi.Argument{{Name: "tokenId", Type: TyUint256}},
+		[]abi.Argument{{Name: "owner", Type: TyAddress}},
+	),
+	// ERC-1155 balanceOf(address,uint256), keyed apart from the ERC-20 balanceOf
+	"erc1155BalanceOf": abi.NewMethod("erc1155BalanceOf", "balanceOf", abi.Function, "", false, false,
+		[]abi.Argument{{Name: "account", Type: TyAddress}, {Name: "id", Type: TyUint256}},
+		[]abi.Argument{{Name: "balance", Type: TyUint256}},
+	),
 }
 
 type GetReservesResponse struct {
@@ -117,6 +171,31 @@ type GetReservesResponse struct {
 	Timestamp *big.Int
 }
 
+type BalancerPoolResponse struct {
+	Tokens      []common.Address
+	Balances    []*big.Int
+	Weights     []*big.Int
+	TotalWeight *big.Int
+	SwapFee     *big.Int
+}
+
+type Slot0Response struct {
+	SqrtPriceX96               *big.Int
+	Tick                       *big.Int
+	ObservationIndex           uint16
+	ObservationCardinality     uint16
+	ObservationCardinalityNext uint16
+	FeeProtocol                uint8
+	Unlocked                   bool
+}
+
+type V3PoolResponse struct {
+	Slot0       *Slot0Response
+	Liquidity   *big.Int
+	TickSpacing *big.Int
+	Fee         *big.Int
+}
+
 var myAbi = abi.ABI{
 	Methods: methods,
 }
@@ -125,32 +204,58 @@ type RpcWrapper struct {
 	RpcAddress         string
 	Signer             types.Signer
 	MaxTxAllowedToSend int
+	// Observer is nil when upstream calls are not measured
+	Observer Observer
 
 	sent int
 }
 
-func (r *RpcWrapper) BlockHeight(ctx context.Context) (uint64, error) {
-	client, _ := ethclient.DialContext(ctx, r.RpcAddress)
+func (r *RpcWrapper) BlockHeight(ctx context.Context) (height uint64, err error) {
+	defer r.observe("eth_blockNumber", time.Now(), &err)
+	client, err := r.dialEth(ctx)
+	if err != nil {
+		return 0, err
+	}
 	defer client.Close()
 
 	return client.BlockNumber(ctx)
 }
 
-func (r *RpcWrapper) BlockTxs(ctx context.Context, height uint64) (*types.Block, error) {
-	client, _ := ethclient.DialContext(ctx, r.RpcAddress)
+func (r *RpcWrapper) BlockTxs(ctx context.Context, height uint64) (block *types.Block, err error) {
+	defer r.observe("eth_getBlockByNumber", time.Now(), &err)
+	client, err := r.dialEth(ctx)
+	if err != nil {
+		return nil, err
+	}
 	defer client.Close()
 
 	return client.BlockByNumber(ctx, big.NewInt(0).SetUint64(height))
 }
 
-func (r *RpcWrapper) BlockTxReceipts(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
-	client, _ := ethclient.DialContext(ctx, r.RpcAddress)
+func (r *RpcWrapper) BlockHeader(ctx context.Context, height uint64) (header *types.Header, err error) {
+	defer r.observe("eth_getBlockByNumber", time.Now(), &err)
+	client, err := r.dialEth(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	return client.HeaderByNumber(ctx, big.NewInt(0).SetUint64(height))
+}
+
+func (r *RpcWrapper) BlockTxReceipts(ctx context.Context, hash common.Hash) (receipt *types.Receipt, err error) {
+	defer r.observe("eth_getTransactionReceipt", time.Now(), &err)
+	client, err := r.dialEth(ctx)
+	if err != nil {
+		return nil, err
+	}
 	defer client.Close()
 
 	return client.TransactionReceipt(ctx, hash)
 }
 
-func (r *RpcWrapper) GetValueRetUint(ctx context.Context, contract common.Address, field string) (int2 *big.Int, err error) {
+// GetValueRetUint calls the uint256 getter field at height, nil for the latest block
+func (r *RpcWrapper) GetValueRetUint(ctx context.Context, contract common.Address, field string, height *big.Int) (int2 *big.Int, err error) {
 	method := crypto.Keccak256([]byte(field + "()"))[:4]
 
 	bytes, err := myAbi.Pack("_general_uint256")
@@ -160,7 +265,12 @@ func (r *RpcWrapper) GetValueRetUint(ctx context.Context, contract common.Addres
 	}
 	allBytes := append(method, bytes[4:]...)
 
-	client, _ := ethclient.DialContext(ctx, r.RpcAddress)
+	defer r.observe("eth_call", time.Now(), &err)
+	client, err := r.dialEth(ctx)
+	if err != nil {
+		return
+	}
+	defer client.Close()
 
 	ret, err := client.CallContract(ctx, ethereum.CallMsg{
 		From:     common.Address{},
@@ -169,7 +279,7 @@ func (r *RpcWrapper) GetValueRetUint(ctx context.Context, contract common.Addres
 		GasPrice: Zero,
 		Value:    Zero,
 		Data:     allBytes,
-	}, nil)
+	}, height)
 	if err != nil {
 		logrus.WithError(err).Warn("call contract")
 		return
@@ -188,7 +298,12 @@ func (r *RpcWrapper) GetValueRetString(ctx context.Context, contract common.Addr
 	}
 	allBytes := append(method, bytesRaw[4:]...)
 
-	client, _ := ethclient.DialContext(ctx, r.RpcAddress)
+	defer r.observe("eth_call", time.Now(), &err)
+	client, err := r.dialEth(ctx)
+	if err != nil {
+		return
+	}
+	defer client.Close()
 
 	ret, err := client.CallContract(ctx, ethereum.CallMsg{
 		From:     common.Address{},
@@ -225,7 +340,12 @@ func (r *RpcWrapper) GetValueRetAddress(ctx context.Context, contract common.Add
 	}
 	allBytes := append(method, bytes[4:]...)
 
-	client, _ := ethclient.DialContext(ctx, r.RpcAddress)
+	defer r.observe("eth_call", time.Now(), &err)
+	client, err := r.dialEth(ctx)
+	if err != nil {
+		return
+	}
+	defer client.Close()
 
 	ret, err := client.CallContract(ctx, ethereum.CallMsg{
 		From:     common.Address{},
@@ -253,7 +373,12 @@ func (r *RpcWrapper) GetValueRetBool(ctx context.Context, contract common.Addres
 	}
 	allBytes := append(method, bytes[4:]...)
 
-	client, _ := ethclient.DialContext(ctx, r.RpcAddress)
+	defer r.observe("eth_call", time.Now(), &err)
+	client, err := r.dialEth(ctx)
+	if err != nil {
+		return
+	}
+	defer client.Close()
 
 	ret, err := client.CallContract(ctx, ethereum.CallMsg{
 		From:     common.Address{},
@@ -281,7 +406,12 @@ func (r *RpcWrapper) GetValueRetAddressArray(ctx context.Context, contract commo
 	}
 	allBytes := append(method, bytes[4:]...)
 
-	client, _ := ethclient.DialContext(ctx, r.RpcAddress)
+	defer r.observe("eth_call", time.Now(), &err)
+	client, err := r.dialEth(ctx)
+	if err != nil {
+		return
+	}
+	defer client.Close()
 
 	ret, err := client.CallContract(ctx, ethereum.CallMsg{
 		From:     common.Address{},
@@ -309,7 +439,12 @@ func (r *RpcWrapper) GetListValueByIndexRetAddress(ctx context.Context, contract
 	}
 	allBytes := append(method, bytes[4:]...)
 
-	client, _ := ethclient.DialContext(ctx, r.RpcAddress)
+	defer r.observe("eth_call", time.Now(), &err)
+	client, err := r.dialEth(ctx)
+	if err != nil {
+		return
+	}
+	defer client.Close()
 
 	ret, err := client.CallContract(ctx, ethereum.CallMsg{
 		From:     common.Address{},
@@ -334,7 +469,12 @@ func (r *RpcWrapper) GetUniswapLiquidities(ctx context.Context, contract common.
 		return
 	}
 
-	client, _ := ethclient.DialContext(ctx, r.RpcAddress)
+	defer r.observe("eth_call", time.Now(), &err)
+	client, err := r.dialEth(ctx)
+	if err != nil {
+		return
+	}
+	defer client.Close()
 
 	ret, err := client.CallContract(ctx, ethereum.CallMsg{
 		From:     common.Address{},
@@ -355,9 +495,207 @@ func (r *RpcWrapper) GetUniswapLiquidities(ctx context.Context, contract common.
 	return
 }
 
+// GetTokenBalance reads the ERC-20 balance of owner at the given height
+func (r *RpcWrapper) GetTokenBalance(ctx context.Context, token common.Address, owner common.Address, height *big.Int) (balance *big.Int, err error) {
+	bytes, err := myAbi.Pack("balanceOf", owner)
+	if err != nil {
+		logrus.WithError(err).Error("pack field")
+		return
+	}
+
+	defer r.observe("eth_call", time.Now(), &err)
+	client, err := r.dialEth(ctx)
+	if err != nil {
+		return
+	}
+	defer client.Close()
+
+	ret, err := client.CallContract(ctx, ethereum.CallMsg{
+		From:     common.Address{},
+		To:       &token,
+		Gas:      0,
+		GasPrice: Zero,
+		Value:    Zero,
+		Data:     bytes,
+	}, height)
+	if err != nil {
+		logrus.WithError(err).Warn("call contract")
+		return
+	}
+
+	err = myAbi.UnpackIntoInterface(&balance, "balanceOf", ret)
+	return
+}
+
+// GetUniswapV3Slot0 reads slot0 of a Uniswap V3 pool at the given height
+func (r *RpcWrapper) GetUniswapV3Slot0(ctx context.Context, contract common.Address, height *big.Int) (resp *Slot0Response, err error) {
+	bytes, err := myAbi.Pack("slot0")
+	if err != nil {
+		logrus.WithError(err).Error("pack field")
+		return
+	}
+
+	defer r.observe("eth_call", time.Now(), &err)
+	client, err := r.dialEth(ctx)
+	if err != nil {
+		return
+	}
+	defer client.Close()
+
+	ret, err := client.CallContract(ctx, ethereum.CallMsg{
+		From:     common.Address{},
+		To:       &contract,
+		Gas:      0,
+		GasPrice: Zero,
+		Value:    Zero,
+		Data:     bytes,
+	}, height)
+	if err != nil {
+		logrus.WithError(err).Warn("call contract")
+		return
+	}
+
+	resp = &Slot0Response{}
+
+	err = myAbi.UnpackIntoInterface(resp, "slot0", ret)
+	return
+}
+
+// GetUniswapV3Pool reads slot0, liquidity, tick spacing and fee of a Uniswap V3 pool at the given height
+func (r *RpcWrapper) GetUniswapV3Pool(ctx context.Context, contract common.Address, height *big.Int) (resp *V3PoolResponse, err error) {
+	client, err := r.dialEth(ctx)
+	if err != nil {
+		return
+	}
+	defer client.Close()
+
+	call := func(method string) (ret []byte, err error) {
+		bytes, err := myAbi.Pack(method)
+		if err != nil {
+			logrus.WithError(err).Error("pack field")
+			return
+		}
+		defer r.observe("eth_call", time.Now(), &err)
+		ret, err = client.CallContract(ctx, ethereum.CallMsg{
+			From:     common.Address{},
+			To:       &contract,
+			Gas:      0,
+			GasPrice: Zero,
+			Value:    Zero,
+			Data:     bytes,
+		}, height)
+		if err != nil {
+			logrus.WithError(err).Warn("call contract")
+		}
+		return
+	}
+
+	resp = &V3PoolResponse{
+		Slot0: &Slot0Response{},
+	}
+	ret, err := call("slot0")
+	if err != nil {
+		return
+	}
+	if err = myAbi.UnpackIntoInterface(resp.Slot0, "slot0", ret); err != nil {
+		return
+	}
+	if ret, err = call("liquidity"); err != nil {
+		return
+	}
+	if err = myAbi.UnpackIntoInterface(&resp.Liquidity, "liquidity", ret); err != nil {
+		return
+	}
+	if ret, err = call("tickSpacing"); err != nil {
+		return
+	}
+	if err = myAbi.UnpackIntoInterface(&resp.TickSpacing, "tickSpacing", ret); err != nil {
+		return
+	}
+	if ret, err = call("fee"); err != nil {
+		return
+	}
+	err = myAbi.UnpackIntoInterface(&resp.Fee, "fee", ret)
+	return
+}
+
+// GetBalancerPool reads the tokens, balances, denormalized weights and swap fee of a Balancer V1 pool at the given height
+func (r *RpcWrapper) GetBalancerPool(ctx context.Context, contract common.Address, height *big.Int) (resp *BalancerPoolResponse, err error) {
+	client, err := r.dialEth(ctx)
+	if err != nil {
+		return
+	}
+	defer client.Close()
+
+	call := func(method string, args ...interface{}) (ret []byte, err error) {
+		bytes, err := myAbi.Pack(method, args...)
+		if err != nil {
+			logrus.WithError(err).Error("pack field")
+			return
+		}
+		defer r.observe("eth_call", time.Now(), &err)
+		ret, err = client.CallContract(ctx, ethereum.CallMsg{
+			From:     common.Address{},
+			To:       &contract,
+			Gas:      0,
+			GasPrice: Zero,
+			Value:    Zero,
+			Data:     bytes,
+		}, height)
+		if err != nil {
+			logrus.WithError(err).Warn("call contract")
+		}
+		return
+	}
+
+	resp = &BalancerPoolResponse{}
+	ret, err := call("getCurrentTokens")
+	if err != nil {
+		return
+	}
+	if err = myAbi.UnpackIntoInterface(&resp.Tokens, "getCurrentTokens", ret); err != nil {
+		return
+	}
+	if ret, err = call("getTotalDenormalizedWeight"); err != nil {
+		return
+	}
+	if err = myAbi.UnpackIntoInterface(&resp.TotalWeight, "getTotalDenormalizedWeight", ret); err != nil {
+		return
+	}
+	if ret, err = call("getSwapFee"); err != nil {
+		return
+	}
+	if err = myAbi.UnpackIntoInterface(&resp.SwapFee, "getSwapFee", ret); err != nil {
+		return
+	}
+	for _, token := range resp.Tokens {
+		var balance, weight *big.Int
+		if ret, err = call("getBalance", token); err != nil {
+			return
+		}
+		if err = myAbi.UnpackIntoInterface(&balance, "getBalance", ret); err != nil {
+			return
+		}
+		if ret, err = call("getDenormalizedWeight", token); err != nil {
+			return
+		}
+		if err = myAbi.UnpackIntoInterface(&weight, "getDenormalizedWeight", ret); err != nil {
+			return
+		}
+		resp.Balances = append(resp.Balances, balance)
+		resp.Weights = append(resp.Weights, weight)
+	}
+	return
+}
+
 // Sync log
 func (r *RpcWrapper) GetTradeLog(ctx context.Context, height uint64, topics []common.Hash) (logs []types.Log, err error) {
-	client, _ := ethclient.DialContext(ctx, r.RpcAddress)
+	defer r.observe("eth_getLogs", time.Now(), &err)
+	client, err := r.dialEth(ctx)
+	if err != nil {
+		return
+	}
+	defer client.Close()
 	logs, err = client.FilterLogs(ctx, ethereum.FilterQuery{
 		BlockHash: nil,
 		FromBlock: big.NewInt(int64(height)),
@@ -369,7 +707,12 @@ func (r *RpcWrapper) GetTradeLog(ctx context.Context, height uint64, topics []co
 }
 
 func (r *RpcWrapper) GetTradeLogFromTo(ctx context.Context, fromHeight uint64, toHeight uint64, topic common.Hash, addresses []common.Address) (logs []types.Log, err error) {
-	client, _ := ethclient.DialContext(ctx, r.RpcAddress)
+	defer r.observe("eth_getLogs", time.Now(), &err)
+	client, err := r.dialEth(ctx)
+	if err != nil {
+		return
+	}
+	defer client.Close()
 	logs, err = client.FilterLogs(ctx, ethereum.FilterQuery{
 		BlockHash: nil,
 		FromBlock: big.NewInt(int64(fromHeight)),
@@ -380,8 +723,30 @@ func (r *RpcWrapper) GetTradeLogFromTo(ctx context.Context, fromHeight uint64, t
 	return
 }
 
+// GetLogsFromTo returns the logs in [fromHeight, toHeight] matching the topics by position, an empty position matches any topic
+func (r *RpcWrapper) GetLogsFromTo(ctx context.Context, fromHeight uint64, toHeight uint64, topics [][]common.Hash, addresses []common.Address) (logs []types.Log, err error) {
+	defer r.observe("eth_getLogs", time.Now(), &err)
+	client, err := r.dialEth(ctx)
+	if err != nil {
+		return
+	}
+	defer client.Close()
+	logs, err = client.FilterLogs(ctx, ethereum.FilterQuery{
+		FromBlock: big.NewInt(int64(fromHeight)),
+		ToBlock:   big.NewInt(int64(toHeight)),
+		Addresses: addresses,
+		Topics:    topics,
+	})
+	return
+}
+
 func (r *RpcWrapper) GetTradeLogs(ctx context.Context, height uint64, topics []common.Hash) (logs []types.Log, err error) {
-	client, _ := ethclient.DialContext(ctx, r.RpcAddress)
+	defer r.observe("eth_getLogs", time.Now(), &err)
+	client, err := r.dialEth(ctx)
+	if err != nil {
+		return
+	}
+	defer client.Close()
 	logs, err = client.FilterLogs(ctx, ethereum.FilterQuery{
 		BlockHash: nil,
 		FromBlock: big.NewInt(int64(height)),
@@ -393,7 +758,12 @@ func (r *RpcWrapper) GetTradeLogs(ctx context.Context, height uint64, topics []c
 }
 
 func (r *RpcWrapper) GetBlockGasPrices(ctx context.Context, height uint64) (gases []uint64, err error) {
-	client, _ := ethclient.DialContext(ctx, r.RpcAddress)
+	defer r.observe("eth_getBlockByNumber", time.Now(), &err)
+	client, err := r.dialEth(ctx)
+	if err != nil {
+		return
+	}
+	defer client.Close()
 	block, er := client.BlockByNumber(ctx, big.NewInt(int64(height)))
 	if er != nil {
 		err = er
@@ -406,16 +776,23 @@ func (r *RpcWrapper) GetBlockGasPrices(ctx context.Context, height uint64) (gase
 	return
 }
 
-func (r *RpcWrapper) GetSuggestedGasPrice(ctx context.Context) (*big.Int, error) {
-	client, _ := ethclient.DialContext(ctx, r.RpcAddress)
+func (r *RpcWrapper) GetSuggestedGasPrice(ctx context.Context) (price *big.Int, err error) {
+	defer r.observe("eth_gasPrice", time.Now(), &err)
+	client, err := r.dialEth(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
 	return client.SuggestGasPrice(ctx)
 }
 
 func (r *RpcWrapper) GetTxPoolRaw(ctx context.Context) (obj *fastjson.Object, err error) {
-	c, err := rpc.DialContext(ctx, r.RpcAddress)
+	defer r.observe("txpool_content", time.Now(), &err)
+	c, err := r.dial(ctx)
 	if err != nil {
 		return nil, err
 	}
+	defer c.Close()
 	var response json.RawMessage
 
 	err = c.CallContext(ctx, &response, "txpool_content")
@@ -435,7 +812,12 @@ func (r *RpcWrapper) GetTxPoolRaw(ctx context.Context) (obj *fastjson.Object, er
 }
 
 func (r *RpcWrapper) PendingNonceAt(ctx context.Context, address common.Address) (nonce uint64, err error) {
-	client, _ := ethclient.DialContext(ctx, r.RpcAddress)
+	defer r.observe("eth_getTransactionCount", time.Now(), &err)
+	client, err := r.dialEth(ctx)
+	if err != nil {
+		return
+	}
+	defer client.Close()
 
 	nonce, err = client.PendingNonceAt(ctx, address)
 	if err != nil {
@@ -444,7 +826,12 @@ func (r *RpcWrapper) PendingNonceAt(ctx context.Context, address common.Address)
 	return
 }
 func (r *RpcWrapper) NonceAt(ctx context.Context, address common.Address) (nonce uint64, err error) {
-	client, _ := ethclient.DialContext(ctx, r.RpcAddress)
+	defer r.observe("eth_getTransactionCount", time.Now(), &err)
+	client, err := r.dialEth(ctx)
+	if err != nil {
+		return
+	}
+	defer client.Close()
 
 	nonce, err = client.NonceAt(ctx, address, nil)
 	if err != nil {
@@ -466,17 +853,182 @@ func HexToAccount(hexPrivKey string) (*ecdsa.PrivateKey, *ecdsa.PublicKey, commo
 }
 
 func (r *RpcWrapper) GetBalanceETH(ctx context.Context, account common.Address) (v *big.Int, err error) {
-	client, _ := ethclient.DialContext(ctx, r.RpcAddress)
+	defer r.observe("eth_getBalance", time.Now(), &err)
+	client, err := r.dialEth(ctx)
+	if err != nil {
+		return
+	}
+	defer client.Close()
 	return client.BalanceAt(ctx, account, nil)
 }
 
 func (r *RpcWrapper) GetTransactionByHash(ctx context.Context, hash common.Hash) (tx *types.Transaction, isPending bool, err error) {
-	client, _ := ethclient.DialContext(ctx, r.RpcAddress)
+	defer r.observe("eth_getTransactionByHash", time.Now(), &err)
+	client, err := r.dialEth(ctx)
+	if err != nil {
+		return
+	}
+	defer client.Close()
 	return client.TransactionByHash(ctx, hash)
 }
 
 func (r *RpcWrapper) GetTx(ctx context.Context, hash common.Hash) (tx *types.Transaction, isPending bool, err error) {
-	client, _ := ethclient.DialContext(ctx, r.RpcAddress)
+	defer r.observe("eth_getTransactionByHash", time.Now(), &err)
+	client, err := r.dialEth(ctx)
+	if err != nil {
+		return
+	}
 	defer client.Close()
 	return client.TransactionByHash(ctx, hash)
 }
+
+// GetAllPairsLength returns the number of pairs created by a Uniswap V2 style factory
+func (r *RpcWrapper) GetAllPairsLength(ctx context.Context, factory common.Address) (length *big.Int, err error) {
+	bytes, err := myAbi.Pack("allPairsLength")
+	if err != nil {
+		logrus.WithError(err).Error("pack field")
+		return
+	}
+
+	defer r.observe("eth_call", time.Now(), &err)
+	client, err := r.dialEth(ctx)
+	if err != nil {
+		return
+	}
+	defer client.Close()
+
+	ret, err := client.CallContract(ctx, ethereum.CallMsg{
+		From:     common.Address{},
+		To:       &factory,
+		Gas:      0,
+		GasPrice: Zero,
+		Value:    Zero,
+		Data:     bytes,
+	}, nil)
+	if err != nil {
+		logrus.WithError(err).Warn("call contract")
+		return
+	}
+	err = myAbi.UnpackIntoInterface(&length, "allPairsLength", ret)
+	return
+}
+
+// GetNftOwner reads the ERC-721 owner of the token at the given height
+func (r *RpcWrapper) GetNftOwner(ctx context.Context, contract common.Address, tokenId *big.Int, height *big.Int) (owner common.Address, err error) {
+	bytes, err := myAbi.Pack("ownerOf", tokenId)
+	if err != nil {
+		logrus.WithError(err).Error("pack field")
+		return
+	}
+
+	defer r.observe("eth_call", time.Now(), &err)
+	client, err := r.dialEth(ctx)
+	if err != nil {
+		return
+	}
+	defer client.Close()
+
+	ret, err := client.CallContract(ctx, ethereum.CallMsg{
+		From:     common.Address{},
+		To:       &contract,
+		Gas:      0,
+		GasPrice: Zero,
+		Value:    Zero,
+		Data:     bytes,
+	}, height)
+	if err != nil {
+		logrus.WithError(err).Warn("call contract")
+		return
+	}
+	err = myAbi.UnpackIntoInterface(&owner, "ownerOf", ret)
+	return
+}
+
+// GetNftBalance reads the ERC-1155 balance of the token held by account at the given height
+func (r *RpcWrapper) GetNftBalance(ctx context.Context, contract common.Address, account common.Address, tokenId *big.Int, height *big.Int) (balance *big.Int, err error) {
+	bytes, err := myAbi.Pack("erc1155BalanceOf", account, tokenId)
+	if err != nil {
+		logrus.WithError(err).Error("pack field")
+		return
+	}
+
+	defer r.observe("eth_call", time.Now(), &err)
+	client, err := r.dialEth(ctx)
+	if err != nil {
+		return
+	}
+	defer client.Close()
+
+	ret, err := client.CallContract(ctx, ethereum.CallMsg{
+		From:     common.Address{},
+		To:       &contract,
+		Gas:      0,
+		GasPrice: Zero,
+		Value:    Zero,
+		Data:     bytes,
+	}, height)
+	if err != nil {
+		logrus.WithError(err).Warn("call contract")
+		return
+	}
+	err = myAbi.UnpackIntoInterface(&balance, "erc1155BalanceOf", ret)
+	return
+}
+
+// SupportsInterface asks the contract through ERC-165 whether it implements the interface
+func (r *RpcWrapper) SupportsInterface(ctx context.Context, contract common.Address, interfaceId [4]byte) (supported bool, err error) {
+	bytes, err := myAbi.Pack("supportsInterface", interfaceId)
+	if err != nil {
+		logrus.WithError(err).Error("pack field")
+		return
+	}
+
+	defer r.observe("eth_call", time.Now(), &err)
+	client, err := r.dialEth(ctx)
+	if err != nil {
+		return
+	}
+	defer client.Close()
+
+	ret, err := client.CallContract(ctx, ethereum.CallMsg{
+		From:     common.Address{},
+		To:       &contract,
+		Gas:      0,
+		GasPrice: Zero,
+		Value:    Zero,
+		Data:     bytes,
+	}, nil)
+	if err != nil {
+		return
+	}
+	err = myAbi.UnpackIntoInterface(&supported, "supportsInterface", ret)
+	return
+}
+
+// ReplayCall re-executes the transaction as an eth_call on the state at the given height.
+// When the call reverts, the revert data is returned in revertData if the node provides it.
+func (r *RpcWrapper) ReplayCall(ctx context.Context, from common.Address, tx *types.Transaction, height *big.Int) (ret []byte, revertData []byte, err error) {
+	defer r.observe("eth_call", time.Now(), &err)
+	client, err := r.dialEth(ctx)
+	if err != nil {
+		return
+	}
+	defer client.Close()
+
+	ret, err = client.CallContract(ctx, ethereum.CallMsg{
+		From:     from,
+		To:       tx.To(),
+		Gas:      tx.Gas(),
+		GasPrice: tx.GasPrice(),
+		Value:    tx.Value(),
+		Data:     tx.Data(),
+	}, height)
+	if err != nil {
+		if de, ok := err.(rpc.DataError); ok {
+			if data, ok := de.ErrorData().(string); ok {
+				revertData, _ = hexutil.Decode(data)
+			}
+		}
+	}
+	return
+}
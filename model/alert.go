@@ -0,0 +1,20 @@
+package model
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Alert is a match of an alert rule, in a transaction or in the whole block
+type Alert struct {
+	Id          string `json:"id"`
+	Rule        string `json:"rule"`
+	Description string `json:"description,omitempty"`
+	Height      uint64 `json:"height"`
+	// TxHash, From and To are absent for block rules
+	TxHash *common.Hash    `json:"tx_hash,omitempty"`
+	From   *common.Address `json:"from,omitempty"`
+	To     *common.Address `json:"to,omitempty"`
+	Time   int64           `json:"time"`
+	// Suppressed counts the alerts of the rule dropped by the rate limit since the previous delivery
+	Suppressed uint64 `json:"suppressed,omitempty"`
+}
@@ -0,0 +1,29 @@
+package model
+
+import (
+	"time"
+)
+
+// NodeHealth is the state of the upstream node as seen by etherxray
+type NodeHealth struct {
+	Reachable     bool
+	Error         string
+	ClientVersion string
+	PeerCount     uint64
+	Syncing       bool
+	// CurrentBlock and HighestBlock are set while syncing
+	CurrentBlock uint64
+	HighestBlock uint64
+	Head         uint64
+	HeadTime     time.Time
+	// HeadAge is the wall-clock time since the head block
+	HeadAge time.Duration
+	// Archive is nil until the state probe answered
+	Archive *bool
+	// IndexedBlock is the last block committed by the indexer, zero without one
+	IndexedBlock uint64
+	Ready        bool
+	// Reasons explains why the node is not ready
+	Reasons   []string
+	CheckedAt time.Time
+}
@@ -0,0 +1,69 @@
+package model
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"time"
+)
+
+// Block is the header summary of an indexed block
+type Block struct {
+	Height     uint64
+	Hash       common.Hash
+	ParentHash common.Hash
+	Timestamp  uint64
+	Coinbase   common.Address
+	TxHashes   []common.Hash
+	// Pruned blocks keep the decoded transactions without their raw logs, nor the log index entries
+	Pruned bool
+}
+
+// EventLog is an indexed log with its event signature when the topic is known
+type EventLog struct {
+	Height   uint64
+	TxHash   common.Hash
+	TxIndex  uint
+	LogIndex uint
+	Address  common.Address
+	Topics   []common.Hash
+	Data     []byte
+	Event    string
+}
+
+// LogFilter selects indexed logs. Zero address or topic matches any.
+type LogFilter struct {
+	Address common.Address
+	Topic0  common.Hash
+	From    uint64
+	To      uint64
+	Limit   int
+}
+
+// Checkpoint is the last block an indexer committed
+type Checkpoint struct {
+	Height uint64
+	Hash   common.Hash
+}
+
+// IndexerStatus reports the progress of the indexer
+type IndexerStatus struct {
+	From uint64
+	// To is zero when the indexer follows the head
+	To        uint64
+	Head      uint64
+	Indexed   uint64
+	Following bool
+	Reorgs    uint64
+	// BlocksPerSecond is the commit rate over the last minute
+	BlocksPerSecond float64
+	StartedAt       time.Time
+	LastError       string
+}
+
+// TableStats is the space used by one table of the index
+type TableStats struct {
+	Name  string
+	Keys  uint64
+	Bytes uint64
+	// DiskBytes is the compressed size on disk as estimated by the database
+	DiskBytes uint64
+}
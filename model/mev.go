@@ -0,0 +1,117 @@
+package model
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+)
+
+// Roles of a transaction in a detected MEV pattern
+const (
+	MevSandwichFront  = "sandwich_front"
+	MevSandwichBack   = "sandwich_back"
+	MevSandwichVictim = "sandwich_victim"
+	MevArbitrage      = "arbitrage"
+	MevLiquidation    = "liquidation"
+)
+
+const (
+	LendingAave     = "aave"
+	LendingCompound = "compound"
+)
+
+// BlockMev is the MEV found in a block
+type BlockMev struct {
+	Height       uint64
+	Sandwiches   []Sandwich
+	Arbitrages   []Arbitrage
+	Liquidations []Liquidation
+}
+
+// SearcherCost is what a searcher transaction paid to be included.
+// GasCost is gas used times the effective gas price, which includes the priority fee.
+// CoinbaseTransfer is the value sent directly to the block producer.
+// BuilderTip is the priority fee plus CoinbaseTransfer, all in wei.
+type SearcherCost struct {
+	GasCost          *big.Int
+	CoinbaseTransfer *big.Int
+	BuilderTip       *big.Int
+}
+
+// Arbitrage is a chain of swaps across pools in one transaction which starts and ends in the same token.
+// Profit is in units of that token. ProfitEth and NetProfitEth are nil when the token cannot be priced,
+// NetProfitEth deducts the gas cost and the direct coinbase transfer.
+type Arbitrage struct {
+	Tx           common.Hash
+	Searcher     common.Address
+	Contract     common.Address
+	Token        common.Address
+	Symbol       string
+	Decimals     uint8
+	Pools        []common.Address
+	Profit       *big.Int
+	ProfitEth    *big.Rat
+	Cost         SearcherCost
+	NetProfitEth *big.Rat
+}
+
+// Liquidation is an Aave LiquidationCall or Compound LiquidateBorrow event.
+// Amounts are in underlying token units. ProfitEth is the seized collateral minus the repaid debt in ETH.
+// The cost is the one of the whole transaction, so NetProfitEth is only set when it holds a single liquidation.
+type Liquidation struct {
+	Tx                 common.Hash
+	Protocol           string
+	Liquidator         common.Address
+	Borrower           common.Address
+	DebtToken          common.Address
+	DebtSymbol         string
+	DebtDecimals       uint8
+	DebtRepaid         *big.Int
+	CollateralToken    common.Address
+	CollateralSymbol   string
+	CollateralDecimals uint8
+	CollateralSeized   *big.Int
+	ProfitEth          *big.Rat
+	Cost               SearcherCost
+	NetProfitEth       *big.Rat
+	LogIndex           uint
+}
+
+// Sandwich is a pair of opposite swaps by the same actor in one pool around one or more victim swaps.
+// Amounts are in units of the token the attacker starts and ends with, ETH values are nil when it cannot be priced.
+type Sandwich struct {
+	Pool      common.Address
+	Protocol  string
+	Attacker  common.Address
+	FrontTx   common.Hash
+	BackTx    common.Hash
+	Token     common.Address
+	Symbol    string
+	Decimals  uint8
+	Profit    *big.Int
+	ProfitEth *big.Rat
+	// GasCost is the gas paid by the front and back transactions in wei
+	GasCost *big.Int
+	Victims []SandwichVictim
+}
+
+// SandwichVictim is a swap executed between the front and back legs of a sandwich.
+// Loss is in units of the token the victim bought, estimated from the price impact of the front leg.
+type SandwichVictim struct {
+	Tx       common.Hash
+	From     common.Address
+	Token    common.Address
+	Symbol   string
+	Decimals uint8
+	Loss     *big.Int
+	LossEth  *big.Rat
+}
+
+// HasMev tells whether the transaction plays the role in a detected MEV pattern
+func (t *Tx) HasMev(role string) bool {
+	for _, v := range t.Mev {
+		if v == role {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package model
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+)
+
+// Pair is a Uniswap V2 or V3 style pool created by a factory
+type Pair struct {
+	Address common.Address `json:"address"`
+	Factory common.Address `json:"factory"`
+	// Index is the allPairs index of a V2 pair
+	Index  uint64         `json:"index"`
+	Token0 common.Address `json:"token0"`
+	Token1 common.Address `json:"token1"`
+	// Protocol is empty for pairs stored before V3 pools were crawled, which are all V2
+	Protocol string `json:"protocol,omitempty"`
+	// Fee is the fee tier of a V3 pool in hundredths of a bip
+	Fee uint64 `json:"fee,omitempty"`
+}
+
+// IsV3 tells whether the pair is a Uniswap V3 pool
+func (p *Pair) IsV3() bool {
+	return p.Protocol == ProtocolUniswapV3
+}
+
+// PoolSample is the state of a V2 pool at a block, or right after a Sync event
+type PoolSample struct {
+	Height    uint64
+	Timestamp uint64
+	TxHash    common.Hash
+	LogIndex  uint
+	Reserve0  *big.Int
+	Reserve1  *big.Int
+	// Price0 is the price of token0 in token1, Price1 the price of token1 in token0. Nil when a reserve is empty.
+	Price0 *big.Rat
+	Price1 *big.Rat
+}
+
+// V3PoolState is the state of a Uniswap V3 pool at a block
+type V3PoolState struct {
+	Address common.Address
+	Height  uint64
+	Token0  common.Address
+	Token1  common.Address
+	// Fee is in hundredths of a bip, 3000 is 0.3%
+	Fee          uint64
+	TickSpacing  int64
+	Liquidity    *big.Int
+	SqrtPriceX96 *big.Int
+	Tick         int64
+	// Price0 is the price of token0 in token1, Price1 the price of token1 in token0. Nil when the pool is not initialized.
+	Price0 *big.Rat
+	Price1 *big.Rat
+}
+
+const (
+	ProtocolUniswapV2 = "uniswap_v2"
+	ProtocolUniswapV3 = "uniswap_v3"
+)
+
+// Swap is a trade against a V2 or V3 pool decoded from its Swap event
+type Swap struct {
+	Protocol    string
+	Pool        common.Address
+	Sender      common.Address
+	Recipient   common.Address
+	TokenIn     common.Address
+	SymbolIn    string
+	DecimalsIn  uint8
+	TokenOut    common.Address
+	SymbolOut   string
+	DecimalsOut uint8
+	AmountIn    *big.Int
+	AmountOut   *big.Int
+	// PriceImpact is the relative drop of the pool price of TokenIn caused by the swap. Nil when unknown.
+	PriceImpact *big.Rat
+	LogIndex    uint
+}
+
+const (
+	LiquidityMint    = "mint"
+	LiquidityBurn    = "burn"
+	LiquidityCollect = "collect"
+)
+
+// LiquidityEvent is a V3 Mint, Burn or Collect event
+type LiquidityEvent struct {
+	Kind      string
+	Pool      common.Address
+	Owner     common.Address
+	TickLower int64
+	TickUpper int64
+	// Liquidity is nil for Collect
+	Liquidity *big.Int
+	Amount0   *big.Int
+	Amount1   *big.Int
+	LogIndex  uint
+}
+
+// BalancerPool is the state of a Balancer V1 weighted pool at a block
+type BalancerPool struct {
+	Address common.Address
+	Height  uint64
+	// SwapFee is a fraction, 0.003 is 0.3%
+	SwapFee *big.Rat
+	Tokens  []BalancerToken
+}
+
+type BalancerToken struct {
+	Token        common.Address
+	Symbol       string
+	Decimals     uint8
+	Balance      *big.Int
+	DenormWeight *big.Int
+	// Weight is the normalized weight, weights of a pool sum to 1
+	Weight *big.Rat
+}
@@ -0,0 +1,64 @@
+package model
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+)
+
+// TokenInfo is the metadata of a token contract
+type TokenInfo struct {
+	Address  common.Address `json:"address"`
+	Name     string         `json:"name"`
+	Symbol   string         `json:"symbol"`
+	Decimals uint8          `json:"decimals"`
+	// IsErc20 is false when the contract does not answer decimals()
+	IsErc20 bool `json:"is_erc20"`
+	// Interfaces are probed through ERC-165 for NFT collections only
+	InterfacesProbed bool `json:"interfaces_probed"`
+	IsErc721         bool `json:"is_erc721"`
+	IsErc1155        bool `json:"is_erc1155"`
+}
+
+// TokenTransfer is an ERC-20 Transfer event
+type TokenTransfer struct {
+	Token    common.Address
+	Symbol   string
+	Decimals uint8
+	From     common.Address
+	To       common.Address
+	Amount   *big.Int
+	LogIndex uint
+	// Usd is the value of the amount, nil when the token could not be priced
+	Usd *big.Rat
+}
+
+const (
+	NftStandardErc721  = "erc721"
+	NftStandardErc1155 = "erc1155"
+)
+
+// NftTransfer is an ERC-721 Transfer or one item of an ERC-1155 TransferSingle or TransferBatch event
+type NftTransfer struct {
+	Contract   common.Address
+	Standard   string
+	Collection string
+	Operator   common.Address
+	From       common.Address
+	To         common.Address
+	TokenId    *big.Int
+	Amount     *big.Int
+	LogIndex   uint
+}
+
+// TokenPrice is the value of one token at a block, routed through DEX pools
+type TokenPrice struct {
+	Token  common.Address
+	Height uint64
+	Eth    *big.Rat
+	// Usd is nil when ETH could not be priced in the stablecoin
+	Usd *big.Rat
+	// Path is the pools the price was routed through, empty for WETH itself
+	Path []common.Address
+	// Depth is the liquidity of the shallowest hop of the path in ETH
+	Depth *big.Rat
+}
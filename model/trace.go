@@ -0,0 +1,67 @@
+package model
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+)
+
+// CallFrame is one call in the call tree of a transaction, as reported by callTracer
+type CallFrame struct {
+	Type         string
+	From         common.Address
+	To           common.Address
+	Value        *big.Int
+	Gas          uint64
+	GasUsed      uint64
+	Input        []byte
+	Output       []byte
+	Error        string
+	RevertReason string
+	Method       string
+	Depth        int
+	Calls        []*CallFrame
+}
+
+// AccountState is the state of an account as reported by prestateTracer
+type AccountState struct {
+	Balance *big.Int
+	Nonce   uint64
+	Code    []byte
+	Storage map[common.Hash]common.Hash
+}
+
+// StateDiff is the prestateTracer output in diff mode.
+// Post storage only contains the slots that were modified by the transaction.
+// An account present in Pre but missing in Post was destructed.
+type StateDiff struct {
+	Pre  map[common.Address]*AccountState
+	Post map[common.Address]*AccountState
+}
+
+type StorageChange struct {
+	Slot   common.Hash
+	Before common.Hash
+	After  common.Hash
+}
+
+// TokenChange is an ERC-20 balance change recovered from a balance mapping slot
+type TokenChange struct {
+	Token    common.Address
+	Symbol   string
+	Decimals uint8
+	Holder   common.Address
+	Before   *big.Int
+	After    *big.Int
+}
+
+// AccountChange is everything a transaction changed on one account
+type AccountChange struct {
+	Address        common.Address
+	BalanceBefore  *big.Int
+	BalanceAfter   *big.Int
+	NonceBefore    uint64
+	NonceAfter     uint64
+	CodeChanged    bool
+	StorageChanges []StorageChange
+	TokenChanges   []TokenChange
+}
@@ -11,5 +11,65 @@ type Tx struct {
 	Receipt *types.Receipt
 	From    common.Address
 	GasCost *big.Int
-	Rating  uint64
+	// EthUsd is the price of ETH in USD at the block, nil when pricing is off
+	EthUsd            *big.Rat
+	Rating            uint64
+	RatingBreakdown   []RatingScore
+	InternalTransfers []InternalTransfer
+	TokenTransfers    []TokenTransfer
+	NftTransfers      []NftTransfer
+	Swaps             []Swap
+	LiquidityEvents   []LiquidityEvent
+	Failure           *Failure
+	Tags              []string
+	// Mev are the roles of the transaction in detected MEV patterns
+	Mev []string
+}
+
+// InternalTransfer is a value transfer made inside a contract call
+type InternalTransfer struct {
+	Type  string
+	From  common.Address
+	To    common.Address
+	Value *big.Int
+	Depth int
+}
+
+// Failure is the decoded reason of a failed transaction
+type Failure struct {
+	Kind   string
+	Reason string
+}
+
+// Transaction categories assigned by the classifier
+const (
+	TxTypeTransfer        = "transfer"
+	TxTypeDeployment      = "deployment"
+	TxTypeErc20Transfer   = "erc20_transfer"
+	TxTypeErc20Approve    = "erc20_approve"
+	TxTypeNftTransfer     = "nft_transfer"
+	TxTypeSwap            = "swap"
+	TxTypeLiquidityAdd    = "liquidity_add"
+	TxTypeLiquidityRemove = "liquidity_remove"
+	TxTypeBridge          = "bridge"
+	TxTypeLending         = "lending"
+	TxTypeMultisig        = "multisig"
+	TxTypeUnknown         = "unknown"
+)
+
+// HasTag tells whether the classifier tagged the transaction with the category
+func (t *Tx) HasTag(tag string) bool {
+	for _, v := range t.Tags {
+		if v == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// RatingScore is the contribution of one scorer to the rating of a transaction
+type RatingScore struct {
+	Name   string
+	Weight float64
+	Score  float64
 }
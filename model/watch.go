@@ -0,0 +1,47 @@
+package model
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Watch is a watched address or contract
+type Watch struct {
+	Address   common.Address `json:"address"`
+	Label     string         `json:"label"`
+	CreatedAt int64          `json:"created_at"`
+}
+
+// What a watch alert matched in the transaction
+const (
+	WatchMatchTx               = "tx"
+	WatchMatchInternalTransfer = "internal_transfer"
+	WatchMatchTokenTransfer    = "token_transfer"
+	WatchMatchNftTransfer      = "nft_transfer"
+	WatchMatchEvent            = "event"
+)
+
+// WatchAlert is one activity of a watched address in a transaction
+type WatchAlert struct {
+	Kind    string         `json:"kind"`
+	Address common.Address `json:"address"`
+	Label   string         `json:"label"`
+	TxHash  common.Hash    `json:"tx_hash"`
+	From    common.Address `json:"from"`
+	// To is absent for contract deployments and events
+	To *common.Address `json:"to,omitempty"`
+	// Value is in wei, or in token units for transfers
+	Value string `json:"value,omitempty"`
+	// Token is the token or collection of a transfer
+	Token    *common.Address `json:"token,omitempty"`
+	Symbol   string          `json:"symbol,omitempty"`
+	TokenId  string          `json:"token_id,omitempty"`
+	Event    string          `json:"event,omitempty"`
+	LogIndex *uint           `json:"log_index,omitempty"`
+}
+
+// WatchPayload is the body posted to webhooks for a block with watched activity
+type WatchPayload struct {
+	Id     string       `json:"id"`
+	Height uint64       `json:"height"`
+	Alerts []WatchAlert `json:"alerts"`
+}
@@ -0,0 +1,75 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"os"
+	"sync"
+)
+
+// Sink delivers JSON payloads. id identifies the payload to the receiver.
+type Sink interface {
+	Send(id string, payload interface{})
+}
+
+// File appends one JSON line per payload to a local file
+type File struct {
+	Path string
+
+	mu sync.Mutex
+	f  *os.File
+}
+
+func (s *File) InitDefault() {
+	f, err := os.OpenFile(s.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		logrus.WithError(err).WithField("file", s.Path).Fatal("failed to open alert file")
+	}
+	s.f = f
+}
+
+func (s *File) Start() {
+}
+
+// Stop closes the file, payloads sent afterwards are dropped with a warning
+func (s *File) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.f.Close(); err != nil {
+		logrus.WithError(err).WithField("file", s.Path).Warn("failed to close payload file")
+	}
+}
+
+func (s *File) Name() string {
+	return "AlertFile"
+}
+
+func (s *File) Send(id string, payload interface{}) {
+	content, err := json.Marshal(payload)
+	if err != nil {
+		logrus.WithError(err).Warn("failed to marshal payload")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err = s.f.Write(append(content, '\n')); err != nil {
+		logrus.WithError(err).WithField("file", s.Path).Warn("failed to write payload")
+	}
+}
+
+// Stdout prints one JSON line per payload
+type Stdout struct {
+	mu sync.Mutex
+}
+
+func (s *Stdout) Send(id string, payload interface{}) {
+	content, err := json.Marshal(payload)
+	if err != nil {
+		logrus.WithError(err).Warn("failed to marshal payload")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Println(string(content))
+}
@@ -0,0 +1,247 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// HeaderSignature is the hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the secret, prefixed with "sha256="
+	HeaderSignature = "X-Etherxray-Signature"
+	HeaderTimestamp = "X-Etherxray-Timestamp"
+	HeaderId        = "X-Etherxray-Id"
+	HeaderAttempt   = "X-Etherxray-Attempt"
+)
+
+// queueSize bounds the deliveries waiting for a worker, payloads beyond it go to the dead letter log
+const queueSize = 1024
+
+// Webhook posts signed JSON payloads to the configured URLs.
+// Failed deliveries are retried with exponential backoff, then written to the dead letter log.
+type Webhook struct {
+	Urls   []string
+	Secret string
+	// MaxAttempts counts the first delivery
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+	Timeout     time.Duration
+	Workers     int
+	// DeadLetterFile receives one JSON line per undelivered payload
+	DeadLetterFile string
+
+	client     *http.Client
+	queue      chan *delivery
+	quit       chan bool
+	wg         sync.WaitGroup
+	stopMu     sync.RWMutex
+	stopped    bool
+	deadMu     sync.Mutex
+	deadLetter io.WriteCloser
+}
+
+type delivery struct {
+	Url      string          `json:"url"`
+	Id       string          `json:"id"`
+	Attempts int             `json:"attempts"`
+	Error    string          `json:"error"`
+	Time     int64           `json:"time"`
+	Payload  json.RawMessage `json:"payload"`
+}
+
+func (w *Webhook) InitDefault() {
+	if w.MaxAttempts <= 0 {
+		w.MaxAttempts = 5
+	}
+	if w.Backoff == 0 {
+		w.Backoff = time.Second * 2
+	}
+	if w.MaxBackoff == 0 {
+		w.MaxBackoff = time.Minute * 5
+	}
+	if w.Timeout == 0 {
+		w.Timeout = time.Second * 10
+	}
+	if w.Workers <= 0 {
+		w.Workers = 4
+	}
+	w.client = &http.Client{Timeout: w.Timeout}
+	w.queue = make(chan *delivery, queueSize)
+	w.quit = make(chan bool)
+	if w.DeadLetterFile != "" {
+		f, err := os.OpenFile(w.DeadLetterFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			logrus.WithError(err).WithField("file", w.DeadLetterFile).Fatal("failed to open dead letter log")
+		}
+		w.deadLetter = f
+	}
+}
+
+func (w *Webhook) Start() {
+	for i := 0; i < w.Workers; i++ {
+		w.wg.Add(1)
+		go w.work()
+	}
+}
+
+// Stop waits for the deliveries in progress. Queued payloads are written to the dead letter log.
+func (w *Webhook) Stop() {
+	// no payload is queued once stopped
+	w.stopMu.Lock()
+	w.stopped = true
+	w.stopMu.Unlock()
+	close(w.quit)
+	w.wg.Wait()
+	for {
+		select {
+		case d := <-w.queue:
+			d.Error = "stopped before delivery"
+			w.dead(d)
+		default:
+			if w.deadLetter != nil {
+				_ = w.deadLetter.Close()
+			}
+			return
+		}
+	}
+}
+
+func (w *Webhook) Name() string {
+	return "Webhook"
+}
+
+// Send queues the payload for every URL. id identifies the payload to the receivers.
+// Payloads sent after Stop are refused and logged.
+func (w *Webhook) Send(id string, payload interface{}) {
+	content, err := json.Marshal(payload)
+	if err != nil {
+		logrus.WithError(err).Warn("failed to marshal webhook payload")
+		return
+	}
+	w.stopMu.RLock()
+	defer w.stopMu.RUnlock()
+	if w.stopped {
+		logrus.WithField("id", id).WithField("payload", string(content)).Error("webhook stopped, payload dropped")
+		return
+	}
+	for _, url := range w.Urls {
+		d := &delivery{
+			Url:     url,
+			Id:      id,
+			Payload: content,
+		}
+		select {
+		case w.queue <- d:
+		default:
+			d.Error = "queue full"
+			w.dead(d)
+		}
+	}
+}
+
+func (w *Webhook) work() {
+	defer w.wg.Done()
+	for {
+		select {
+		case <-w.quit:
+			return
+		case d := <-w.queue:
+			w.deliver(d)
+		}
+	}
+}
+
+// deliver posts the payload until it is accepted, refused or out of attempts
+func (w *Webhook) deliver(d *delivery) {
+	backoff := w.Backoff
+	for {
+		d.Attempts++
+		retry, err := w.post(d)
+		if err == nil {
+			return
+		}
+		d.Error = err.Error()
+		if !retry || d.Attempts >= w.MaxAttempts {
+			w.dead(d)
+			return
+		}
+		logrus.WithError(err).WithField("url", d.Url).WithField("attempt", d.Attempts).Debug("webhook delivery failed")
+		select {
+		case <-w.quit:
+			w.dead(d)
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > w.MaxBackoff {
+			backoff = w.MaxBackoff
+		}
+	}
+}
+
+// post makes one delivery attempt. Client errors other than 429 are not retried.
+func (w *Webhook) post(d *delivery) (retry bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, d.Url, bytes.NewReader(d.Payload))
+	if err != nil {
+		return
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderId, d.Id)
+	req.Header.Set(HeaderAttempt, strconv.Itoa(d.Attempts))
+	req.Header.Set(HeaderTimestamp, timestamp)
+	if w.Secret != "" {
+		req.Header.Set(HeaderSignature, "sha256="+Sign(w.Secret, timestamp, d.Payload))
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		retry = true
+		return
+	}
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return
+	}
+	retry = resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+	err = fmt.Errorf("status %d", resp.StatusCode)
+	return
+}
+
+// Sign returns the hex HMAC-SHA256 of "<timestamp>.<body>", for receivers to verify the payload
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (w *Webhook) dead(d *delivery) {
+	logrus.WithField("url", d.Url).WithField("id", d.Id).WithField("error", d.Error).Warn("webhook payload undelivered")
+	if w.deadLetter == nil {
+		return
+	}
+	d.Time = time.Now().Unix()
+	content, err := json.Marshal(d)
+	if err != nil {
+		return
+	}
+	w.deadMu.Lock()
+	defer w.deadMu.Unlock()
+	if _, err = w.deadLetter.Write(append(content, '\n')); err != nil {
+		logrus.WithError(err).Warn("failed to write dead letter log")
+	}
+}
@@ -0,0 +1,266 @@
+package pricing
+
+import (
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/latifrons/etherxray/dex"
+	"github.com/latifrons/etherxray/metrics"
+	"github.com/latifrons/etherxray/middleware"
+	"github.com/latifrons/etherxray/model"
+	"github.com/latifrons/etherxray/token"
+	"github.com/latifrons/etherxray/tools"
+	"math/big"
+	"sync"
+)
+
+// maxCacheEntries bounds the quote and pool caches, which are cleared when full
+const maxCacheEntries = 4096
+
+var ErrNoRoute = errors.New("no pool route to WETH")
+
+// Oracles are the reference tokens prices are routed to
+type Oracles struct {
+	Weth       common.Address
+	Stablecoin common.Address
+	// EthUsdPool pins the pool pricing WETH in the stablecoin. Zero picks the deepest WETH/stablecoin pool.
+	EthUsdPool common.Address
+}
+
+// Service values tokens in ETH and USD through the deepest path to WETH over the crawled V2 and V3 pools.
+// A path is either a direct pool or goes through one of the Connectors.
+type Service struct {
+	RpcWrapper *middleware.RpcWrapper
+	Tokens     *token.Cache
+	Pairs      *dex.PairStore
+	Oracles    Oracles
+	Connectors []common.Address
+
+	mu     sync.Mutex
+	quotes map[quoteKey]*model.TokenPrice
+	pools  map[poolKey]*poolState
+}
+
+type quoteKey struct {
+	oracles Oracles
+	token   common.Address
+	height  uint64
+}
+
+type poolKey struct {
+	pool   common.Address
+	height uint64
+}
+
+// poolState is the price of token0 in token1 and the reserves of a pool, in token units
+type poolState struct {
+	price0   *big.Rat
+	reserve0 *big.Rat
+	reserve1 *big.Rat
+}
+
+func (s *Service) InitDefault() {
+	s.quotes = make(map[quoteKey]*model.TokenPrice)
+	s.pools = make(map[poolKey]*poolState)
+}
+
+// Quote values one token at the given height with the configured oracles
+func (s *Service) Quote(token common.Address, height uint64) (*model.TokenPrice, error) {
+	return s.QuoteWith(s.Oracles, token, height)
+}
+
+// QuoteWith values one token at the given height with the given oracles
+func (s *Service) QuoteWith(oracles Oracles, token common.Address, height uint64) (price *model.TokenPrice, err error) {
+	key := quoteKey{oracles: oracles, token: token, height: height}
+	s.mu.Lock()
+	price, ok := s.quotes[key]
+	s.mu.Unlock()
+	metrics.Cache("price", ok)
+	if ok {
+		return
+	}
+
+	price = &model.TokenPrice{
+		Token:  token,
+		Height: height,
+	}
+	ethUsd, ethUsdErr := s.EthUsdWith(oracles, height)
+	if token == oracles.Stablecoin && ethUsdErr == nil {
+		price.Usd = big.NewRat(1, 1)
+		price.Eth = big.NewRat(1, 1).Inv(ethUsd)
+	} else {
+		price.Eth, price.Path, price.Depth, err = s.route(oracles.Weth, token, height)
+		if err != nil {
+			return
+		}
+		if ethUsdErr == nil {
+			price.Usd = big.NewRat(1, 1).Mul(price.Eth, ethUsd)
+		}
+	}
+
+	s.mu.Lock()
+	if len(s.quotes) >= maxCacheEntries {
+		s.quotes = make(map[quoteKey]*model.TokenPrice)
+	}
+	s.quotes[key] = price
+	s.mu.Unlock()
+	return
+}
+
+// EthUsd returns the price of ETH in USD at the given height with the configured oracles
+func (s *Service) EthUsd(height uint64) (*big.Rat, error) {
+	return s.EthUsdWith(s.Oracles, height)
+}
+
+// EthUsdWith returns the price of WETH in the stablecoin at the given height
+func (s *Service) EthUsdWith(oracles Oracles, height uint64) (price *big.Rat, err error) {
+	if oracles.Stablecoin == (common.Address{}) {
+		err = errors.New("no stablecoin configured")
+		return
+	}
+	if oracles.EthUsdPool != (common.Address{}) {
+		pair, ok := s.Pairs.Get(oracles.EthUsdPool)
+		if !ok {
+			err = errors.New("ETH/USD pool is not crawled")
+			return
+		}
+		price, _, err = s.pairPrice(pair, oracles.Weth, height)
+		return
+	}
+	price, _, _, err = s.deepestPool(oracles.Weth, oracles.Stablecoin, height)
+	return
+}
+
+// route finds the deepest path from token to WETH and returns the price of token in ETH
+func (s *Service) route(weth common.Address, token common.Address, height uint64) (price *big.Rat, path []common.Address, depth *big.Rat, err error) {
+	if token == weth {
+		price = big.NewRat(1, 1)
+		return
+	}
+	// direct pool
+	price, depth, pool, er := s.deepestPool(token, weth, height)
+	if er == nil {
+		path = []common.Address{pool}
+	}
+	for _, connector := range s.Connectors {
+		if connector == token || connector == weth {
+			continue
+		}
+		price1, depth1, pool1, er := s.deepestPool(token, connector, height)
+		if er != nil {
+			continue
+		}
+		price2, depth2, pool2, er := s.deepestPool(connector, weth, height)
+		if er != nil {
+			continue
+		}
+		// depth of the first hop in ETH
+		hopDepth := big.NewRat(1, 1).Mul(depth1, price2)
+		if hopDepth.Cmp(depth2) > 0 {
+			hopDepth = depth2
+		}
+		if depth == nil || hopDepth.Cmp(depth) > 0 {
+			price = big.NewRat(1, 1).Mul(price1, price2)
+			depth = hopDepth
+			path = []common.Address{pool1, pool2}
+		}
+	}
+	if path == nil {
+		err = ErrNoRoute
+	}
+	return
+}
+
+// deepestPool returns the price of tokenIn in tokenOut from the pool between them with the largest tokenOut reserve.
+// depth is that reserve in tokenOut units.
+func (s *Service) deepestPool(tokenIn common.Address, tokenOut common.Address, height uint64) (price *big.Rat, depth *big.Rat, pool common.Address, err error) {
+	for _, pair := range s.Pairs.ByToken(tokenIn) {
+		if pair.Token0 != tokenOut && pair.Token1 != tokenOut {
+			continue
+		}
+		p, reserveOut, er := s.pairPrice(pair, tokenIn, height)
+		if er != nil || reserveOut.Sign() == 0 {
+			continue
+		}
+		if depth == nil || reserveOut.Cmp(depth) > 0 {
+			price, depth, pool = p, reserveOut, pair.Address
+		}
+	}
+	if depth == nil {
+		err = ErrNoRoute
+	}
+	return
+}
+
+// pairPrice returns the price of tokenIn in the other token of the pair and the reserve of the other token
+func (s *Service) pairPrice(pair *model.Pair, tokenIn common.Address, height uint64) (price *big.Rat, reserveOut *big.Rat, err error) {
+	state, err := s.poolState(pair, height)
+	if err != nil {
+		return
+	}
+	if state.price0 == nil || state.price0.Sign() == 0 {
+		err = ErrNoRoute
+		return
+	}
+	if tokenIn == pair.Token0 {
+		return state.price0, state.reserve1, nil
+	}
+	return big.NewRat(1, 1).Inv(state.price0), state.reserve0, nil
+}
+
+func (s *Service) poolState(pair *model.Pair, height uint64) (state *poolState, err error) {
+	key := poolKey{pool: pair.Address, height: height}
+	s.mu.Lock()
+	state, ok := s.pools[key]
+	s.mu.Unlock()
+	metrics.Cache("pool_state", ok)
+	if ok {
+		return
+	}
+
+	token0 := s.Tokens.Get(pair.Token0)
+	token1 := s.Tokens.Get(pair.Token1)
+	blockHeight := big.NewInt(0).SetUint64(height)
+	state = &poolState{}
+	if pair.IsV3() {
+		// V3 liquidity is concentrated, the token balances of the pool are used as its depth
+		slot0, er := s.RpcWrapper.GetUniswapV3Slot0(tools.GetContextDefault(), pair.Address, blockHeight)
+		if er != nil {
+			err = er
+			return
+		}
+		balance0, er := s.RpcWrapper.GetTokenBalance(tools.GetContextDefault(), pair.Token0, pair.Address, blockHeight)
+		if er != nil {
+			err = er
+			return
+		}
+		balance1, er := s.RpcWrapper.GetTokenBalance(tools.GetContextDefault(), pair.Token1, pair.Address, blockHeight)
+		if er != nil {
+			err = er
+			return
+		}
+		state.reserve0 = tools.FromDecimals(balance0, token0.Decimals)
+		state.reserve1 = tools.FromDecimals(balance1, token1.Decimals)
+		if slot0.SqrtPriceX96.Sign() != 0 {
+			state.price0 = dex.SqrtPriceX96ToPrice(slot0.SqrtPriceX96, token0.Decimals, token1.Decimals)
+		}
+	} else {
+		reserves, er := s.RpcWrapper.GetUniswapLiquidities(tools.GetContextDefault(), pair.Address, blockHeight)
+		if er != nil {
+			err = er
+			return
+		}
+		state.reserve0 = tools.FromDecimals(reserves.Reserve0, token0.Decimals)
+		state.reserve1 = tools.FromDecimals(reserves.Reserve1, token1.Decimals)
+		if state.reserve0.Sign() != 0 {
+			state.price0 = big.NewRat(1, 1).Quo(state.reserve1, state.reserve0)
+		}
+	}
+
+	s.mu.Lock()
+	if len(s.pools) >= maxCacheEntries {
+		s.pools = make(map[poolKey]*poolState)
+	}
+	s.pools[key] = state
+	s.mu.Unlock()
+	return
+}
@@ -0,0 +1,106 @@
+package rating
+
+import (
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/latifrons/etherxray/model"
+	"math"
+	"sort"
+)
+
+const DefaultMaxRating = 5
+
+// BlockContext is what a scorer may know about the block a transaction is in
+type BlockContext struct {
+	Coinbase common.Address
+	Position int
+}
+
+// Scorer scores one aspect of a transaction between 0 and 1
+type Scorer interface {
+	Name() string
+	Score(tx *model.Tx, block *BlockContext) float64
+}
+
+// Params is the configuration section of a scorer
+type Params interface {
+	GetFloat64(key string) float64
+	GetStringSlice(key string) []string
+	IsSet(key string) bool
+}
+
+// ScorerFactory builds a scorer from its configuration section
+type ScorerFactory func(params Params) (Scorer, error)
+
+var factories = map[string]ScorerFactory{}
+
+// Register makes a scorer available to the rating configuration under the given name
+func Register(name string, factory ScorerFactory) {
+	factories[name] = factory
+}
+
+// NewScorer builds the registered scorer with the given name
+func NewScorer(name string, params Params) (Scorer, error) {
+	factory, ok := factories[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown scorer %s", name)
+	}
+	return factory(params)
+}
+
+type weightedScorer struct {
+	scorer Scorer
+	weight float64
+}
+
+// Engine rates transactions as the weighted sum of its scorers, capped at Max.
+// A weight is the number of stars a scorer contributes when its score is 1.
+type Engine struct {
+	Max uint64
+
+	scorers []weightedScorer
+}
+
+func (e *Engine) InitDefault() {
+	if e.Max == 0 {
+		e.Max = DefaultMaxRating
+	}
+}
+
+func (e *Engine) AddScorer(scorer Scorer, weight float64) {
+	e.scorers = append(e.scorers, weightedScorer{
+		scorer: scorer,
+		weight: weight,
+	})
+	sort.SliceStable(e.scorers, func(i, j int) bool {
+		return e.scorers[i].scorer.Name() < e.scorers[j].scorer.Name()
+	})
+}
+
+// Rate returns the rating of the transaction and the contribution of every scorer
+func (e *Engine) Rate(tx *model.Tx, block *BlockContext) (rating uint64, breakdown []model.RatingScore) {
+	total := 0.0
+	for _, ws := range e.scorers {
+		score := ws.scorer.Score(tx, block)
+		score = math.Max(0, math.Min(1, score))
+		total += score * ws.weight
+		breakdown = append(breakdown, model.RatingScore{
+			Name:   ws.scorer.Name(),
+			Weight: ws.weight,
+			Score:  score,
+		})
+	}
+	total = math.Max(0, math.Min(float64(e.Max), total))
+	rating = uint64(math.Floor(total))
+	return
+}
+
+// RateBlock rates every transaction of a block in place
+func (e *Engine) RateBlock(txs []model.Tx, coinbase common.Address) {
+	for i := range txs {
+		txs[i].Rating, txs[i].RatingBreakdown = e.Rate(&txs[i], &BlockContext{
+			Coinbase: coinbase,
+			Position: i,
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package rating
+
+import (
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/latifrons/etherxray/model"
+	"github.com/latifrons/etherxray/tools"
+	"math/big"
+)
+
+func init() {
+	Register("gas", newGasScorer)
+	Register("value", newValueScorer)
+	Register("logs", newLogsScorer)
+	Register("depth", newDepthScorer)
+	Register("watchlist", newWatchlistScorer)
+	Register("failed", newFailedScorer)
+	Register("mev", newMevScorer)
+}
+
+// paramOr reads a float parameter, falling back to the default when missing
+func paramOr(params Params, key string, def float64) float64 {
+	if params == nil || !params.IsSet(key) {
+		return def
+	}
+	return params.GetFloat64(key)
+}
+
+// fullParam reads the value scoring 1, which must be positive
+func fullParam(params Params, def float64) (full float64, err error) {
+	full = paramOr(params, "full", def)
+	if full <= 0 {
+		err = fmt.Errorf("full must be positive, got %v", full)
+	}
+	return
+}
+
+// GasScorer scores the gas consumed, reaching 1 at Full gas
+type GasScorer struct {
+	Full float64
+}
+
+func newGasScorer(params Params) (Scorer, error) {
+	full, err := fullParam(params, 525000)
+	if err != nil {
+		return nil, err
+	}
+	return &GasScorer{Full: full}, nil
+}
+
+func (s *GasScorer) Name() string {
+	return "gas"
+}
+
+func (s *GasScorer) Score(tx *model.Tx, block *BlockContext) float64 {
+	return float64(tx.Receipt.GasUsed) / s.Full
+}
+
+// ValueScorer scores the ether moved by the transaction and its internal transfers, reaching 1 at Full ether
+type ValueScorer struct {
+	Full float64
+}
+
+func newValueScorer(params Params) (Scorer, error) {
+	full, err := fullParam(params, 100)
+	if err != nil {
+		return nil, err
+	}
+	return &ValueScorer{Full: full}, nil
+}
+
+func (s *ValueScorer) Name() string {
+	return "value"
+}
+
+func (s *ValueScorer) Score(tx *model.Tx, block *BlockContext) float64 {
+	total := big.NewInt(0).Set(tx.BasicTx.Value())
+	for _, transfer := range tx.InternalTransfers {
+		total.Add(total, transfer.Value)
+	}
+	value, _ := tools.FromWei(total).Float64()
+	return value / s.Full
+}
+
+// LogsScorer scores the number of emitted logs, reaching 1 at Full logs
+type LogsScorer struct {
+	Full float64
+}
+
+func newLogsScorer(params Params) (Scorer, error) {
+	full, err := fullParam(params, 20)
+	if err != nil {
+		return nil, err
+	}
+	return &LogsScorer{Full: full}, nil
+}
+
+func (s *LogsScorer) Name() string {
+	return "logs"
+}
+
+func (s *LogsScorer) Score(tx *model.Tx, block *BlockContext) float64 {
+	return float64(len(tx.Receipt.Logs)) / s.Full
+}
+
+// DepthScorer scores the deepest internal transfer, reaching 1 at Full call depth.
+// It needs internal transfer enrichment.
+type DepthScorer struct {
+	Full float64
+}
+
+func newDepthScorer(params Params) (Scorer, error) {
+	full, err := fullParam(params, 5)
+	if err != nil {
+		return nil, err
+	}
+	return &DepthScorer{Full: full}, nil
+}
+
+func (s *DepthScorer) Name() string {
+	return "depth"
+}
+
+func (s *DepthScorer) Score(tx *model.Tx, block *BlockContext) float64 {
+	depth := 0
+	for _, transfer := range tx.InternalTransfers {
+		if transfer.Depth > depth {
+			depth = transfer.Depth
+		}
+	}
+	return float64(depth) / s.Full
+}
+
+// WatchlistScorer scores 1 when a watched address sends, receives or is called
+type WatchlistScorer struct {
+	Addresses map[common.Address]bool
+}
+
+func newWatchlistScorer(params Params) (Scorer, error) {
+	s := &WatchlistScorer{Addresses: make(map[common.Address]bool)}
+	if params != nil {
+		for _, address := range params.GetStringSlice("addresses") {
+			s.Addresses[common.HexToAddress(address)] = true
+		}
+	}
+	return s, nil
+}
+
+func (s *WatchlistScorer) Name() string {
+	return "watchlist"
+}
+
+func (s *WatchlistScorer) Score(tx *model.Tx, block *BlockContext) float64 {
+	if s.Addresses[tx.From] {
+		return 1
+	}
+	if tx.BasicTx.To() != nil && s.Addresses[*tx.BasicTx.To()] {
+		return 1
+	}
+	for _, transfer := range tx.InternalTransfers {
+		if s.Addresses[transfer.From] || s.Addresses[transfer.To] {
+			return 1
+		}
+	}
+	return 0
+}
+
+// FailedScorer scores 1 for failed transactions
+type FailedScorer struct {
+}
+
+func newFailedScorer(params Params) (Scorer, error) {
+	return &FailedScorer{}, nil
+}
+
+func (s *FailedScorer) Name() string {
+	return "failed"
+}
+
+func (s *FailedScorer) Score(tx *model.Tx, block *BlockContext) float64 {
+	if tx.Receipt.Status != types.ReceiptStatusSuccessful {
+		return 1
+	}
+	return 0
+}
+
+// MevScorer looks for signals of MEV extraction: a detected sandwich leg, a direct payment to the block producer,
+// a zero gas price bundle transaction, or a swap at the top of the block
+type MevScorer struct {
+	TopPositions int
+}
+
+func newMevScorer(params Params) (Scorer, error) {
+	return &MevScorer{TopPositions: int(paramOr(params, "top_positions", 3))}, nil
+}
+
+func (s *MevScorer) Name() string {
+	return "mev"
+}
+
+func (s *MevScorer) Score(tx *model.Tx, block *BlockContext) float64 {
+	if tx.HasMev(model.MevSandwichFront) || tx.HasMev(model.MevSandwichBack) {
+		return 1
+	}
+	if block.Coinbase != (common.Address{}) {
+		if tx.BasicTx.To() != nil && *tx.BasicTx.To() == block.Coinbase && tx.BasicTx.Value().Sign() > 0 {
+			return 1
+		}
+		for _, transfer := range tx.InternalTransfers {
+			if transfer.To == block.Coinbase {
+				return 1
+			}
+		}
+	}
+	if tx.BasicTx.GasPrice().Sign() == 0 {
+		return 1
+	}
+	if block.Position < s.TopPositions && tx.HasTag(model.TxTypeSwap) {
+		return 0.5
+	}
+	return 0
+}
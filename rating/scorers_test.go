@@ -0,0 +1,134 @@
+package rating
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/latifrons/etherxray/model"
+	"math"
+	"math/big"
+	"testing"
+)
+
+// testParams is a scorer configuration section
+type testParams map[string]interface{}
+
+func (p testParams) GetFloat64(key string) float64 {
+	v, _ := p[key].(float64)
+	return v
+}
+
+func (p testParams) GetStringSlice(key string) []string {
+	v, _ := p[key].([]string)
+	return v
+}
+
+func (p testParams) IsSet(key string) bool {
+	_, ok := p[key]
+	return ok
+}
+
+var (
+	testCoinbase = common.HexToAddress("0x00000000000000000000000000000000000000c0")
+	testWatched  = common.HexToAddress("0x00000000000000000000000000000000000000a1")
+)
+
+func ether(n int64) *big.Int {
+	return big.NewInt(0).Mul(big.NewInt(n), big.NewInt(1e18))
+}
+
+func testTx(to common.Address, value *big.Int, gasPrice int64, gasUsed uint64) *model.Tx {
+	return &model.Tx{
+		BasicTx: types.NewTransaction(0, to, value, 1000000, big.NewInt(gasPrice), nil),
+		Receipt: &types.Receipt{Status: types.ReceiptStatusSuccessful, GasUsed: gasUsed},
+	}
+}
+
+func TestFullMustBePositive(t *testing.T) {
+	for _, name := range []string{"gas", "value", "logs", "depth"} {
+		for _, full := range []float64{0, -1} {
+			if _, err := NewScorer(name, testParams{"full": full}); err == nil {
+				t.Errorf("%s scorer accepted full = %v", name, full)
+			}
+		}
+		scorer, err := NewScorer(name, nil)
+		if err != nil {
+			t.Errorf("%s scorer without params: %v", name, err)
+			continue
+		}
+		score := scorer.Score(testTx(common.Address{}, big.NewInt(0), 1, 21000), &BlockContext{})
+		if math.IsNaN(score) || math.IsInf(score, 0) {
+			t.Errorf("%s scorer with the default full scored %v", name, score)
+		}
+	}
+}
+
+func TestScorers(t *testing.T) {
+	deep := testTx(common.Address{}, ether(30), 1, 21000)
+	deep.InternalTransfers = []model.InternalTransfer{
+		{To: testWatched, Value: ether(20), Depth: 2},
+		{To: testCoinbase, Value: big.NewInt(1), Depth: 4},
+	}
+	logs := testTx(common.Address{}, big.NewInt(0), 1, 21000)
+	logs.Receipt.Logs = make([]*types.Log, 5)
+	failed := testTx(common.Address{}, big.NewInt(0), 1, 21000)
+	failed.Receipt.Status = types.ReceiptStatusFailed
+	sandwich := testTx(common.Address{}, big.NewInt(0), 1, 21000)
+	sandwich.Mev = []string{model.MevSandwichBack}
+	swap := testTx(common.Address{}, big.NewInt(0), 1, 21000)
+	swap.Tags = []string{model.TxTypeSwap}
+
+	tests := []struct {
+		name   string
+		scorer string
+		params Params
+		tx     *model.Tx
+		block  BlockContext
+		score  float64
+	}{
+		{"gas half of full", "gas", testParams{"full": 42000.0}, testTx(common.Address{}, big.NewInt(0), 1, 21000), BlockContext{}, 0.5},
+		{"value counts internal transfers", "value", testParams{"full": 100.0}, deep, BlockContext{}, 0.5},
+		{"logs", "logs", testParams{"full": 20.0}, logs, BlockContext{}, 0.25},
+		{"depth of the deepest transfer", "depth", testParams{"full": 8.0}, deep, BlockContext{}, 0.5},
+		{"watchlist internal transfer", "watchlist", testParams{"addresses": []string{testWatched.Hex()}}, deep, BlockContext{}, 1},
+		{"watchlist not involved", "watchlist", testParams{"addresses": []string{testWatched.Hex()}}, logs, BlockContext{}, 0},
+		{"failed", "failed", nil, failed, BlockContext{}, 1},
+		{"succeeded", "failed", nil, logs, BlockContext{}, 0},
+		{"mev sandwich leg", "mev", nil, sandwich, BlockContext{Position: 10}, 1},
+		{"mev coinbase payment", "mev", nil, deep, BlockContext{Coinbase: testCoinbase, Position: 10}, 1},
+		{"mev zero gas price", "mev", nil, testTx(common.Address{}, big.NewInt(0), 0, 21000), BlockContext{Position: 10}, 1},
+		{"mev swap at the top", "mev", nil, swap, BlockContext{Position: 1}, 0.5},
+		{"mev swap further down", "mev", nil, swap, BlockContext{Position: 3}, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			scorer, err := NewScorer(test.scorer, test.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if score := scorer.Score(test.tx, &test.block); score != test.score {
+				t.Errorf("score %v, want %v", score, test.score)
+			}
+		})
+	}
+}
+
+func TestEngineCapsRating(t *testing.T) {
+	engine := &Engine{Max: 3}
+	engine.InitDefault()
+	for _, name := range []string{"failed", "mev"} {
+		scorer, err := NewScorer(name, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		engine.AddScorer(scorer, 2)
+	}
+	tx := testTx(common.Address{}, big.NewInt(0), 0, 21000)
+	tx.Receipt.Status = types.ReceiptStatusFailed
+	rating, breakdown := engine.Rate(tx, &BlockContext{})
+	if rating != 3 {
+		t.Errorf("rating %d, want the cap 3", rating)
+	}
+	if len(breakdown) != 2 || breakdown[0].Name != "failed" || breakdown[1].Name != "mev" {
+		t.Errorf("breakdown %+v, want failed then mev", breakdown)
+	}
+}
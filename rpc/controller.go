@@ -1,14 +1,25 @@
 package rpc
 
 import (
+	"encoding/csv"
 	"errors"
 	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-contrib/static"
 	"github.com/gin-gonic/gin"
+	"github.com/latifrons/etherxray/auth"
+	"github.com/latifrons/etherxray/dex"
 	"github.com/latifrons/etherxray/ethnode"
+	"github.com/latifrons/etherxray/health"
+	"github.com/latifrons/etherxray/indexer"
+	"github.com/latifrons/etherxray/metrics"
 	"github.com/latifrons/etherxray/model"
+	"github.com/latifrons/etherxray/pricing"
+	"github.com/latifrons/etherxray/store"
 	"github.com/latifrons/etherxray/tools"
+	"github.com/latifrons/etherxray/watch"
 	"github.com/sirupsen/logrus"
 	"math/big"
 	"net/http"
@@ -16,11 +27,41 @@ import (
 )
 
 type RpcController struct {
-	EthNode *ethnode.EthNode
+	EthNode  *ethnode.EthNode
+	Pairs    *dex.PairStore
+	History  *dex.PoolHistory
+	V3       *dex.V3Reader
+	Balancer *dex.BalancerInspector
+	Pricing  *pricing.Service
+	// Index is nil when the local index is disabled
+	Index store.Repository
+	// Indexer is nil when the indexer is disabled
+	Indexer *indexer.Indexer
+	// Watchlist is nil when neither watching nor retention is enabled
+	Watchlist  *watch.Watchlist
+	NodeHealth *health.Checker
+	// Metrics measures the HTTP requests and serves /metrics on the admin listener
+	Metrics bool
+	// Guard is nil when the API is open
+	Guard *auth.Guard
+	// CorsOrigins are the origins allowed to call the API from a browser, "*" for any
+	CorsOrigins     []string
+	CorsCredentials bool
 }
 
 func (rpc *RpcController) NewRouter() *gin.Engine {
+	return rpc.addRouter(rpc.newEngine())
+}
+
+// NewAdminRouter serves the operational routes without API keys nor limits, for a listener bound to localhost
+func (rpc *RpcController) NewAdminRouter() *gin.Engine {
+	return rpc.addAdminRouter(rpc.newEngine())
+}
+
+func (rpc *RpcController) newEngine() *gin.Engine {
 	router := gin.New()
+	// X-Forwarded-For is only believed from the trusted proxies, see auth.Guard
+	router.ForwardedByClientIP = false
 	if logrus.GetLevel() > logrus.DebugLevel {
 		logger := gin.LoggerWithConfig(gin.LoggerConfig{
 			Formatter: ginLogFormatter,
@@ -31,7 +72,7 @@ func (rpc *RpcController) NewRouter() *gin.Engine {
 	}
 
 	router.Use(gin.RecoveryWithWriter(logrus.StandardLogger().Out))
-	return rpc.addRouter(router)
+	return router
 }
 
 var ginLogFormatter = func(param gin.LogFormatterParams) string {
@@ -65,27 +106,83 @@ var ginLogFormatter = func(param gin.LogFormatterParams) string {
 }
 
 func (rpc *RpcController) addRouter(router *gin.Engine) *gin.Engine {
-	router.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{"*"},
-		AllowHeaders:     []string{"*"},
-		ExposeHeaders:    []string{"Content-Length"},
-		AllowCredentials: true,
-	}))
+	if rpc.Metrics {
+		router.Use(metrics.GinMiddleware())
+	}
+	// without origins, browsers only call the API from the pages it serves
+	if len(rpc.CorsOrigins) > 0 {
+		router.Use(cors.New(cors.Config{
+			AllowOrigins:     rpc.CorsOrigins,
+			AllowMethods:     []string{"GET", "POST", "DELETE", "OPTIONS"},
+			AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-Api-Key"},
+			ExposeHeaders:    []string{"Content-Length", "Retry-After", "X-RateLimit-Remaining"},
+			AllowCredentials: rpc.CorsCredentials,
+		}))
+	}
 	router.Use(static.Serve("/", static.LocalFile("web", false)))
 
-	router.GET("/health", rpc.Health)
-	router.GET("/block/:height", rpc.Block)
+	router.GET("/openapi.json", rpc.OpenApi)
+	for _, r := range rpc.routes() {
+		if r.DocOnly {
+			continue
+		}
+		if r.Name == "" {
+			router.Handle(r.Method, r.Path, r.Handler)
+			continue
+		}
+		router.Handle(r.Method, r.Path, rpc.guard(r.Name, r.Scope), r.Handler)
+	}
 
 	return router
 }
 
+// guard checks the API key and the rate limits before the handler, route names the cost of the call
+func (rpc *RpcController) guard(route string, scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if rpc.Guard == nil {
+			return
+		}
+		if status, err := rpc.Guard.Admit(c, route, scope); err != nil {
+			c.AbortWithStatusJSON(status, RpcError{Error: err.Error()})
+		}
+	}
+}
+
+func (rpc *RpcController) addAdminRouter(router *gin.Engine) *gin.Engine {
+	if rpc.Metrics {
+		router.GET("/metrics", gin.WrapH(metrics.Handler()))
+	}
+	router.GET("/openapi.json", rpc.OpenApi)
+	for _, r := range rpc.routes() {
+		if r.Admin {
+			router.Handle(r.Method, r.Path, r.Handler)
+		}
+	}
+	return router
+}
+
 func Response(c *gin.Context, status int, err error, data interface{}) {
 	c.JSON(status, data)
 }
 
+// Health fails when the node is unreachable
 func (rpc *RpcController) Health(c *gin.Context) {
-	Response(c, http.StatusOK, nil, "ok")
+	h := rpc.NodeHealth.Check()
+	status := http.StatusOK
+	if !h.Reachable {
+		status = http.StatusServiceUnavailable
+	}
+	Response(c, status, nil, toRpcHealth(h))
+}
+
+// Ready fails when the node is unreachable, syncing or lagging behind the chain
+func (rpc *RpcController) Ready(c *gin.Context) {
+	h := rpc.NodeHealth.Check()
+	status := http.StatusOK
+	if !h.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	Response(c, status, nil, toRpcHealth(h))
 }
 
 func (rpc *RpcController) Block(c *gin.Context) {
@@ -101,12 +198,767 @@ func (rpc *RpcController) Block(c *gin.Context) {
 		Response(c, http.StatusInternalServerError, err, nil)
 		return
 	}
-	txsm := rpc.toRpcTxs(txs)
+	txsm := filterRpcTxs(txs, rpc.toRpcTxs(txs), c.Query("type"))
 
 	Response(c, http.StatusOK, nil, txsm)
 	return
 }
 
+func (rpc *RpcController) BlockMev(c *gin.Context) {
+	height, err := tools.StrToNum(c.Param("height"))
+	if err != nil {
+		Response(c, http.StatusBadRequest, errors.New("bad height"), nil)
+		return
+	}
+	blockMev, err := rpc.EthNode.GetBlockMev(height)
+	if err != nil {
+		Response(c, http.StatusInternalServerError, err, nil)
+		return
+	}
+	Response(c, http.StatusOK, nil, rpc.toRpcBlockMev(blockMev))
+	return
+}
+
+func (rpc *RpcController) IndexerStatus(c *gin.Context) {
+	if rpc.Indexer == nil {
+		Response(c, http.StatusNotImplemented, errors.New("indexer disabled"), nil)
+		return
+	}
+	Response(c, http.StatusOK, nil, toRpcIndexerStatus(rpc.Indexer.Status()))
+}
+
+func (rpc *RpcController) Watches(c *gin.Context) {
+	if rpc.Watchlist == nil {
+		Response(c, http.StatusNotImplemented, errors.New("watch disabled"), nil)
+		return
+	}
+	watches := []RpcWatch{}
+	for _, w := range rpc.Watchlist.All() {
+		watches = append(watches, toRpcWatch(w))
+	}
+	Response(c, http.StatusOK, nil, watches)
+}
+
+func (rpc *RpcController) AddWatch(c *gin.Context) {
+	if rpc.Watchlist == nil {
+		Response(c, http.StatusNotImplemented, errors.New("watch disabled"), nil)
+		return
+	}
+	var req RpcWatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		Response(c, http.StatusBadRequest, err, nil)
+		return
+	}
+	if !common.IsHexAddress(req.Address) {
+		Response(c, http.StatusBadRequest, errors.New("bad address"), nil)
+		return
+	}
+	w := rpc.Watchlist.Add(common.HexToAddress(req.Address), req.Label)
+	Response(c, http.StatusOK, nil, toRpcWatch(w))
+}
+
+func (rpc *RpcController) RemoveWatch(c *gin.Context) {
+	if rpc.Watchlist == nil {
+		Response(c, http.StatusNotImplemented, errors.New("watch disabled"), nil)
+		return
+	}
+	addressS := c.Param("address")
+	if !common.IsHexAddress(addressS) {
+		Response(c, http.StatusBadRequest, errors.New("bad address"), nil)
+		return
+	}
+	if !rpc.Watchlist.Remove(common.HexToAddress(addressS)) {
+		Response(c, http.StatusNotFound, errors.New("address not watched"), nil)
+		return
+	}
+	Response(c, http.StatusOK, nil, "ok")
+}
+
+func (rpc *RpcController) TxTrace(c *gin.Context) {
+	hashS := c.Param("hash")
+	if !isHash(hashS) {
+		Response(c, http.StatusBadRequest, errors.New("bad hash"), nil)
+		return
+	}
+	frame, err := rpc.EthNode.GetTxTrace(common.HexToHash(hashS))
+	if err != nil {
+		Response(c, http.StatusInternalServerError, err, nil)
+		return
+	}
+
+	Response(c, http.StatusOK, nil, rpc.toRpcCallFrame(frame))
+	return
+}
+
+func (rpc *RpcController) TxStateDiff(c *gin.Context) {
+	hashS := c.Param("hash")
+	if !isHash(hashS) {
+		Response(c, http.StatusBadRequest, errors.New("bad hash"), nil)
+		return
+	}
+	changes, err := rpc.EthNode.GetTxStateDiff(common.HexToHash(hashS))
+	if err != nil {
+		Response(c, http.StatusInternalServerError, err, nil)
+		return
+	}
+
+	Response(c, http.StatusOK, nil, rpc.toRpcAccountChanges(changes))
+	return
+}
+
+const (
+	// DefaultAddressScanBlocks is the range scanned by the address page when no range is given
+	DefaultAddressScanBlocks = 10
+	// MaxAddressScanBlocks is the widest range scanned through the node when the index does not hold all of it
+	MaxAddressScanBlocks = 100
+)
+
+func (rpc *RpcController) Address(c *gin.Context) {
+	addressS := c.Param("address")
+	if !common.IsHexAddress(addressS) {
+		Response(c, http.StatusBadRequest, errors.New("bad address"), nil)
+		return
+	}
+	address := common.HexToAddress(addressS)
+
+	from, to, err := rpc.blockRange(c, DefaultAddressScanBlocks)
+	if err != nil {
+		Response(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	var txs []model.Tx
+	if rpc.Index != nil {
+		first, last, er := rpc.Index.HeightRange()
+		switch {
+		case er != nil && er != store.ErrNotFound:
+			Response(c, http.StatusInternalServerError, er, nil)
+			return
+		case er == nil && from >= first && to <= last:
+			txs, err = rpc.Index.TxsByAddress(address, from, to, rpc.limit(c))
+		case to >= from && to-from+1 > MaxAddressScanBlocks:
+			// too wide to scan through the node, the caller narrows the range to the index
+			Response(c, http.StatusConflict, nil, RpcIndexRange{
+				Error: "range not indexed",
+				From:  first,
+				To:    last,
+			})
+			return
+		default:
+			txs, err = rpc.EthNode.GetAddressTxs(address, from, to)
+		}
+	} else {
+		txs, err = rpc.EthNode.GetAddressTxs(address, from, to)
+	}
+	if err != nil {
+		Response(c, http.StatusInternalServerError, err, nil)
+		return
+	}
+
+	activity := RpcAddressActivity{
+		Address:           address.Hex(),
+		From:              from,
+		To:                to,
+		Txs:               []RpcTx{},
+		InternalTransfers: []RpcInternalTransfer{},
+	}
+	for i, rpcTx := range rpc.toRpcTxs(txs) {
+		// the deployment of a contract is part of its history
+		if txs[i].From == address || rpcTx.To == address.Hex() || txs[i].Receipt.ContractAddress == address {
+			activity.Txs = append(activity.Txs, rpcTx)
+		}
+		for _, transfer := range rpcTx.InternalTransfers {
+			if transfer.From == address.Hex() || transfer.To == address.Hex() {
+				activity.InternalTransfers = append(activity.InternalTransfers, transfer)
+			}
+		}
+	}
+
+	Response(c, http.StatusOK, nil, activity)
+	return
+}
+
+// DefaultIndexLimit caps the number of records of an index query without limit
+const DefaultIndexLimit = 1000
+
+func (rpc *RpcController) limit(c *gin.Context) int {
+	limit, err := tools.StrToNum(c.Query("limit"))
+	if err != nil || limit == 0 || limit > DefaultIndexLimit {
+		return DefaultIndexLimit
+	}
+	return int(limit)
+}
+
+func (rpc *RpcController) TokenTxs(c *gin.Context) {
+	addressS := c.Param("address")
+	if !common.IsHexAddress(addressS) {
+		Response(c, http.StatusBadRequest, errors.New("bad address"), nil)
+		return
+	}
+	rpc.indexTxs(c, func(from uint64, to uint64, limit int) ([]model.Tx, error) {
+		return rpc.Index.TxsByToken(common.HexToAddress(addressS), from, to, limit)
+	})
+}
+
+func (rpc *RpcController) SelectorTxs(c *gin.Context) {
+	selectorB, err := hexutil.Decode(c.Param("selector"))
+	if err != nil || len(selectorB) != 4 {
+		Response(c, http.StatusBadRequest, errors.New("bad selector"), nil)
+		return
+	}
+	var selector [4]byte
+	copy(selector[:], selectorB)
+	rpc.indexTxs(c, func(from uint64, to uint64, limit int) ([]model.Tx, error) {
+		return rpc.Index.TxsBySelector(selector, from, to, limit)
+	})
+}
+
+// indexTxs answers a transaction query from the local index over the requested block range
+func (rpc *RpcController) indexTxs(c *gin.Context, query func(from uint64, to uint64, limit int) ([]model.Tx, error)) {
+	if rpc.Index == nil {
+		Response(c, http.StatusNotImplemented, errors.New("index disabled"), nil)
+		return
+	}
+	from, to, err := rpc.blockRange(c, DefaultNftScanBlocks)
+	if err != nil {
+		Response(c, http.StatusBadRequest, err, nil)
+		return
+	}
+	txs, err := query(from, to, rpc.limit(c))
+	if err != nil {
+		Response(c, http.StatusInternalServerError, err, nil)
+		return
+	}
+	rpcTxs := rpc.toRpcTxs(txs)
+	if rpcTxs == nil {
+		rpcTxs = []RpcTx{}
+	}
+	Response(c, http.StatusOK, nil, rpcTxs)
+}
+
+// Logs queries indexed logs by emitter address and/or topic0
+func (rpc *RpcController) Logs(c *gin.Context) {
+	if rpc.Index == nil {
+		Response(c, http.StatusNotImplemented, errors.New("index disabled"), nil)
+		return
+	}
+	filter := model.LogFilter{
+		Limit: rpc.limit(c),
+	}
+	if addressS := c.Query("address"); addressS != "" {
+		if !common.IsHexAddress(addressS) {
+			Response(c, http.StatusBadRequest, errors.New("bad address"), nil)
+			return
+		}
+		filter.Address = common.HexToAddress(addressS)
+	}
+	if topicS := c.Query("topic0"); topicS != "" {
+		if !isHash(topicS) {
+			Response(c, http.StatusBadRequest, errors.New("bad topic0"), nil)
+			return
+		}
+		filter.Topic0 = common.HexToHash(topicS)
+	}
+	if filter.Address == (common.Address{}) && filter.Topic0 == (common.Hash{}) {
+		Response(c, http.StatusBadRequest, errors.New("address or topic0 is required"), nil)
+		return
+	}
+	var err error
+	filter.From, filter.To, err = rpc.blockRange(c, DefaultNftScanBlocks)
+	if err != nil {
+		Response(c, http.StatusBadRequest, err, nil)
+		return
+	}
+	logs, err := rpc.Index.Logs(filter)
+	if err != nil {
+		Response(c, http.StatusInternalServerError, err, nil)
+		return
+	}
+
+	rpcLogs := []RpcEventLog{}
+	for _, log := range logs {
+		rpcLog := RpcEventLog{
+			Height:   log.Height,
+			TxHash:   log.TxHash.Hex(),
+			TxIndex:  log.TxIndex,
+			LogIndex: log.LogIndex,
+			Address:  log.Address.Hex(),
+			Topics:   []string{},
+			Data:     hexutil.Encode(log.Data),
+			Event:    log.Event,
+		}
+		for _, topic := range log.Topics {
+			rpcLog.Topics = append(rpcLog.Topics, topic.Hex())
+		}
+		rpcLogs = append(rpcLogs, rpcLog)
+	}
+	Response(c, http.StatusOK, nil, rpcLogs)
+}
+
+// DefaultNftScanBlocks is the range scanned for a token history when no range is given
+const DefaultNftScanBlocks = 10000
+
+func (rpc *RpcController) NftHistory(c *gin.Context) {
+	contractS := c.Param("contract")
+	if !common.IsHexAddress(contractS) {
+		Response(c, http.StatusBadRequest, errors.New("bad contract"), nil)
+		return
+	}
+	contract := common.HexToAddress(contractS)
+	tokenId, ok := big.NewInt(0).SetString(c.Param("id"), 0)
+	if !ok {
+		Response(c, http.StatusBadRequest, errors.New("bad token id"), nil)
+		return
+	}
+	from, to, err := rpc.blockRange(c, DefaultNftScanBlocks)
+	if err != nil {
+		Response(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	info, items, owners, err := rpc.EthNode.GetNftHistory(contract, tokenId, from, to)
+	if err != nil {
+		Response(c, http.StatusInternalServerError, err, nil)
+		return
+	}
+
+	history := RpcNftHistory{
+		Contract:   contract.Hex(),
+		TokenId:    tokenId.String(),
+		Standard:   model.NftStandardErc721,
+		Collection: info.Name,
+		From:       from,
+		To:         to,
+		Owners:     []RpcNftOwner{},
+		Transfers:  []RpcNftHistoryItem{},
+	}
+	if info.IsErc1155 {
+		history.Standard = model.NftStandardErc1155
+	}
+	for _, item := range items {
+		history.Transfers = append(history.Transfers, RpcNftHistoryItem{
+			Height:   item.Height,
+			TxHash:   item.TxHash.Hex(),
+			Operator: item.Transfer.Operator.Hex(),
+			From:     item.Transfer.From.Hex(),
+			To:       item.Transfer.To.Hex(),
+			Amount:   item.Transfer.Amount.String(),
+		})
+	}
+	for _, owner := range owners {
+		rpcOwner := RpcNftOwner{
+			Owner:       owner.Owner.Hex(),
+			SinceHeight: owner.SinceHeight,
+			Amount:      owner.Amount.String(),
+		}
+		if owner.TxHash != (common.Hash{}) {
+			rpcOwner.TxHash = owner.TxHash.Hex()
+		}
+		history.Owners = append(history.Owners, rpcOwner)
+	}
+
+	Response(c, http.StatusOK, nil, history)
+	return
+}
+
+func (rpc *RpcController) TokenPools(c *gin.Context) {
+	addressS := c.Param("address")
+	if !common.IsHexAddress(addressS) {
+		Response(c, http.StatusBadRequest, errors.New("bad address"), nil)
+		return
+	}
+
+	pools := []RpcPair{}
+	for _, pair := range rpc.Pairs.ByToken(common.HexToAddress(addressS)) {
+		pools = append(pools, RpcPair{
+			Address:      pair.Address.Hex(),
+			Factory:      pair.Factory.Hex(),
+			Token0:       pair.Token0.Hex(),
+			Token0Symbol: rpc.EthNode.Tokens.Get(pair.Token0).Symbol,
+			Token1:       pair.Token1.Hex(),
+			Token1Symbol: rpc.EthNode.Tokens.Get(pair.Token1).Symbol,
+		})
+	}
+
+	Response(c, http.StatusOK, nil, pools)
+	return
+}
+
+// Pool dispatches /pool/:address/history, /pool/:address/v3 and /pool/balancer/:address, because gin does not allow
+// a static segment next to a parameter. History is guarded under its own route name, pool_history, as it calls the
+// node once or twice per sample.
+func (rpc *RpcController) Pool(c *gin.Context) {
+	var route string
+	var handler gin.HandlerFunc
+	switch {
+	case c.Param("address") == "balancer":
+		route, handler = "pool", func(c *gin.Context) {
+			rpc.BalancerPool(c, c.Param("action"))
+		}
+	case c.Param("action") == "history":
+		route, handler = "pool_history", rpc.PoolHistory
+	case c.Param("action") == "v3":
+		route, handler = "pool", rpc.PoolV3
+	default:
+		Response(c, http.StatusNotFound, errors.New("not found"), nil)
+		return
+	}
+	if rpc.guard(route, auth.ScopeRead)(c); c.IsAborted() {
+		return
+	}
+	handler(c)
+}
+
+const (
+	PoolHistoryModeInterval = "interval"
+	PoolHistoryModeSync     = "sync"
+	// DefaultPoolHistorySamples is the number of samples of an interval query without step
+	DefaultPoolHistorySamples = 100
+	DefaultPoolHistoryBlocks  = 10000
+)
+
+func (rpc *RpcController) PoolHistory(c *gin.Context) {
+	addressS := c.Param("address")
+	if !common.IsHexAddress(addressS) {
+		Response(c, http.StatusBadRequest, errors.New("bad address"), nil)
+		return
+	}
+	pool := common.HexToAddress(addressS)
+	from, to, err := rpc.blockRange(c, DefaultPoolHistoryBlocks)
+	if err != nil {
+		Response(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	mode := c.DefaultQuery("mode", PoolHistoryModeInterval)
+	var samples []*model.PoolSample
+	switch mode {
+	case PoolHistoryModeInterval:
+		var step uint64
+		if stepS := c.Query("step"); stepS != "" {
+			step, err = tools.StrToNum(stepS)
+			if err != nil {
+				Response(c, http.StatusBadRequest, err, nil)
+				return
+			}
+		} else if to > from {
+			step = (to-from)/DefaultPoolHistorySamples + 1
+		}
+		samples, err = rpc.History.SampleInterval(pool, from, to, step)
+	case PoolHistoryModeSync:
+		samples, err = rpc.History.SampleSyncs(pool, from, to)
+	default:
+		Response(c, http.StatusBadRequest, errors.New("bad mode"), nil)
+		return
+	}
+	if err != nil {
+		Response(c, http.StatusInternalServerError, err, nil)
+		return
+	}
+	token0, token1, err := rpc.History.PoolTokens(pool)
+	if err != nil {
+		Response(c, http.StatusInternalServerError, err, nil)
+		return
+	}
+
+	history := RpcPoolHistory{
+		Pool:         pool.Hex(),
+		Token0:       token0.Address.Hex(),
+		Token0Symbol: token0.Symbol,
+		Token1:       token1.Address.Hex(),
+		Token1Symbol: token1.Symbol,
+		Mode:         mode,
+		Samples:      []RpcPoolSample{},
+	}
+	for _, sample := range samples {
+		rpcSample := RpcPoolSample{
+			Height:    sample.Height,
+			Timestamp: sample.Timestamp,
+			Reserve0:  tools.FromDecimals(sample.Reserve0, token0.Decimals).FloatString(int(token0.Decimals)),
+			Reserve1:  tools.FromDecimals(sample.Reserve1, token1.Decimals).FloatString(int(token1.Decimals)),
+		}
+		if sample.TxHash != (common.Hash{}) {
+			rpcSample.TxHash = sample.TxHash.Hex()
+		}
+		if sample.Price0 != nil {
+			rpcSample.Price0 = sample.Price0.FloatString(18)
+			rpcSample.Price1 = sample.Price1.FloatString(18)
+		}
+		history.Samples = append(history.Samples, rpcSample)
+	}
+
+	if c.Query("format") == "csv" {
+		writePoolHistoryCsv(c, history)
+		return
+	}
+	Response(c, http.StatusOK, nil, history)
+	return
+}
+
+func writePoolHistoryCsv(c *gin.Context, history RpcPoolHistory) {
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.csv", history.Pool))
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write([]string{"height", "timestamp", "tx_hash",
+		"reserve0_" + history.Token0Symbol, "reserve1_" + history.Token1Symbol,
+		history.Token0Symbol + "_in_" + history.Token1Symbol, history.Token1Symbol + "_in_" + history.Token0Symbol})
+	for _, sample := range history.Samples {
+		_ = w.Write([]string{
+			fmt.Sprint(sample.Height), fmt.Sprint(sample.Timestamp), sample.TxHash,
+			sample.Reserve0, sample.Reserve1, sample.Price0, sample.Price1,
+		})
+	}
+	w.Flush()
+}
+
+func (rpc *RpcController) PoolV3(c *gin.Context) {
+	addressS := c.Param("address")
+	if !common.IsHexAddress(addressS) {
+		Response(c, http.StatusBadRequest, errors.New("bad address"), nil)
+		return
+	}
+	height, err := rpc.blockHeight(c)
+	if err != nil {
+		Response(c, http.StatusBadRequest, err, nil)
+		return
+	}
+	state, err := rpc.V3.PoolState(common.HexToAddress(addressS), height)
+	if err != nil {
+		Response(c, http.StatusInternalServerError, err, nil)
+		return
+	}
+
+	pool := RpcV3Pool{
+		Pool:         state.Address.Hex(),
+		Height:       state.Height,
+		Token0:       state.Token0.Hex(),
+		Token0Symbol: rpc.EthNode.Tokens.Get(state.Token0).Symbol,
+		Token1:       state.Token1.Hex(),
+		Token1Symbol: rpc.EthNode.Tokens.Get(state.Token1).Symbol,
+		Fee:          state.Fee,
+		TickSpacing:  state.TickSpacing,
+		Liquidity:    state.Liquidity.String(),
+		SqrtPriceX96: state.SqrtPriceX96.String(),
+		Tick:         state.Tick,
+	}
+	if state.Price0 != nil {
+		pool.Price0 = state.Price0.FloatString(18)
+		pool.Price1 = state.Price1.FloatString(18)
+	}
+
+	Response(c, http.StatusOK, nil, pool)
+	return
+}
+
+func (rpc *RpcController) BalancerPool(c *gin.Context, addressS string) {
+	if !common.IsHexAddress(addressS) {
+		Response(c, http.StatusBadRequest, errors.New("bad address"), nil)
+		return
+	}
+	height, err := rpc.blockHeight(c)
+	if err != nil {
+		Response(c, http.StatusBadRequest, err, nil)
+		return
+	}
+	state, err := rpc.Balancer.PoolState(common.HexToAddress(addressS), height)
+	if err != nil {
+		Response(c, http.StatusInternalServerError, err, nil)
+		return
+	}
+
+	pool := RpcBalancerPool{
+		Pool:       state.Address.Hex(),
+		Height:     state.Height,
+		SwapFee:    state.SwapFee.FloatString(6),
+		Tokens:     []RpcBalancerToken{},
+		SpotPrices: []RpcSpotPrice{},
+	}
+	for _, item := range state.Tokens {
+		rpcToken := RpcBalancerToken{
+			Token:        item.Token.Hex(),
+			Symbol:       item.Symbol,
+			Balance:      tools.FromDecimals(item.Balance, item.Decimals).FloatString(int(item.Decimals)),
+			DenormWeight: tools.FromWei(item.DenormWeight).FloatString(6),
+		}
+		if item.Weight != nil {
+			rpcToken.Weight = item.Weight.FloatString(6)
+		}
+		pool.Tokens = append(pool.Tokens, rpcToken)
+	}
+	for _, in := range state.Tokens {
+		for _, out := range state.Tokens {
+			if in.Token == out.Token {
+				continue
+			}
+			price := dex.BalancerSpotPrice(in, out, state.SwapFee)
+			if price == nil {
+				continue
+			}
+			pool.SpotPrices = append(pool.SpotPrices, RpcSpotPrice{
+				TokenIn:         in.Token.Hex(),
+				SymbolIn:        in.Symbol,
+				TokenOut:        out.Token.Hex(),
+				SymbolOut:       out.Symbol,
+				Price:           price.FloatString(18),
+				PriceWithoutFee: dex.BalancerSpotPrice(in, out, nil).FloatString(18),
+			})
+		}
+	}
+
+	Response(c, http.StatusOK, nil, pool)
+	return
+}
+
+// TokenPrice values a token in ETH and USD. The weth, stablecoin and eth_usd_pool query parameters override the configured oracles.
+func (rpc *RpcController) TokenPrice(c *gin.Context) {
+	tokenS := c.Param("token")
+	if !common.IsHexAddress(tokenS) {
+		Response(c, http.StatusBadRequest, errors.New("bad token"), nil)
+		return
+	}
+	height, err := rpc.blockHeight(c)
+	if err != nil {
+		Response(c, http.StatusBadRequest, err, nil)
+		return
+	}
+	oracles := rpc.Pricing.Oracles
+	for name, oracle := range map[string]*common.Address{
+		"weth":         &oracles.Weth,
+		"stablecoin":   &oracles.Stablecoin,
+		"eth_usd_pool": &oracles.EthUsdPool,
+	} {
+		if value := c.Query(name); value != "" {
+			if !common.IsHexAddress(value) {
+				Response(c, http.StatusBadRequest, errors.New("bad "+name), nil)
+				return
+			}
+			*oracle = common.HexToAddress(value)
+		}
+	}
+
+	token := common.HexToAddress(tokenS)
+	price, err := rpc.Pricing.QuoteWith(oracles, token, height)
+	if err == pricing.ErrNoRoute {
+		Response(c, http.StatusNotFound, err, nil)
+		return
+	}
+	if err != nil {
+		Response(c, http.StatusInternalServerError, err, nil)
+		return
+	}
+
+	rpcPrice := RpcTokenPrice{
+		Token:  token.Hex(),
+		Symbol: rpc.EthNode.Tokens.Get(token).Symbol,
+		Height: price.Height,
+		Eth:    price.Eth.FloatString(18),
+		Path:   []string{},
+	}
+	if price.Usd != nil {
+		rpcPrice.Usd = price.Usd.FloatString(6)
+	}
+	if price.Depth != nil {
+		rpcPrice.DepthEth = price.Depth.FloatString(6)
+	}
+	for _, pool := range price.Path {
+		rpcPrice.Path = append(rpcPrice.Path, pool.Hex())
+	}
+
+	Response(c, http.StatusOK, nil, rpcPrice)
+	return
+}
+
+// blockHeight reads the height query parameter, defaulting to the chain head
+func (rpc *RpcController) blockHeight(c *gin.Context) (height uint64, err error) {
+	if heightS := c.Query("height"); heightS != "" {
+		return tools.StrToNum(heightS)
+	}
+	return rpc.EthNode.RpcWrapper.BlockHeight(tools.GetContextDefault())
+}
+
+// blockRange reads the from and to query parameters.
+// to defaults to the chain head and from defaults to defaultSpan blocks before to.
+func (rpc *RpcController) blockRange(c *gin.Context, defaultSpan uint64) (from uint64, to uint64, err error) {
+	if toS := c.Query("to"); toS != "" {
+		to, err = tools.StrToNum(toS)
+	} else {
+		to, err = rpc.EthNode.RpcWrapper.BlockHeight(tools.GetContextDefault())
+	}
+	if err != nil {
+		return
+	}
+	if fromS := c.Query("from"); fromS != "" {
+		from, err = tools.StrToNum(fromS)
+	} else if to >= defaultSpan {
+		from = to - defaultSpan + 1
+	}
+	return
+}
+
+func isHash(s string) bool {
+	b, err := hexutil.Decode(s)
+	return err == nil && len(b) == common.HashLength
+}
+
+func (rpc *RpcController) toRpcCallFrame(frame *model.CallFrame) *RpcCallFrame {
+	rpcFrame := &RpcCallFrame{
+		Type:         frame.Type,
+		From:         frame.From.Hex(),
+		To:           frame.To.Hex(),
+		Value:        tools.FromWei(frame.Value).FloatString(8),
+		Gas:          frame.Gas,
+		GasUsed:      frame.GasUsed,
+		Input:        hexutil.Encode(frame.Input),
+		Output:       hexutil.Encode(frame.Output),
+		Method:       frame.Method,
+		Error:        frame.Error,
+		RevertReason: frame.RevertReason,
+		Depth:        frame.Depth,
+	}
+	for _, call := range frame.Calls {
+		rpcFrame.Calls = append(rpcFrame.Calls, rpc.toRpcCallFrame(call))
+	}
+	return rpcFrame
+}
+
+func (rpc *RpcController) toRpcAccountChanges(changes []*model.AccountChange) (rpcChanges []RpcAccountChange) {
+	for _, change := range changes {
+		delta := big.NewInt(0).Sub(change.BalanceAfter, change.BalanceBefore)
+		rpcChange := RpcAccountChange{
+			Address:        change.Address.Hex(),
+			BalanceBefore:  tools.FromWei(change.BalanceBefore).FloatString(8),
+			BalanceAfter:   tools.FromWei(change.BalanceAfter).FloatString(8),
+			BalanceDelta:   tools.FromWei(delta).FloatString(8),
+			NonceBefore:    change.NonceBefore,
+			NonceAfter:     change.NonceAfter,
+			CodeChanged:    change.CodeChanged,
+			StorageChanges: []RpcStorageChange{},
+			TokenChanges:   []RpcTokenChange{},
+		}
+		for _, storage := range change.StorageChanges {
+			rpcChange.StorageChanges = append(rpcChange.StorageChanges, RpcStorageChange{
+				Slot:   storage.Slot.Hex(),
+				Before: storage.Before.Hex(),
+				After:  storage.After.Hex(),
+			})
+		}
+		for _, token := range change.TokenChanges {
+			tokenDelta := big.NewInt(0).Sub(token.After, token.Before)
+			rpcChange.TokenChanges = append(rpcChange.TokenChanges, RpcTokenChange{
+				Token:  token.Token.Hex(),
+				Symbol: token.Symbol,
+				Before: tools.FromDecimals(token.Before, token.Decimals).FloatString(8),
+				After:  tools.FromDecimals(token.After, token.Decimals).FloatString(8),
+				Delta:  tools.FromDecimals(tokenDelta, token.Decimals).FloatString(8),
+			})
+		}
+		rpcChanges = append(rpcChanges, rpcChange)
+	}
+	return
+}
+
 func (rpc *RpcController) toRpcTxs(txs []model.Tx) (rpcTx []RpcTx) {
 	for i, tx := range txs {
 		var to string
@@ -129,6 +981,305 @@ func (rpc *RpcController) toRpcTxs(txs []model.Tx) (rpcTx []RpcTx) {
 			Value:      tools.FromWei(tx.BasicTx.Value()).FloatString(8),
 			DataLength: len(tx.BasicTx.Data()),
 			Rating:     tx.Rating,
+			Tags:       tx.Tags,
+			Mev:        tx.Mev,
+
+			RatingBreakdown: rpc.toRpcRatingScores(tx),
+
+			InternalTransfers: rpc.toRpcInternalTransfers(tx),
+			TokenTransfers:    rpc.toRpcTokenTransfers(tx),
+			NftTransfers:      rpc.toRpcNftTransfers(tx),
+			Swaps:             rpc.toRpcSwaps(tx),
+			LiquidityEvents:   rpc.toRpcLiquidityEvents(tx),
+		})
+		if tx.EthUsd != nil {
+			rpcTx[i].ValueUsd = big.NewRat(1, 1).Mul(tools.FromWei(tx.BasicTx.Value()), tx.EthUsd).FloatString(2)
+			rpcTx[i].GasCostUsd = big.NewRat(1, 1).Mul(tools.FromWei(tx.GasCost), tx.EthUsd).FloatString(2)
+		}
+		if tx.Failure != nil {
+			rpcTx[i].FailureKind = tx.Failure.Kind
+			rpcTx[i].FailureReason = tx.Failure.Reason
+		}
+	}
+	return
+}
+
+func (rpc *RpcController) toRpcTokenTransfers(tx model.Tx) (transfers []RpcTokenTransfer) {
+	for _, transfer := range tx.TokenTransfers {
+		rpcTransfer := RpcTokenTransfer{
+			Token:    transfer.Token.Hex(),
+			Symbol:   transfer.Symbol,
+			From:     transfer.From.Hex(),
+			To:       transfer.To.Hex(),
+			Amount:   tools.FromDecimals(transfer.Amount, transfer.Decimals).FloatString(int(transfer.Decimals)),
+			LogIndex: transfer.LogIndex,
+		}
+		if transfer.Usd != nil {
+			rpcTransfer.AmountUsd = transfer.Usd.FloatString(2)
+		}
+		transfers = append(transfers, rpcTransfer)
+	}
+	return
+}
+
+func (rpc *RpcController) toRpcNftTransfers(tx model.Tx) (transfers []RpcNftTransfer) {
+	for _, transfer := range tx.NftTransfers {
+		transfers = append(transfers, RpcNftTransfer{
+			Contract:   transfer.Contract.Hex(),
+			Standard:   transfer.Standard,
+			Collection: transfer.Collection,
+			From:       transfer.From.Hex(),
+			To:         transfer.To.Hex(),
+			TokenId:    transfer.TokenId.String(),
+			Amount:     transfer.Amount.String(),
+			LogIndex:   transfer.LogIndex,
+		})
+	}
+	return
+}
+
+func (rpc *RpcController) toRpcSwaps(tx model.Tx) (swaps []RpcSwap) {
+	for _, swap := range tx.Swaps {
+		rpcSwap := RpcSwap{
+			Protocol:  swap.Protocol,
+			Pool:      swap.Pool.Hex(),
+			Sender:    swap.Sender.Hex(),
+			Recipient: swap.Recipient.Hex(),
+			TokenIn:   swap.TokenIn.Hex(),
+			SymbolIn:  swap.SymbolIn,
+			AmountIn:  tools.FromDecimals(swap.AmountIn, swap.DecimalsIn).FloatString(int(swap.DecimalsIn)),
+			TokenOut:  swap.TokenOut.Hex(),
+			SymbolOut: swap.SymbolOut,
+			AmountOut: tools.FromDecimals(swap.AmountOut, swap.DecimalsOut).FloatString(int(swap.DecimalsOut)),
+			LogIndex:  swap.LogIndex,
+		}
+		if swap.PriceImpact != nil {
+			rpcSwap.PriceImpact = swap.PriceImpact.FloatString(6)
+		}
+		swaps = append(swaps, rpcSwap)
+	}
+	return
+}
+
+func (rpc *RpcController) toRpcLiquidityEvents(tx model.Tx) (events []RpcLiquidityEvent) {
+	for _, event := range tx.LiquidityEvents {
+		rpcEvent := RpcLiquidityEvent{
+			Kind:      event.Kind,
+			Pool:      event.Pool.Hex(),
+			Owner:     event.Owner.Hex(),
+			TickLower: event.TickLower,
+			TickUpper: event.TickUpper,
+			Amount0:   event.Amount0.String(),
+			Amount1:   event.Amount1.String(),
+			LogIndex:  event.LogIndex,
+		}
+		if event.Liquidity != nil {
+			rpcEvent.Liquidity = event.Liquidity.String()
+		}
+		events = append(events, rpcEvent)
+	}
+	return
+}
+
+func (rpc *RpcController) toRpcBlockMev(blockMev *model.BlockMev) RpcBlockMev {
+	rpcMev := RpcBlockMev{
+		Height:       blockMev.Height,
+		Sandwiches:   []RpcSandwich{},
+		Arbitrages:   []RpcArbitrage{},
+		Liquidations: []RpcLiquidation{},
+	}
+	for _, sandwich := range blockMev.Sandwiches {
+		rpcSandwich := RpcSandwich{
+			Pool:     sandwich.Pool.Hex(),
+			Protocol: sandwich.Protocol,
+			Attacker: sandwich.Attacker.Hex(),
+			FrontTx:  sandwich.FrontTx.Hex(),
+			BackTx:   sandwich.BackTx.Hex(),
+			Token:    sandwich.Token.Hex(),
+			Symbol:   sandwich.Symbol,
+			Profit:   tools.FromDecimals(sandwich.Profit, sandwich.Decimals).FloatString(int(sandwich.Decimals)),
+			GasCost:  tools.FromWei(sandwich.GasCost).FloatString(8),
+			Victims:  []RpcSandwichVictim{},
+		}
+		if sandwich.ProfitEth != nil {
+			rpcSandwich.ProfitEth = sandwich.ProfitEth.FloatString(8)
+		}
+		for _, victim := range sandwich.Victims {
+			rpcVictim := RpcSandwichVictim{
+				TxHash: victim.Tx.Hex(),
+				From:   victim.From.Hex(),
+				Token:  victim.Token.Hex(),
+				Symbol: victim.Symbol,
+			}
+			if victim.Loss != nil {
+				rpcVictim.Loss = tools.FromDecimals(victim.Loss, victim.Decimals).FloatString(int(victim.Decimals))
+			}
+			if victim.LossEth != nil {
+				rpcVictim.LossEth = victim.LossEth.FloatString(8)
+			}
+			rpcSandwich.Victims = append(rpcSandwich.Victims, rpcVictim)
+		}
+		rpcMev.Sandwiches = append(rpcMev.Sandwiches, rpcSandwich)
+	}
+	for _, arbitrage := range blockMev.Arbitrages {
+		rpcArbitrage := RpcArbitrage{
+			TxHash:       arbitrage.Tx.Hex(),
+			Searcher:     arbitrage.Searcher.Hex(),
+			Contract:     arbitrage.Contract.Hex(),
+			Token:        arbitrage.Token.Hex(),
+			Symbol:       arbitrage.Symbol,
+			Pools:        []string{},
+			Profit:       tools.FromDecimals(arbitrage.Profit, arbitrage.Decimals).FloatString(int(arbitrage.Decimals)),
+			ProfitEth:    ratString(arbitrage.ProfitEth, 8),
+			Cost:         toRpcSearcherCost(arbitrage.Cost),
+			NetProfitEth: ratString(arbitrage.NetProfitEth, 8),
+		}
+		for _, pool := range arbitrage.Pools {
+			rpcArbitrage.Pools = append(rpcArbitrage.Pools, pool.Hex())
+		}
+		rpcMev.Arbitrages = append(rpcMev.Arbitrages, rpcArbitrage)
+	}
+	for _, liquidation := range blockMev.Liquidations {
+		rpcMev.Liquidations = append(rpcMev.Liquidations, RpcLiquidation{
+			TxHash:           liquidation.Tx.Hex(),
+			Protocol:         liquidation.Protocol,
+			Liquidator:       liquidation.Liquidator.Hex(),
+			Borrower:         liquidation.Borrower.Hex(),
+			DebtToken:        liquidation.DebtToken.Hex(),
+			DebtSymbol:       liquidation.DebtSymbol,
+			DebtRepaid:       tools.FromDecimals(liquidation.DebtRepaid, liquidation.DebtDecimals).FloatString(int(liquidation.DebtDecimals)),
+			CollateralToken:  liquidation.CollateralToken.Hex(),
+			CollateralSymbol: liquidation.CollateralSymbol,
+			CollateralSeized: tools.FromDecimals(liquidation.CollateralSeized, liquidation.CollateralDecimals).FloatString(int(liquidation.CollateralDecimals)),
+			ProfitEth:        ratString(liquidation.ProfitEth, 8),
+			Cost:             toRpcSearcherCost(liquidation.Cost),
+			NetProfitEth:     ratString(liquidation.NetProfitEth, 8),
+			LogIndex:         liquidation.LogIndex,
+		})
+	}
+	return rpcMev
+}
+
+func toRpcWatch(w *model.Watch) RpcWatch {
+	return RpcWatch{
+		Address:   w.Address.Hex(),
+		Label:     w.Label,
+		CreatedAt: w.CreatedAt,
+	}
+}
+
+func toRpcHealth(h model.NodeHealth) RpcHealth {
+	rpcHealth := RpcHealth{
+		Status:        "ok",
+		Reachable:     h.Reachable,
+		Error:         h.Error,
+		ClientVersion: h.ClientVersion,
+		PeerCount:     h.PeerCount,
+		Syncing:       h.Syncing,
+		CurrentBlock:  h.CurrentBlock,
+		HighestBlock:  h.HighestBlock,
+		Head:          h.Head,
+		Archive:       h.Archive,
+		IndexedBlock:  h.IndexedBlock,
+		Reasons:       h.Reasons,
+		CheckedAt:     h.CheckedAt.Unix(),
+	}
+	if !h.HeadTime.IsZero() {
+		rpcHealth.HeadTimestamp = h.HeadTime.Unix()
+		rpcHealth.HeadAge = int64(h.HeadAge.Seconds())
+	}
+	switch {
+	case !h.Reachable:
+		rpcHealth.Status = "down"
+	case !h.Ready:
+		rpcHealth.Status = "degraded"
+	}
+	return rpcHealth
+}
+
+func toRpcIndexerStatus(status model.IndexerStatus) RpcIndexerStatus {
+	rpcStatus := RpcIndexerStatus{
+		From:            status.From,
+		To:              status.To,
+		Head:            status.Head,
+		Indexed:         status.Indexed,
+		Following:       status.Following,
+		Reorgs:          status.Reorgs,
+		BlocksPerSecond: status.BlocksPerSecond,
+		StartedAt:       status.StartedAt.Unix(),
+		LastError:       status.LastError,
+	}
+	if status.Head > status.Indexed {
+		rpcStatus.Lag = status.Head - status.Indexed
+	}
+	target := status.Head
+	if status.To != 0 && status.To < target {
+		target = status.To
+	}
+	switch {
+	case target < status.From:
+		// the head has not reached the range yet
+	case status.Indexed >= target:
+		rpcStatus.Progress = 1
+	case status.Indexed >= status.From:
+		rpcStatus.Progress = float64(status.Indexed-status.From+1) / float64(target-status.From+1)
+	}
+	return rpcStatus
+}
+
+func toRpcSearcherCost(cost model.SearcherCost) RpcSearcherCost {
+	return RpcSearcherCost{
+		GasCost:          tools.FromWei(cost.GasCost).FloatString(8),
+		CoinbaseTransfer: tools.FromWei(cost.CoinbaseTransfer).FloatString(8),
+		BuilderTip:       tools.FromWei(cost.BuilderTip).FloatString(8),
+	}
+}
+
+// ratString formats an optional value, empty when nil
+func ratString(value *big.Rat, prec int) string {
+	if value == nil {
+		return ""
+	}
+	return value.FloatString(prec)
+}
+
+func (rpc *RpcController) toRpcRatingScores(tx model.Tx) (scores []RpcRatingScore) {
+	for _, score := range tx.RatingBreakdown {
+		scores = append(scores, RpcRatingScore{
+			Name:   score.Name,
+			Weight: score.Weight,
+			Score:  score.Score,
+			Points: score.Weight * score.Score,
+		})
+	}
+	return
+}
+
+// filterRpcTxs keeps the transactions tagged with the given category or MEV role. Empty category keeps all.
+// rpcTxs are the conversions of txs, filtered after the conversion so they keep their position in the block.
+func filterRpcTxs(txs []model.Tx, rpcTxs []RpcTx, category string) (filtered []RpcTx) {
+	if category == "" {
+		return rpcTxs
+	}
+	filtered = []RpcTx{}
+	for i := range txs {
+		if txs[i].HasTag(category) || txs[i].HasMev(category) {
+			filtered = append(filtered, rpcTxs[i])
+		}
+	}
+	return
+}
+
+func (rpc *RpcController) toRpcInternalTransfers(tx model.Tx) (transfers []RpcInternalTransfer) {
+	for _, transfer := range tx.InternalTransfers {
+		transfers = append(transfers, RpcInternalTransfer{
+			Height: tx.Receipt.BlockNumber.Uint64(),
+			TxHash: tx.BasicTx.Hash().Hex(),
+			Type:   transfer.Type,
+			From:   transfer.From.Hex(),
+			To:     transfer.To.Hex(),
+			Value:  tools.FromWei(transfer.Value).FloatString(8),
+			Depth:  transfer.Depth,
 		})
 	}
 	return
@@ -13,4 +13,384 @@ type RpcTx struct {
 	Value      string `json:"value"`
 	DataLength int    `json:"data_length"`
 	Rating     uint64 `json:"rating"`
+
+	ValueUsd   string `json:"value_usd,omitempty"`
+	GasCostUsd string `json:"gas_cost_usd,omitempty"`
+
+	RatingBreakdown []RpcRatingScore `json:"rating_breakdown"`
+
+	Tags          []string `json:"tags"`
+	Mev           []string `json:"mev,omitempty"`
+	FailureKind   string   `json:"failure_kind,omitempty"`
+	FailureReason string   `json:"failure_reason,omitempty"`
+
+	InternalTransfers []RpcInternalTransfer `json:"internal_transfers,omitempty"`
+	TokenTransfers    []RpcTokenTransfer    `json:"token_transfers,omitempty"`
+	NftTransfers      []RpcNftTransfer      `json:"nft_transfers,omitempty"`
+	Swaps             []RpcSwap             `json:"swaps,omitempty"`
+	LiquidityEvents   []RpcLiquidityEvent   `json:"liquidity_events,omitempty"`
+}
+
+type RpcCallFrame struct {
+	Type         string          `json:"type"`
+	From         string          `json:"from"`
+	To           string          `json:"to"`
+	Value        string          `json:"value"`
+	Gas          uint64          `json:"gas"`
+	GasUsed      uint64          `json:"gas_used"`
+	Input        string          `json:"input"`
+	Output       string          `json:"output"`
+	Method       string          `json:"method"`
+	Error        string          `json:"error"`
+	RevertReason string          `json:"revert_reason"`
+	Depth        int             `json:"depth"`
+	Calls        []*RpcCallFrame `json:"calls,omitempty"`
+}
+
+type RpcStorageChange struct {
+	Slot   string `json:"slot"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+type RpcTokenChange struct {
+	Token  string `json:"token"`
+	Symbol string `json:"symbol"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+	Delta  string `json:"delta"`
+}
+
+type RpcAccountChange struct {
+	Address        string             `json:"address"`
+	BalanceBefore  string             `json:"balance_before"`
+	BalanceAfter   string             `json:"balance_after"`
+	BalanceDelta   string             `json:"balance_delta"`
+	NonceBefore    uint64             `json:"nonce_before"`
+	NonceAfter     uint64             `json:"nonce_after"`
+	CodeChanged    bool               `json:"code_changed"`
+	StorageChanges []RpcStorageChange `json:"storage_changes"`
+	TokenChanges   []RpcTokenChange   `json:"token_changes"`
+}
+
+type RpcInternalTransfer struct {
+	Height uint64 `json:"height"`
+	TxHash string `json:"tx_hash"`
+	Type   string `json:"type"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Value  string `json:"value"`
+	Depth  int    `json:"depth"`
+}
+
+type RpcAddressActivity struct {
+	Address           string                `json:"address"`
+	From              uint64                `json:"from"`
+	To                uint64                `json:"to"`
+	Txs               []RpcTx               `json:"txs"`
+	InternalTransfers []RpcInternalTransfer `json:"internal_transfers"`
+}
+
+type RpcRatingScore struct {
+	Name   string  `json:"name"`
+	Weight float64 `json:"weight"`
+	Score  float64 `json:"score"`
+	Points float64 `json:"points"`
+}
+
+type RpcTokenTransfer struct {
+	Token    string `json:"token"`
+	Symbol   string `json:"symbol"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Amount   string `json:"amount"`
+	LogIndex uint   `json:"log_index"`
+
+	AmountUsd string `json:"amount_usd,omitempty"`
+}
+
+type RpcNftTransfer struct {
+	Contract   string `json:"contract"`
+	Standard   string `json:"standard"`
+	Collection string `json:"collection"`
+	From       string `json:"from"`
+	To         string `json:"to"`
+	TokenId    string `json:"token_id"`
+	Amount     string `json:"amount"`
+	LogIndex   uint   `json:"log_index"`
+}
+
+type RpcNftHistoryItem struct {
+	Height   uint64 `json:"height"`
+	TxHash   string `json:"tx_hash"`
+	Operator string `json:"operator"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Amount   string `json:"amount"`
+}
+
+// RpcNftOwner holds the token at the end of the range. SinceHeight and TxHash are omitted when the owner
+// received the token before the range.
+type RpcNftOwner struct {
+	Owner       string `json:"owner"`
+	SinceHeight uint64 `json:"since_height,omitempty"`
+	TxHash      string `json:"tx_hash,omitempty"`
+	Amount      string `json:"amount"`
+}
+
+type RpcNftHistory struct {
+	Contract   string              `json:"contract"`
+	TokenId    string              `json:"token_id"`
+	Standard   string              `json:"standard"`
+	Collection string              `json:"collection"`
+	From       uint64              `json:"from"`
+	To         uint64              `json:"to"`
+	Owners     []RpcNftOwner       `json:"owners"`
+	Transfers  []RpcNftHistoryItem `json:"transfers"`
+}
+
+type RpcPair struct {
+	Address      string `json:"address"`
+	Factory      string `json:"factory"`
+	Token0       string `json:"token0"`
+	Token0Symbol string `json:"token0_symbol"`
+	Token1       string `json:"token1"`
+	Token1Symbol string `json:"token1_symbol"`
+}
+
+type RpcPoolSample struct {
+	Height    uint64 `json:"height"`
+	Timestamp uint64 `json:"timestamp"`
+	TxHash    string `json:"tx_hash,omitempty"`
+	Reserve0  string `json:"reserve0"`
+	Reserve1  string `json:"reserve1"`
+	Price0    string `json:"price0"`
+	Price1    string `json:"price1"`
+}
+
+type RpcPoolHistory struct {
+	Pool         string          `json:"pool"`
+	Token0       string          `json:"token0"`
+	Token0Symbol string          `json:"token0_symbol"`
+	Token1       string          `json:"token1"`
+	Token1Symbol string          `json:"token1_symbol"`
+	Mode         string          `json:"mode"`
+	Samples      []RpcPoolSample `json:"samples"`
+}
+
+type RpcSwap struct {
+	Protocol    string `json:"protocol"`
+	Pool        string `json:"pool"`
+	Sender      string `json:"sender"`
+	Recipient   string `json:"recipient"`
+	TokenIn     string `json:"token_in"`
+	SymbolIn    string `json:"symbol_in"`
+	AmountIn    string `json:"amount_in"`
+	TokenOut    string `json:"token_out"`
+	SymbolOut   string `json:"symbol_out"`
+	AmountOut   string `json:"amount_out"`
+	PriceImpact string `json:"price_impact,omitempty"`
+	LogIndex    uint   `json:"log_index"`
+}
+
+type RpcLiquidityEvent struct {
+	Kind      string `json:"kind"`
+	Pool      string `json:"pool"`
+	Owner     string `json:"owner"`
+	TickLower int64  `json:"tick_lower"`
+	TickUpper int64  `json:"tick_upper"`
+	Liquidity string `json:"liquidity,omitempty"`
+	Amount0   string `json:"amount0"`
+	Amount1   string `json:"amount1"`
+	LogIndex  uint   `json:"log_index"`
+}
+
+type RpcV3Pool struct {
+	Pool         string `json:"pool"`
+	Height       uint64 `json:"height"`
+	Token0       string `json:"token0"`
+	Token0Symbol string `json:"token0_symbol"`
+	Token1       string `json:"token1"`
+	Token1Symbol string `json:"token1_symbol"`
+	Fee          uint64 `json:"fee"`
+	TickSpacing  int64  `json:"tick_spacing"`
+	Liquidity    string `json:"liquidity"`
+	SqrtPriceX96 string `json:"sqrt_price_x96"`
+	Tick         int64  `json:"tick"`
+	Price0       string `json:"price0"`
+	Price1       string `json:"price1"`
+}
+
+type RpcBalancerToken struct {
+	Token        string `json:"token"`
+	Symbol       string `json:"symbol"`
+	Balance      string `json:"balance"`
+	DenormWeight string `json:"denorm_weight"`
+	Weight       string `json:"weight"`
+}
+
+type RpcSpotPrice struct {
+	TokenIn   string `json:"token_in"`
+	SymbolIn  string `json:"symbol_in"`
+	TokenOut  string `json:"token_out"`
+	SymbolOut string `json:"symbol_out"`
+	// Price is the amount of token_in paid for one token_out, fee included
+	Price           string `json:"price"`
+	PriceWithoutFee string `json:"price_without_fee"`
+}
+
+type RpcBalancerPool struct {
+	Pool       string             `json:"pool"`
+	Height     uint64             `json:"height"`
+	SwapFee    string             `json:"swap_fee"`
+	Tokens     []RpcBalancerToken `json:"tokens"`
+	SpotPrices []RpcSpotPrice     `json:"spot_prices"`
+}
+
+type RpcTokenPrice struct {
+	Token    string   `json:"token"`
+	Symbol   string   `json:"symbol"`
+	Height   uint64   `json:"height"`
+	Eth      string   `json:"eth"`
+	Usd      string   `json:"usd,omitempty"`
+	Path     []string `json:"path"`
+	DepthEth string   `json:"depth_eth,omitempty"`
+}
+
+type RpcSandwichVictim struct {
+	TxHash  string `json:"tx_hash"`
+	From    string `json:"from"`
+	Token   string `json:"token"`
+	Symbol  string `json:"symbol"`
+	Loss    string `json:"loss,omitempty"`
+	LossEth string `json:"loss_eth,omitempty"`
+}
+
+type RpcSandwich struct {
+	Pool      string              `json:"pool"`
+	Protocol  string              `json:"protocol"`
+	Attacker  string              `json:"attacker"`
+	FrontTx   string              `json:"front_tx"`
+	BackTx    string              `json:"back_tx"`
+	Token     string              `json:"token"`
+	Symbol    string              `json:"symbol"`
+	Profit    string              `json:"profit"`
+	ProfitEth string              `json:"profit_eth,omitempty"`
+	GasCost   string              `json:"gas_cost"`
+	Victims   []RpcSandwichVictim `json:"victims"`
+}
+
+type RpcBlockMev struct {
+	Height       uint64           `json:"height"`
+	Sandwiches   []RpcSandwich    `json:"sandwiches"`
+	Arbitrages   []RpcArbitrage   `json:"arbitrages"`
+	Liquidations []RpcLiquidation `json:"liquidations"`
+}
+
+type RpcSearcherCost struct {
+	GasCost          string `json:"gas_cost"`
+	CoinbaseTransfer string `json:"coinbase_transfer"`
+	BuilderTip       string `json:"builder_tip"`
+}
+
+type RpcArbitrage struct {
+	TxHash       string          `json:"tx_hash"`
+	Searcher     string          `json:"searcher"`
+	Contract     string          `json:"contract"`
+	Token        string          `json:"token"`
+	Symbol       string          `json:"symbol"`
+	Pools        []string        `json:"pools"`
+	Profit       string          `json:"profit"`
+	ProfitEth    string          `json:"profit_eth,omitempty"`
+	Cost         RpcSearcherCost `json:"cost"`
+	NetProfitEth string          `json:"net_profit_eth,omitempty"`
+}
+
+type RpcLiquidation struct {
+	TxHash           string          `json:"tx_hash"`
+	Protocol         string          `json:"protocol"`
+	Liquidator       string          `json:"liquidator"`
+	Borrower         string          `json:"borrower"`
+	DebtToken        string          `json:"debt_token"`
+	DebtSymbol       string          `json:"debt_symbol"`
+	DebtRepaid       string          `json:"debt_repaid"`
+	CollateralToken  string          `json:"collateral_token"`
+	CollateralSymbol string          `json:"collateral_symbol"`
+	CollateralSeized string          `json:"collateral_seized"`
+	ProfitEth        string          `json:"profit_eth,omitempty"`
+	Cost             RpcSearcherCost `json:"cost"`
+	NetProfitEth     string          `json:"net_profit_eth,omitempty"`
+	LogIndex         uint            `json:"log_index"`
+}
+
+type RpcEventLog struct {
+	Height   uint64   `json:"height"`
+	TxHash   string   `json:"tx_hash"`
+	TxIndex  uint     `json:"tx_index"`
+	LogIndex uint     `json:"log_index"`
+	Address  string   `json:"address"`
+	Topics   []string `json:"topics"`
+	Data     string   `json:"data"`
+	Event    string   `json:"event,omitempty"`
+}
+
+type RpcIndexerStatus struct {
+	From uint64 `json:"from"`
+	// To is zero when the indexer follows the head
+	To        uint64  `json:"to"`
+	Head      uint64  `json:"head"`
+	Indexed   uint64  `json:"indexed"`
+	Lag       uint64  `json:"lag"`
+	Progress  float64 `json:"progress"`
+	Following bool    `json:"following"`
+	Reorgs    uint64  `json:"reorgs"`
+	// BlocksPerSecond is the commit rate over the last minute
+	BlocksPerSecond float64 `json:"blocks_per_second"`
+	StartedAt       int64   `json:"started_at"`
+	LastError       string  `json:"last_error,omitempty"`
+}
+
+type RpcHealth struct {
+	Status        string `json:"status"`
+	Reachable     bool   `json:"reachable"`
+	Error         string `json:"error,omitempty"`
+	ClientVersion string `json:"client_version"`
+	PeerCount     uint64 `json:"peer_count"`
+	Syncing       bool   `json:"syncing"`
+	CurrentBlock  uint64 `json:"current_block,omitempty"`
+	HighestBlock  uint64 `json:"highest_block,omitempty"`
+	Head          uint64 `json:"head"`
+	HeadTimestamp int64  `json:"head_timestamp"`
+	// HeadAge is the number of seconds since the head block
+	HeadAge int64 `json:"head_age"`
+	// Archive is null when the node could not be probed
+	Archive *bool `json:"archive"`
+	// IndexedBlock is the last block committed by the indexer, omitted without one
+	IndexedBlock uint64   `json:"indexed_block,omitempty"`
+	Reasons      []string `json:"reasons,omitempty"`
+	CheckedAt    int64    `json:"checked_at"`
+}
+
+// RpcIndexRange is the body of a query outside the blocks held by the index, From and To are zero when it is empty
+type RpcIndexRange struct {
+	Error string `json:"error"`
+	From  uint64 `json:"from"`
+	To    uint64 `json:"to"`
+}
+
+// RpcError is the body of the requests rejected by the API guard
+type RpcError struct {
+	Error string `json:"error"`
+}
+
+type RpcWatch struct {
+	Address   string `json:"address"`
+	Label     string `json:"label"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+// RpcWatchRequest is the body of POST /watchlist
+type RpcWatchRequest struct {
+	Address string `json:"address"`
+	Label   string `json:"label"`
 }
@@ -0,0 +1,223 @@
+package rpc
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// ApiVersion is the version of the API documented in /openapi.json
+const ApiVersion = "1.0.0"
+
+// OpenApi serves the OpenAPI 3 document of the routes
+func (rpc *RpcController) OpenApi(c *gin.Context) {
+	Response(c, http.StatusOK, nil, rpc.openApi())
+}
+
+// openApi documents the routes. Schemas are generated from the response types by reflection, following their json tags.
+func (rpc *RpcController) openApi() map[string]interface{} {
+	schemas := &schemaSet{schemas: make(map[string]interface{})}
+	paths := make(map[string]map[string]interface{})
+	for _, r := range rpc.routes() {
+		if r.Hidden {
+			continue
+		}
+		path := openApiPath(r.Path)
+		if paths[path] == nil {
+			paths[path] = make(map[string]interface{})
+		}
+		paths[path][strings.ToLower(r.Method)] = rpc.openApiOperation(r, schemas)
+	}
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "etherxray",
+			"description": "Enriched Ethereum blocks, transactions, traces, pools, prices and MEV.",
+			"version":     ApiVersion,
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas.schemas,
+			"securitySchemes": map[string]interface{}{
+				"ApiKey": map[string]interface{}{"type": "apiKey", "in": "header", "name": "X-Api-Key"},
+				"Bearer": map[string]interface{}{"type": "http", "scheme": "bearer"},
+			},
+		},
+	}
+}
+
+func (rpc *RpcController) openApiOperation(r route, schemas *schemaSet) map[string]interface{} {
+	operation := map[string]interface{}{
+		"operationId": operationId(r.Method, r.Path),
+		"summary":     r.Summary,
+		"tags":        []string{strings.Split(strings.TrimPrefix(r.Path, "/"), "/")[0]},
+	}
+	parameters := []interface{}{}
+	for _, p := range r.Params {
+		parameters = append(parameters, openApiParam(p, "path", true))
+	}
+	for _, p := range r.Query {
+		parameters = append(parameters, openApiParam(p, "query", p.Required))
+	}
+	if len(parameters) > 0 {
+		operation["parameters"] = parameters
+	}
+	if r.Body != nil {
+		operation["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": schemas.of(reflect.TypeOf(r.Body))},
+			},
+		}
+	}
+	content := map[string]interface{}{
+		"application/json": map[string]interface{}{"schema": schemas.of(reflect.TypeOf(r.Response))},
+	}
+	if r.Csv {
+		content["text/csv"] = map[string]interface{}{"schema": map[string]interface{}{"type": "string"}}
+	}
+	responses := map[string]interface{}{
+		"200": map[string]interface{}{
+			"description": "OK",
+			"content":     content,
+		},
+		"default": map[string]interface{}{"description": "Error, the body is null"},
+	}
+	if r.Name != "" {
+		rejected := func(description string) map[string]interface{} {
+			return map[string]interface{}{
+				"description": description,
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": schemas.of(reflect.TypeOf(RpcError{}))},
+				},
+			}
+		}
+		responses["401"] = rejected("Missing or unknown API key")
+		responses["403"] = rejected("The API key lacks the " + r.Scope + " scope")
+		responses["429"] = rejected("Rate limited, retry after the Retry-After header")
+		security := []interface{}{
+			map[string]interface{}{"ApiKey": []string{}},
+			map[string]interface{}{"Bearer": []string{}},
+		}
+		if rpc.Guard == nil || rpc.Guard.AllowsAnonymous(r.Scope) {
+			// anonymous calls are allowed
+			security = append(security, map[string]interface{}{})
+		}
+		operation["security"] = security
+		operation["x-scope"] = r.Scope
+	}
+	operation["responses"] = responses
+	return operation
+}
+
+func openApiParam(p param, in string, required bool) map[string]interface{} {
+	return map[string]interface{}{
+		"name":        p.Name,
+		"in":          in,
+		"required":    required,
+		"description": p.Description,
+		"schema":      map[string]interface{}{"type": p.Type},
+	}
+}
+
+// openApiPath turns /block/:height into /block/{height}
+func openApiPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// operationId names the operation after the static segments of the path, GET /tx/:hash/trace is getTxTrace
+func operationId(method string, path string) string {
+	id := strings.ToLower(method)
+	for _, segment := range strings.Split(path, "/") {
+		if segment == "" || strings.HasPrefix(segment, ":") {
+			continue
+		}
+		for _, word := range strings.Split(segment, "_") {
+			runes := []rune(word)
+			runes[0] = unicode.ToUpper(runes[0])
+			id += string(runes)
+		}
+	}
+	return id
+}
+
+// schemaSet collects the schemas of the named types, referenced as #/components/schemas/<name>
+type schemaSet struct {
+	schemas map[string]interface{}
+}
+
+func (s *schemaSet) of(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Ptr:
+		// nullable is ignored next to a $ref, so the reference is wrapped
+		return map[string]interface{}{"allOf": []interface{}{s.of(t.Elem())}, "nullable": true}
+	case reflect.Struct:
+		if _, ok := s.schemas[t.Name()]; !ok {
+			// registered first so that recursive types terminate
+			s.schemas[t.Name()] = nil
+			s.schemas[t.Name()] = s.object(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + t.Name()}
+	case reflect.Slice, reflect.Array:
+		// nil slices are encoded as null
+		return map[string]interface{}{"type": "array", "items": s.of(t.Elem()), "nullable": true}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": s.of(t.Elem())}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	}
+	return map[string]interface{}{}
+}
+
+func (s *schemaSet) object(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	required := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := f.Name
+		omitEmpty := false
+		if tag, ok := f.Tag.Lookup("json"); ok {
+			parts := strings.Split(tag, ",")
+			if parts[0] == "-" {
+				continue
+			}
+			if parts[0] != "" {
+				name = parts[0]
+			}
+			for _, option := range parts[1:] {
+				omitEmpty = omitEmpty || option == "omitempty"
+			}
+		}
+		properties[name] = s.of(f.Type)
+		if !omitEmpty {
+			required = append(required, name)
+		}
+	}
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
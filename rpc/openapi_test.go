@@ -0,0 +1,167 @@
+package rpc
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// documented tells whether the document describes the gin route, where a path segment documented
+// as {name} matches any segment of the route, so the Hidden dispatchers are covered by their actions
+func documented(paths map[string]map[string]interface{}, method string, path string) bool {
+	segments := strings.Split(openApiPath(path), "/")
+	for docPath, operations := range paths {
+		if _, ok := operations[strings.ToLower(method)]; !ok {
+			continue
+		}
+		docSegments := strings.Split(docPath, "/")
+		if len(docSegments) != len(segments) {
+			continue
+		}
+		match := true
+		for i := range segments {
+			variable := strings.HasPrefix(segments[i], "{") || strings.HasPrefix(docSegments[i], "{")
+			if !variable && segments[i] != docSegments[i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+func TestOpenApiCoversRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rpc := &RpcController{}
+	paths := rpc.openApi()["paths"].(map[string]map[string]interface{})
+
+	for _, router := range []*gin.Engine{rpc.addRouter(gin.New()), rpc.addAdminRouter(gin.New())} {
+		for _, r := range router.Routes() {
+			if r.Path == "/openapi.json" {
+				// the document itself
+				continue
+			}
+			if !documented(paths, r.Method, r.Path) {
+				t.Errorf("%s %s is registered but not documented", r.Method, r.Path)
+			}
+		}
+	}
+}
+
+func TestOpenApiDocumentsServedRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rpc := &RpcController{}
+	served := make(map[string]bool)
+	for _, r := range rpc.addRouter(gin.New()).Routes() {
+		served[r.Method+" "+openApiPath(r.Path)] = true
+	}
+	for _, r := range rpc.routes() {
+		if r.Hidden || r.DocOnly {
+			continue
+		}
+		if !served[r.Method+" "+openApiPath(r.Path)] {
+			t.Errorf("%s %s is documented but not served", r.Method, r.Path)
+		}
+	}
+}
+
+func TestOpenApiOperation(t *testing.T) {
+	rpc := &RpcController{}
+	paths := rpc.openApi()["paths"].(map[string]map[string]interface{})
+
+	history, ok := paths["/pool/{address}/history"]["get"].(map[string]interface{})
+	if !ok {
+		t.Fatal("/pool/{address}/history is not documented")
+	}
+	if history["operationId"] != "getPoolHistory" {
+		t.Errorf("operation id %v, want getPoolHistory", history["operationId"])
+	}
+	if history["x-scope"] != "read" {
+		t.Errorf("scope %v, want read", history["x-scope"])
+	}
+	ok200 := history["responses"].(map[string]interface{})["200"].(map[string]interface{})
+	content := ok200["content"].(map[string]interface{})
+	for _, contentType := range []string{"application/json", "text/csv"} {
+		if _, ok := content[contentType]; !ok {
+			t.Errorf("history does not document %s", contentType)
+		}
+	}
+
+	health := paths["/health"]["get"].(map[string]interface{})
+	if _, ok := health["security"]; ok {
+		t.Error("the unguarded /health documents a security requirement")
+	}
+	content = health["responses"].(map[string]interface{})["200"].(map[string]interface{})["content"].(map[string]interface{})
+	if _, ok := content["text/csv"]; ok {
+		t.Error("/health documents text/csv")
+	}
+}
+
+func TestOpenApiPath(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		doc    string
+		id     string
+	}{
+		{http.MethodGet, "/block/:height", "/block/{height}", "getBlock"},
+		{http.MethodGet, "/tx/:hash/trace", "/tx/{hash}/trace", "getTxTrace"},
+		{http.MethodGet, "/status/indexer", "/status/indexer", "getStatusIndexer"},
+		{http.MethodDelete, "/watchlist/:address", "/watchlist/{address}", "deleteWatchlist"},
+		{http.MethodGet, "/mev/block/:height", "/mev/block/{height}", "getMevBlock"},
+	}
+	for _, test := range tests {
+		if doc := openApiPath(test.path); doc != test.doc {
+			t.Errorf("path of %s is %s, want %s", test.path, doc, test.doc)
+		}
+		if id := operationId(test.method, test.path); id != test.id {
+			t.Errorf("operation id of %s %s is %s, want %s", test.method, test.path, id, test.id)
+		}
+	}
+}
+
+type testInner struct {
+	Name string `json:"name"`
+}
+
+type testSchema struct {
+	Height     uint64      `json:"height"`
+	Optional   string      `json:"optional,omitempty"`
+	Skipped    string      `json:"-"`
+	Inner      *testInner  `json:"inner"`
+	Items      []testInner `json:"items"`
+	Self       *testSchema `json:"self,omitempty"`
+	unexported bool
+}
+
+func TestSchemas(t *testing.T) {
+	schemas := &schemaSet{schemas: make(map[string]interface{})}
+	ref := schemas.of(reflect.TypeOf(testSchema{}))
+	if ref["$ref"] != "#/components/schemas/testSchema" {
+		t.Fatalf("reference %v", ref)
+	}
+	schema := schemas.schemas["testSchema"].(map[string]interface{})
+	properties := schema["properties"].(map[string]interface{})
+	for _, name := range []string{"height", "optional", "inner", "items", "self"} {
+		if _, ok := properties[name]; !ok {
+			t.Errorf("property %s is missing", name)
+		}
+	}
+	for _, name := range []string{"Skipped", "unexported"} {
+		if _, ok := properties[name]; ok {
+			t.Errorf("property %s is documented", name)
+		}
+	}
+	required := strings.Join(schema["required"].([]string), ",")
+	if required != "height,inner,items" {
+		t.Errorf("required %s, want height,inner,items", required)
+	}
+	if _, ok := schemas.schemas["testInner"]; !ok {
+		t.Error("the nested type has no schema")
+	}
+}
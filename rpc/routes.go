@@ -0,0 +1,188 @@
+package rpc
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/latifrons/etherxray/auth"
+	"net/http"
+)
+
+// route describes one endpoint. The routes are both registered on the router and documented in /openapi.json.
+type route struct {
+	Method string
+	Path   string
+	// Name is the route the guard charges the cost of, empty for unguarded routes
+	Name    string
+	Scope   string
+	Summary string
+	Params  []param
+	Query   []param
+	// Body and Response are sample values of the request and response types
+	Body     interface{}
+	Response interface{}
+	// Csv routes also answer text/csv to format=csv
+	Csv     bool
+	Handler gin.HandlerFunc
+	// Admin routes are also served by the admin listener, without guard
+	Admin bool
+	// Hidden routes are registered but not documented, they dispatch to documented ones
+	Hidden bool
+	// DocOnly routes are documented but served by a Hidden route
+	DocOnly bool
+}
+
+type param struct {
+	Name        string
+	Type        string
+	Description string
+	Required    bool
+}
+
+var (
+	heightParam  = param{Name: "height", Type: "integer", Description: "block height"}
+	addressParam = param{Name: "address", Type: "string", Description: "0x address"}
+	hashParam    = param{Name: "hash", Type: "string", Description: "0x transaction hash"}
+
+	rangeQuery = []param{
+		{Name: "from", Type: "integer", Description: "first block, defaults to a route specific span before to"},
+		{Name: "to", Type: "integer", Description: "last block, defaults to the head"},
+	}
+	limitQuery        = param{Name: "limit", Type: "integer", Description: "maximum number of records, at most 1000"}
+	atHeightQuery     = param{Name: "height", Type: "integer", Description: "block height, defaults to the head"}
+	indexedRangeQuery = append(append([]param{}, rangeQuery...), limitQuery)
+)
+
+func (rpc *RpcController) routes() []route {
+	return []route{
+		{
+			Method: http.MethodGet, Path: "/health", Summary: "Reachability of the node", Admin: true,
+			Response: RpcHealth{}, Handler: rpc.Health,
+		},
+		{
+			Method: http.MethodGet, Path: "/ready", Summary: "Readiness of the node: reachable, synced and recent", Admin: true,
+			Response: RpcHealth{}, Handler: rpc.Ready,
+		},
+		{
+			Method: http.MethodGet, Path: "/block/:height", Name: "block", Scope: auth.ScopeRead,
+			Summary:  "Enriched transactions of a block",
+			Params:   []param{heightParam},
+			Query:    []param{{Name: "type", Type: "string", Description: "keep the transactions with this tag or MEV role"}},
+			Response: []RpcTx{}, Handler: rpc.Block,
+		},
+		{
+			Method: http.MethodGet, Path: "/tx/:hash/trace", Name: "trace", Scope: auth.ScopeTrace,
+			Summary: "Call tree of a transaction",
+			Params:  []param{hashParam}, Response: &RpcCallFrame{}, Handler: rpc.TxTrace,
+		},
+		{
+			Method: http.MethodGet, Path: "/tx/:hash/statediff", Name: "statediff", Scope: auth.ScopeTrace,
+			Summary: "Balance, nonce, storage and token changes of a transaction",
+			Params:  []param{hashParam}, Response: []RpcAccountChange{}, Handler: rpc.TxStateDiff,
+		},
+		{
+			Method: http.MethodGet, Path: "/address/:address", Name: "address", Scope: auth.ScopeRead,
+			Summary: "Transactions and internal transfers of an address, 409 with the indexed range when a wide range is not indexed",
+			Params:  []param{addressParam}, Query: indexedRangeQuery,
+			Response: RpcAddressActivity{}, Handler: rpc.Address,
+		},
+		{
+			Method: http.MethodGet, Path: "/nft/:contract/:id", Name: "nft", Scope: auth.ScopeRead,
+			Summary: "Transfers and owners of an NFT",
+			Params: []param{
+				{Name: "contract", Type: "string", Description: "0x address of the collection"},
+				{Name: "id", Type: "string", Description: "token id, decimal or 0x hex"},
+			},
+			Query: rangeQuery, Response: RpcNftHistory{}, Handler: rpc.NftHistory,
+		},
+		{
+			Method: http.MethodGet, Path: "/token/:address/pools", Name: "token_pools", Scope: auth.ScopeRead,
+			Summary: "Known pairs trading a token",
+			Params:  []param{addressParam}, Response: []RpcPair{}, Handler: rpc.TokenPools,
+		},
+		{
+			Method: http.MethodGet, Path: "/token/:address/txs", Name: "token_txs", Scope: auth.ScopeRead,
+			Summary: "Indexed transactions transferring a token",
+			Params:  []param{addressParam}, Query: indexedRangeQuery,
+			Response: []RpcTx{}, Handler: rpc.TokenTxs,
+		},
+		{
+			Method: http.MethodGet, Path: "/selector/:selector/txs", Name: "selector_txs", Scope: auth.ScopeRead,
+			Summary: "Indexed transactions calling a function selector",
+			Params:  []param{{Name: "selector", Type: "string", Description: "0x 4-byte selector"}},
+			Query:   indexedRangeQuery, Response: []RpcTx{}, Handler: rpc.SelectorTxs,
+		},
+		{
+			Method: http.MethodGet, Path: "/logs", Name: "logs", Scope: auth.ScopeRead,
+			Summary: "Indexed logs by emitter and/or topic0",
+			Query: append([]param{
+				{Name: "address", Type: "string", Description: "0x address of the emitter"},
+				{Name: "topic0", Type: "string", Description: "0x event topic, address or topic0 is required"},
+			}, indexedRangeQuery...),
+			Response: []RpcEventLog{}, Handler: rpc.Logs,
+		},
+		{
+			// /pool/balancer/:address shares its segments with /pool/:address/history, Pool guards each of them
+			Method: http.MethodGet, Path: "/pool/:address/:action", Scope: auth.ScopeRead,
+			Hidden: true, Handler: rpc.Pool,
+		},
+		{
+			Method: http.MethodGet, Path: "/pool/:address/history", Name: "pool_history", Scope: auth.ScopeRead,
+			Summary: "Reserves and prices of a Uniswap V2 pair over a block range",
+			Params:  []param{addressParam},
+			Query: append(append([]param{}, rangeQuery...),
+				param{Name: "mode", Type: "string", Description: "interval samples every step blocks, sync samples every Sync event"},
+				param{Name: "step", Type: "integer", Description: "blocks between samples in interval mode"},
+				param{Name: "format", Type: "string", Description: "csv answers text/csv instead of JSON"},
+			),
+			Response: RpcPoolHistory{}, Csv: true, DocOnly: true,
+		},
+		{
+			Method: http.MethodGet, Path: "/pool/:address/v3", Name: "pool", Scope: auth.ScopeRead,
+			Summary: "State of a Uniswap V3 pool",
+			Params:  []param{addressParam}, Query: []param{atHeightQuery},
+			Response: RpcV3Pool{}, DocOnly: true,
+		},
+		{
+			Method: http.MethodGet, Path: "/pool/balancer/:address", Name: "pool", Scope: auth.ScopeRead,
+			Summary: "Tokens, weights and spot prices of a Balancer pool",
+			Params:  []param{addressParam}, Query: []param{atHeightQuery},
+			Response: RpcBalancerPool{}, DocOnly: true,
+		},
+		{
+			Method: http.MethodGet, Path: "/price/:token", Name: "price", Scope: auth.ScopeRead,
+			Summary: "Price of a token in ETH and USD",
+			Params:  []param{{Name: "token", Type: "string", Description: "0x address of the token"}},
+			Query: []param{
+				atHeightQuery,
+				{Name: "weth", Type: "string", Description: "overrides the WETH oracle"},
+				{Name: "stablecoin", Type: "string", Description: "overrides the stablecoin oracle"},
+				{Name: "eth_usd_pool", Type: "string", Description: "overrides the ETH/USD pool"},
+			},
+			Response: RpcTokenPrice{}, Handler: rpc.TokenPrice,
+		},
+		{
+			Method: http.MethodGet, Path: "/mev/block/:height", Name: "mev", Scope: auth.ScopeRead,
+			Summary: "Sandwiches, arbitrages and liquidations of a block",
+			Params:  []param{heightParam}, Response: RpcBlockMev{}, Handler: rpc.BlockMev,
+		},
+		{
+			Method: http.MethodGet, Path: "/status/indexer", Name: "indexer", Scope: auth.ScopeRead, Admin: true,
+			Summary:  "Progress of the indexer",
+			Response: RpcIndexerStatus{}, Handler: rpc.IndexerStatus,
+		},
+		{
+			Method: http.MethodGet, Path: "/watchlist", Name: "watchlist", Scope: auth.ScopeRead, Admin: true,
+			Summary:  "Watched addresses",
+			Response: []RpcWatch{}, Handler: rpc.Watches,
+		},
+		{
+			Method: http.MethodPost, Path: "/watchlist", Name: "watchlist", Scope: auth.ScopeWatch, Admin: true,
+			Summary: "Watch an address",
+			Body:    RpcWatchRequest{}, Response: RpcWatch{}, Handler: rpc.AddWatch,
+		},
+		{
+			Method: http.MethodDelete, Path: "/watchlist/:address", Name: "watchlist", Scope: auth.ScopeWatch, Admin: true,
+			Summary: "Stop watching an address",
+			Params:  []param{addressParam}, Response: "ok", Handler: rpc.RemoveWatch,
+		},
+	}
+}
@@ -2,10 +2,13 @@ package rpc
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
-	"github.com/gin-gonic/gin"
+	"github.com/latifrons/etherxray/auth"
 	"github.com/sirupsen/logrus"
+	"net"
 	"net/http"
+	"os"
 	"time"
 )
 
@@ -14,22 +17,80 @@ const ShutdownTimeoutSeconds = 5
 type RpcServer struct {
 	C    *RpcController
 	Port string
+	// CertFile and KeyFile serve the port over HTTPS when set
+	CertFile       string
+	KeyFile        string
+	ReloadInterval time.Duration
+	// Socket is a Unix domain socket served alongside the port, in plain HTTP
+	Socket string
+	// AdminPort serves the admin routes on localhost when set
+	AdminPort string
 
-	router *gin.Engine
-	server *http.Server
+	servers []*http.Server
+	certs   *certReloader
 }
 
 func (srv *RpcServer) Start() {
-	srv.router = srv.C.NewRouter()
-	srv.server = &http.Server{
-		Addr:    ":" + srv.Port,
-		Handler: srv.router,
+	router := srv.C.NewRouter()
+
+	listener, err := net.Listen("tcp", ":"+srv.Port)
+	if err != nil {
+		logrus.WithError(err).Fatalf("failed to listen on port %s", srv.Port)
+	}
+	server := &http.Server{
+		Handler: router,
+	}
+	if srv.certs != nil {
+		server.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: srv.certs.GetCertificate,
+		}
+		srv.certs.Start()
+		logrus.Infof("listening Https on %s", srv.Port)
+	} else {
+		logrus.Infof("listening Http on %s", srv.Port)
+	}
+	srv.serve(server, listener)
+
+	if srv.Socket != "" {
+		// a socket left by an unclean stop would fail the listen
+		if err := os.Remove(srv.Socket); err != nil && !os.IsNotExist(err) {
+			logrus.WithError(err).Fatalf("failed to remove stale socket %s", srv.Socket)
+		}
+		listener, err := net.Listen("unix", srv.Socket)
+		if err != nil {
+			logrus.WithError(err).Fatalf("failed to listen on socket %s", srv.Socket)
+		}
+		logrus.Infof("listening Http on socket %s", srv.Socket)
+		// socket peers share no IP, anonymous requests are not limited per IP there
+		srv.serve(&http.Server{
+			Handler:     router,
+			ConnContext: auth.LocalConn,
+		}, listener)
 	}
 
-	logrus.Infof("listening Http on %s", srv.Port)
+	if srv.AdminPort != "" {
+		listener, err := net.Listen("tcp", "127.0.0.1:"+srv.AdminPort)
+		if err != nil {
+			logrus.WithError(err).Fatalf("failed to listen on admin port %s", srv.AdminPort)
+		}
+		logrus.Infof("listening admin Http on 127.0.0.1:%s", srv.AdminPort)
+		srv.serve(&http.Server{Handler: srv.C.NewAdminRouter()}, listener)
+	}
+}
+
+func (srv *RpcServer) serve(server *http.Server, listener net.Listener) {
+	srv.servers = append(srv.servers, server)
 	go func() {
 		// service connections
-		if err := srv.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if server.TLSConfig != nil {
+			// the certificate comes from GetCertificate
+			err = server.ServeTLS(listener, "", "")
+		} else {
+			err = server.Serve(listener)
+		}
+		if err != nil && err != http.ErrServerClosed {
 			logrus.WithError(err).Fatalf("error in Http server")
 		}
 	}()
@@ -38,8 +99,13 @@ func (srv *RpcServer) Start() {
 func (srv *RpcServer) Stop() {
 	ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeoutSeconds*time.Second)
 	defer cancel()
-	if err := srv.server.Shutdown(ctx); err != nil {
-		logrus.WithError(err).Error("error while shutting down the Http server")
+	for _, server := range srv.servers {
+		if err := server.Shutdown(ctx); err != nil {
+			logrus.WithError(err).Error("error while shutting down the Http server")
+		}
+	}
+	if srv.certs != nil {
+		srv.certs.Stop()
 	}
 	logrus.Infof("http server Stopped")
 }
@@ -49,5 +115,15 @@ func (srv *RpcServer) Name() string {
 }
 
 func (srv *RpcServer) InitDefault() {
-
+	if srv.CertFile == "" && srv.KeyFile == "" {
+		return
+	}
+	srv.certs = &certReloader{
+		CertFile: srv.CertFile,
+		KeyFile:  srv.KeyFile,
+		Interval: srv.ReloadInterval,
+	}
+	if err := srv.certs.InitDefault(); err != nil {
+		logrus.WithError(err).Fatal("failed to load the TLS certificate")
+	}
 }
@@ -0,0 +1,108 @@
+package rpc
+
+import (
+	"crypto/tls"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"os"
+	"sync"
+	"time"
+)
+
+// certReloader serves the certificate of the PEM files and loads them again when they change,
+// so renewed certificates are picked up without a restart
+type certReloader struct {
+	CertFile string
+	KeyFile  string
+	Interval time.Duration
+
+	quit    chan bool
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+func (r *certReloader) InitDefault() (err error) {
+	r.quit = make(chan bool)
+	if r.Interval == 0 {
+		r.Interval = time.Minute
+	}
+	_, err = r.reload()
+	return
+}
+
+func (r *certReloader) Start() {
+	go r.loop()
+}
+
+func (r *certReloader) Stop() {
+	close(r.quit)
+}
+
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.cert == nil {
+		return nil, errors.New("no certificate loaded")
+	}
+	return r.cert, nil
+}
+
+func (r *certReloader) loop() {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.quit:
+			return
+		case <-ticker.C:
+		}
+		reloaded, err := r.reload()
+		if err != nil {
+			// keep serving the previous certificate, the files may be half written
+			logrus.WithError(err).Warn("failed to reload the TLS certificate")
+			continue
+		}
+		if reloaded {
+			logrus.WithField("cert", r.CertFile).Info("TLS certificate reloaded")
+		}
+	}
+}
+
+// reload loads the files when either was modified since the last load
+func (r *certReloader) reload() (reloaded bool, err error) {
+	modTime, err := latestModTime(r.CertFile, r.KeyFile)
+	if err != nil {
+		return
+	}
+	r.mu.RLock()
+	unchanged := r.cert != nil && modTime.Equal(r.modTime)
+	r.mu.RUnlock()
+	if unchanged {
+		return
+	}
+	cert, err := tls.LoadX509KeyPair(r.CertFile, r.KeyFile)
+	if err != nil {
+		return
+	}
+	r.mu.Lock()
+	r.cert = &cert
+	r.modTime = modTime
+	r.mu.Unlock()
+	reloaded = true
+	return
+}
+
+func latestModTime(files ...string) (latest time.Time, err error) {
+	for _, file := range files {
+		info, er := os.Stat(file)
+		if er != nil {
+			err = er
+			return
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return
+}
@@ -0,0 +1,489 @@
+package store
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/latifrons/etherxray/model"
+	"github.com/sirupsen/logrus"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+	"sort"
+)
+
+// Key prefixes. Every prefix is a table of the index.
+var (
+	// block/<height> -> model.Block
+	prefixBlock = []byte("block/")
+	// tx/<hash> -> model.Tx
+	prefixTx = []byte("tx/")
+	// addr/<address><height><tx index> -> tx hash
+	prefixAddress = []byte("addr/")
+	// sel/<selector><height><tx index> -> tx hash
+	prefixSelector = []byte("sel/")
+	// token/<token><height><tx index> -> tx hash
+	prefixToken = []byte("token/")
+	// log/<address><height><log index> -> model.EventLog
+	prefixLog = []byte("log/")
+	// topic/<topic0><height><log index> -> log key
+	prefixTopic = []byte("topic/")
+	// keys/<height> -> index keys written for the block, used to delete it
+	prefixKeys = []byte("keys/")
+	// meta/checkpoint/<name> -> model.Checkpoint
+	prefixCheckpoint = []byte("meta/checkpoint/")
+)
+
+// Tables lists the key prefixes of the index
+var Tables = map[string][]byte{
+	"block":    prefixBlock,
+	"tx":       prefixTx,
+	"address":  prefixAddress,
+	"selector": prefixSelector,
+	"token":    prefixToken,
+	"log":      prefixLog,
+	"topic":    prefixTopic,
+	"keys":     prefixKeys,
+	"meta":     prefixCheckpoint,
+}
+
+// LevelRepository is a Repository in an embedded LevelDB database
+type LevelRepository struct {
+	Path string
+
+	db *leveldb.DB
+}
+
+func (r *LevelRepository) InitDefault() {
+	db, err := leveldb.OpenFile(r.Path, nil)
+	if err != nil {
+		logrus.WithError(err).WithField("path", r.Path).Fatal("failed to open index")
+	}
+	r.db = db
+}
+
+// Start does nothing, the database is opened by InitDefault so other components can use it right away
+func (r *LevelRepository) Start() {
+}
+
+func (r *LevelRepository) Stop() {
+	if err := r.Close(); err != nil {
+		logrus.WithError(err).Warn("failed to close index")
+	}
+}
+
+func (r *LevelRepository) Name() string {
+	return "LevelRepository"
+}
+
+func (r *LevelRepository) Close() error {
+	return r.db.Close()
+}
+
+func (r *LevelRepository) SaveBlock(block *model.Block, txs []model.Tx, logs []model.EventLog) error {
+	if err := r.DeleteBlock(block.Height); err != nil {
+		return err
+	}
+	batch := new(leveldb.Batch)
+	var indexKeys [][]byte
+	put := func(key []byte, value []byte) {
+		batch.Put(key, value)
+		indexKeys = append(indexKeys, key)
+	}
+
+	content, err := json.Marshal(block)
+	if err != nil {
+		return err
+	}
+	put(key(prefixBlock, uint64Bytes(block.Height)), content)
+
+	for i, tx := range txs {
+		hash := tx.BasicTx.Hash()
+		content, err = json.Marshal(tx)
+		if err != nil {
+			return err
+		}
+		put(key(prefixTx, hash.Bytes()), content)
+
+		suffix := append(uint64Bytes(block.Height), uint32Bytes(uint32(i))...)
+		for address := range txAddresses(tx) {
+			put(key(prefixAddress, address.Bytes(), suffix), hash.Bytes())
+		}
+		if data := tx.BasicTx.Data(); len(data) >= 4 {
+			put(key(prefixSelector, data[:4], suffix), hash.Bytes())
+		}
+		for token := range txTokens(tx) {
+			put(key(prefixToken, token.Bytes(), suffix), hash.Bytes())
+		}
+	}
+
+	for _, log := range logs {
+		content, err = json.Marshal(log)
+		if err != nil {
+			return err
+		}
+		suffix := append(uint64Bytes(block.Height), uint32Bytes(uint32(log.LogIndex))...)
+		logKey := key(prefixLog, log.Address.Bytes(), suffix)
+		put(logKey, content)
+		if len(log.Topics) > 0 {
+			put(key(prefixTopic, log.Topics[0].Bytes(), suffix), logKey)
+		}
+	}
+
+	content, err = json.Marshal(indexKeys)
+	if err != nil {
+		return err
+	}
+	batch.Put(key(prefixKeys, uint64Bytes(block.Height)), content)
+	return r.db.Write(batch, nil)
+}
+
+func (r *LevelRepository) DeleteBlock(height uint64) error {
+	keysKey := key(prefixKeys, uint64Bytes(height))
+	content, err := r.db.Get(keysKey, nil)
+	if err == leveldb.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var indexKeys [][]byte
+	if err = json.Unmarshal(content, &indexKeys); err != nil {
+		return err
+	}
+	batch := new(leveldb.Batch)
+	for _, indexKey := range indexKeys {
+		batch.Delete(indexKey)
+	}
+	batch.Delete(keysKey)
+	return r.db.Write(batch, nil)
+}
+
+func (r *LevelRepository) GetBlock(height uint64) (block *model.Block, txs []model.Tx, err error) {
+	block = &model.Block{}
+	if err = r.get(key(prefixBlock, uint64Bytes(height)), block); err != nil {
+		return
+	}
+	for _, hash := range block.TxHashes {
+		var tx *model.Tx
+		tx, err = r.GetTx(hash)
+		if err != nil {
+			return
+		}
+		txs = append(txs, *tx)
+	}
+	return
+}
+
+func (r *LevelRepository) GetHeader(height uint64) (block *model.Block, err error) {
+	block = &model.Block{}
+	err = r.get(key(prefixBlock, uint64Bytes(height)), block)
+	return
+}
+
+func (r *LevelRepository) GetTx(hash common.Hash) (tx *model.Tx, err error) {
+	tx = &model.Tx{}
+	err = r.get(key(prefixTx, hash.Bytes()), tx)
+	return
+}
+
+func (r *LevelRepository) TxsByAddress(address common.Address, from uint64, to uint64, limit int) ([]model.Tx, error) {
+	return r.txsByIndex(prefixAddress, address.Bytes(), from, to, limit)
+}
+
+func (r *LevelRepository) TxsBySelector(selector [4]byte, from uint64, to uint64, limit int) ([]model.Tx, error) {
+	return r.txsByIndex(prefixSelector, selector[:], from, to, limit)
+}
+
+func (r *LevelRepository) TxsByToken(token common.Address, from uint64, to uint64, limit int) ([]model.Tx, error) {
+	return r.txsByIndex(prefixToken, token.Bytes(), from, to, limit)
+}
+
+func (r *LevelRepository) Logs(filter model.LogFilter) (logs []model.EventLog, err error) {
+	var prefix, value []byte
+	byTopic := filter.Topic0 != (common.Hash{})
+	switch {
+	case byTopic:
+		prefix, value = prefixTopic, filter.Topic0.Bytes()
+	case filter.Address != (common.Address{}):
+		prefix, value = prefixLog, filter.Address.Bytes()
+	default:
+		err = errFilterRequired
+		return
+	}
+	iter := r.db.NewIterator(heightRange(prefix, value, filter.From, filter.To), nil)
+	defer iter.Release()
+	for iter.Next() {
+		if filter.Limit > 0 && len(logs) >= filter.Limit {
+			break
+		}
+		logKey := iter.Key()
+		if byTopic {
+			logKey = iter.Value()
+		}
+		var log model.EventLog
+		if err = r.get(logKey, &log); err != nil {
+			return
+		}
+		if filter.Address != (common.Address{}) && log.Address != filter.Address {
+			continue
+		}
+		logs = append(logs, log)
+	}
+	err = iter.Error()
+	return
+}
+
+func (r *LevelRepository) GetCheckpoint(name string) (checkpoint *model.Checkpoint, err error) {
+	checkpoint = &model.Checkpoint{}
+	err = r.get(key(prefixCheckpoint, []byte(name)), checkpoint)
+	return
+}
+
+func (r *LevelRepository) SaveCheckpoint(name string, checkpoint *model.Checkpoint) error {
+	content, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	return r.db.Put(key(prefixCheckpoint, []byte(name)), content, nil)
+}
+
+func (r *LevelRepository) HeightRange() (first uint64, last uint64, err error) {
+	iter := r.db.NewIterator(util.BytesPrefix(prefixBlock), nil)
+	defer iter.Release()
+	if !iter.First() {
+		err = iter.Error()
+		if err == nil {
+			err = ErrNotFound
+		}
+		return
+	}
+	first = binary.BigEndian.Uint64(iter.Key()[len(prefixBlock):])
+	iter.Last()
+	last = binary.BigEndian.Uint64(iter.Key()[len(prefixBlock):])
+	return
+}
+
+func (r *LevelRepository) PruneBlock(height uint64, pinned map[common.Address]bool) (err error) {
+	blockKey := key(prefixBlock, uint64Bytes(height))
+	block := &model.Block{}
+	if err = r.get(blockKey, block); err != nil || block.Pruned {
+		return
+	}
+	keysKey := key(prefixKeys, uint64Bytes(height))
+	var indexKeys [][]byte
+	if err = r.get(keysKey, &indexKeys); err != nil {
+		return
+	}
+
+	batch := new(leveldb.Batch)
+	prunedTxs := make(map[common.Hash]bool)
+	for _, hash := range block.TxHashes {
+		var tx *model.Tx
+		tx, err = r.GetTx(hash)
+		if err != nil {
+			return
+		}
+		if isPinned(*tx, pinned) {
+			continue
+		}
+		prunedTxs[hash] = true
+		if tx.Receipt != nil {
+			// logs is a required field of a receipt, an empty list is kept
+			tx.Receipt.Logs = []*types.Log{}
+			tx.Receipt.Bloom = types.Bloom{}
+		}
+		var content []byte
+		content, err = json.Marshal(tx)
+		if err != nil {
+			return
+		}
+		batch.Put(key(prefixTx, hash.Bytes()), content)
+	}
+
+	// raw logs of the pruned transactions, then the topic entries pointing to them
+	deleted := make(map[string]bool)
+	for _, indexKey := range indexKeys {
+		if !bytes.HasPrefix(indexKey, prefixLog) {
+			continue
+		}
+		var log model.EventLog
+		if err = r.get(indexKey, &log); err != nil {
+			return
+		}
+		if prunedTxs[log.TxHash] {
+			deleted[string(indexKey)] = true
+		}
+	}
+	var kept [][]byte
+	for _, indexKey := range indexKeys {
+		if bytes.HasPrefix(indexKey, prefixTopic) {
+			var logKey []byte
+			logKey, err = r.db.Get(indexKey, nil)
+			if err != nil {
+				return
+			}
+			if deleted[string(logKey)] {
+				deleted[string(indexKey)] = true
+			}
+		}
+		if deleted[string(indexKey)] {
+			batch.Delete(indexKey)
+			continue
+		}
+		kept = append(kept, indexKey)
+	}
+
+	block.Pruned = true
+	content, err := json.Marshal(block)
+	if err != nil {
+		return
+	}
+	batch.Put(blockKey, content)
+	content, err = json.Marshal(kept)
+	if err != nil {
+		return
+	}
+	batch.Put(keysKey, content)
+	return r.db.Write(batch, nil)
+}
+
+func (r *LevelRepository) Stats() (stats []model.TableStats, err error) {
+	for name, prefix := range Tables {
+		table := model.TableStats{Name: name}
+		keyRange := util.BytesPrefix(prefix)
+		iter := r.db.NewIterator(keyRange, nil)
+		for iter.Next() {
+			table.Keys++
+			table.Bytes += uint64(len(iter.Key()) + len(iter.Value()))
+		}
+		iter.Release()
+		if err = iter.Error(); err != nil {
+			return
+		}
+		var sizes leveldb.Sizes
+		sizes, err = r.db.SizeOf([]util.Range{*keyRange})
+		if err != nil {
+			return
+		}
+		table.DiskBytes = uint64(sizes.Sum())
+		stats = append(stats, table)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Name < stats[j].Name
+	})
+	return
+}
+
+func (r *LevelRepository) Compact() error {
+	return r.db.CompactRange(util.Range{})
+}
+
+func (r *LevelRepository) txsByIndex(prefix []byte, value []byte, from uint64, to uint64, limit int) (txs []model.Tx, err error) {
+	iter := r.db.NewIterator(heightRange(prefix, value, from, to), nil)
+	defer iter.Release()
+	for iter.Next() {
+		if limit > 0 && len(txs) >= limit {
+			break
+		}
+		var tx *model.Tx
+		tx, err = r.GetTx(common.BytesToHash(iter.Value()))
+		if err != nil {
+			return
+		}
+		txs = append(txs, *tx)
+	}
+	err = iter.Error()
+	return
+}
+
+func (r *LevelRepository) get(key []byte, v interface{}) error {
+	content, err := r.db.Get(key, nil)
+	if err == leveldb.ErrNotFound {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(content, v)
+}
+
+// txAddresses collects every address sending, receiving or transferring value or tokens in the transaction
+func txAddresses(tx model.Tx) map[common.Address]bool {
+	addresses := map[common.Address]bool{tx.From: true}
+	if tx.BasicTx.To() != nil {
+		addresses[*tx.BasicTx.To()] = true
+	}
+	if tx.Receipt.ContractAddress != (common.Address{}) {
+		addresses[tx.Receipt.ContractAddress] = true
+	}
+	for _, transfer := range tx.InternalTransfers {
+		addresses[transfer.From] = true
+		addresses[transfer.To] = true
+	}
+	for _, transfer := range tx.TokenTransfers {
+		addresses[transfer.From] = true
+		addresses[transfer.To] = true
+	}
+	for _, transfer := range tx.NftTransfers {
+		addresses[transfer.From] = true
+		addresses[transfer.To] = true
+	}
+	return addresses
+}
+
+func isPinned(tx model.Tx, pinned map[common.Address]bool) bool {
+	if len(pinned) == 0 {
+		return false
+	}
+	for address := range txAddresses(tx) {
+		if pinned[address] {
+			return true
+		}
+	}
+	return false
+}
+
+func txTokens(tx model.Tx) map[common.Address]bool {
+	tokens := make(map[common.Address]bool)
+	for _, transfer := range tx.TokenTransfers {
+		tokens[transfer.Token] = true
+	}
+	for _, transfer := range tx.NftTransfers {
+		tokens[transfer.Contract] = true
+	}
+	return tokens
+}
+
+// heightRange covers the keys prefix/value/<height> for heights in [from, to]
+func heightRange(prefix []byte, value []byte, from uint64, to uint64) *util.Range {
+	limit := key(prefix, value, uint64Bytes(to+1))
+	if to == ^uint64(0) {
+		limit = util.BytesPrefix(key(prefix, value)).Limit
+	}
+	return &util.Range{
+		Start: key(prefix, value, uint64Bytes(from)),
+		Limit: limit,
+	}
+}
+
+func key(parts ...[]byte) []byte {
+	var k []byte
+	for _, part := range parts {
+		k = append(k, part...)
+	}
+	return k
+}
+
+func uint64Bytes(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return b
+}
+
+func uint32Bytes(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	return b
+}
@@ -0,0 +1,42 @@
+package store
+
+import (
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/latifrons/etherxray/model"
+)
+
+var (
+	ErrNotFound       = errors.New("not found in index")
+	errFilterRequired = errors.New("an address or a topic is required")
+)
+
+// Repository persists enriched blocks and answers queries from the local index
+type Repository interface {
+	// SaveBlock stores an enriched block, replacing the block previously stored at its height
+	SaveBlock(block *model.Block, txs []model.Tx, logs []model.EventLog) error
+	// DeleteBlock removes the block at the height and every index entry pointing to it
+	DeleteBlock(height uint64) error
+	GetBlock(height uint64) (*model.Block, []model.Tx, error)
+	// GetHeader returns the stored block summary without its transactions
+	GetHeader(height uint64) (*model.Block, error)
+	GetTx(hash common.Hash) (*model.Tx, error)
+	// TxsByAddress returns the transactions in [from, to] the address sends, receives or transfers value or tokens in
+	TxsByAddress(address common.Address, from uint64, to uint64, limit int) ([]model.Tx, error)
+	// TxsBySelector returns the transactions in [from, to] calling the method selector at top level
+	TxsBySelector(selector [4]byte, from uint64, to uint64, limit int) ([]model.Tx, error)
+	// TxsByToken returns the transactions in [from, to] moving the ERC-20 token or NFT collection
+	TxsByToken(token common.Address, from uint64, to uint64, limit int) ([]model.Tx, error)
+	Logs(filter model.LogFilter) ([]model.EventLog, error)
+	// GetCheckpoint returns the last block committed by the named indexer, ErrNotFound before the first commit
+	GetCheckpoint(name string) (*model.Checkpoint, error)
+	SaveCheckpoint(name string, checkpoint *model.Checkpoint) error
+	// HeightRange returns the lowest and highest stored block heights, ErrNotFound when the index is empty
+	HeightRange() (first uint64, last uint64, err error)
+	// PruneBlock drops the raw logs of the block except for transactions involving a pinned address
+	PruneBlock(height uint64, pinned map[common.Address]bool) error
+	Stats() ([]model.TableStats, error)
+	// Compact reclaims the space of deleted and overwritten entries
+	Compact() error
+	Close() error
+}
@@ -0,0 +1,129 @@
+package store
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/latifrons/etherxray/model"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+// prunerCheckpoint names the checkpoint of the last pruned block
+const prunerCheckpoint = "pruner"
+
+// saveEveryBlocks is how often the pruner checkpoint is saved during a pass
+const saveEveryBlocks = 100
+
+// Pruner applies the retention policy: the latest KeepLogBlocks blocks keep their raw logs, older blocks keep
+// the decoded transactions, transfers and swaps but lose the raw logs and their /logs index entries.
+// Transactions involving a pinned address are never pruned. The database is compacted after each pass that pruned blocks.
+type Pruner struct {
+	Repository    Repository
+	KeepLogBlocks uint64
+	// Pinned returns the addresses whose transactions are never pruned, nil pins none
+	Pinned   func() []common.Address
+	Interval time.Duration
+
+	quit chan bool
+	done chan bool
+}
+
+func (p *Pruner) InitDefault() {
+	p.quit = make(chan bool)
+	p.done = make(chan bool)
+	if p.Interval == 0 {
+		p.Interval = time.Hour
+	}
+}
+
+func (p *Pruner) Start() {
+	go p.loop()
+}
+
+// Stop waits for the block being pruned and the compaction in progress
+func (p *Pruner) Stop() {
+	close(p.quit)
+	<-p.done
+}
+
+func (p *Pruner) Name() string {
+	return "Pruner"
+}
+
+func (p *Pruner) loop() {
+	defer close(p.done)
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+	for {
+		pruned, err := p.Prune()
+		if err != nil {
+			logrus.WithError(err).Warn("pruner")
+		}
+		if pruned > 0 {
+			logrus.WithField("blocks", pruned).Info("pruned index")
+			if err = p.Repository.Compact(); err != nil {
+				logrus.WithError(err).Warn("failed to compact index")
+			}
+		}
+		select {
+		case <-p.quit:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Prune prunes the blocks older than the retention window since the last pass
+func (p *Pruner) Prune() (pruned uint64, err error) {
+	first, last, err := p.Repository.HeightRange()
+	if err == ErrNotFound {
+		err = nil
+		return
+	}
+	if err != nil || last < p.KeepLogBlocks {
+		return
+	}
+	until := last - p.KeepLogBlocks
+	from := first
+	checkpoint, err := p.Repository.GetCheckpoint(prunerCheckpoint)
+	switch {
+	case err == nil && checkpoint.Height+1 > from:
+		from = checkpoint.Height + 1
+	case err == ErrNotFound:
+		err = nil
+	case err != nil:
+		return
+	}
+
+	pinned := make(map[common.Address]bool)
+	if p.Pinned != nil {
+		for _, address := range p.Pinned() {
+			pinned[address] = true
+		}
+	}
+	for height := from; height <= until; height++ {
+		select {
+		case <-p.quit:
+			return
+		default:
+		}
+		err = p.Repository.PruneBlock(height, pinned)
+		if err == ErrNotFound {
+			// gap in the index
+			err = nil
+			continue
+		}
+		if err != nil {
+			return
+		}
+		pruned++
+		if pruned%saveEveryBlocks == 0 {
+			if err = p.Repository.SaveCheckpoint(prunerCheckpoint, &model.Checkpoint{Height: height}); err != nil {
+				return
+			}
+		}
+	}
+	if from <= until {
+		err = p.Repository.SaveCheckpoint(prunerCheckpoint, &model.Checkpoint{Height: until})
+	}
+	return
+}
@@ -0,0 +1,216 @@
+package token
+
+import (
+	"encoding/json"
+	"github.com/annchain/commongo/files"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/latifrons/etherxray/metrics"
+	"github.com/latifrons/etherxray/middleware"
+	"github.com/latifrons/etherxray/model"
+	"github.com/latifrons/etherxray/tools"
+	"github.com/sirupsen/logrus"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// SaveInterval throttles how often new metadata is written to disk
+	SaveInterval = time.Second * 10
+	// RetryInterval is how long a failed lookup is served from memory before it is read again
+	RetryInterval = time.Minute * 10
+)
+
+// Cache reads token metadata from the chain once and keeps it on disk
+type Cache struct {
+	RpcWrapper *middleware.RpcWrapper
+	File       string
+
+	mu     sync.RWMutex
+	saveMu sync.Mutex
+	tokens map[common.Address]*model.TokenInfo
+	// failed holds lookups that did not answer, they are retried and never written to disk
+	failed   map[common.Address]failedLookup
+	dirty    bool
+	lastSave time.Time
+}
+
+type failedLookup struct {
+	info *model.TokenInfo
+	at   time.Time
+}
+
+func (c *Cache) InitDefault() {
+	c.tokens = make(map[common.Address]*model.TokenInfo)
+	c.failed = make(map[common.Address]failedLookup)
+	if c.File == "" || !files.FileExists(c.File) {
+		return
+	}
+	content, err := ioutil.ReadFile(c.File)
+	if err != nil {
+		logrus.WithError(err).WithField("file", c.File).Warn("failed to read token cache")
+		return
+	}
+	var infos []*model.TokenInfo
+	err = json.Unmarshal(content, &infos)
+	if err != nil {
+		logrus.WithError(err).WithField("file", c.File).Warn("failed to parse token cache")
+		return
+	}
+	for _, info := range infos {
+		c.tokens[info.Address] = info
+	}
+}
+
+func (c *Cache) Start() {
+}
+
+// Stop writes the metadata read since the last save
+func (c *Cache) Stop() {
+	c.Flush()
+}
+
+func (c *Cache) Name() string {
+	return "TokenCache"
+}
+
+// Get returns the metadata of the token, reading it from the chain on the first request.
+// Lookups that failed are served from memory and read again after RetryInterval.
+func (c *Cache) Get(address common.Address) *model.TokenInfo {
+	c.mu.RLock()
+	info, ok := c.tokens[address]
+	failed, isFailed := c.failed[address]
+	c.mu.RUnlock()
+	if !ok && isFailed && time.Since(failed.at) < RetryInterval {
+		info, ok = failed.info, true
+	}
+	metrics.Cache("token", ok)
+	if ok {
+		return info
+	}
+
+	info, err := c.load(address)
+
+	c.mu.Lock()
+	if err != nil {
+		c.failed[address] = failedLookup{info: info, at: time.Now()}
+		c.mu.Unlock()
+		return info
+	}
+	delete(c.failed, address)
+	c.tokens[address] = info
+	c.mu.Unlock()
+	c.changed()
+	return info
+}
+
+var (
+	InterfaceIdErc721  = [4]byte{0x80, 0xac, 0x58, 0xcd}
+	InterfaceIdErc1155 = [4]byte{0xd9, 0xb6, 0x7a, 0x26}
+)
+
+// GetCollection returns the token metadata with the ERC-721 and ERC-1155 interfaces probed through ERC-165
+func (c *Cache) GetCollection(address common.Address) *model.TokenInfo {
+	info := c.Get(address)
+	if info.InterfacesProbed {
+		return info
+	}
+
+	probed := *info
+	probed.IsErc721, _ = c.RpcWrapper.SupportsInterface(tools.GetContextDefault(), address, InterfaceIdErc721)
+	probed.IsErc1155, _ = c.RpcWrapper.SupportsInterface(tools.GetContextDefault(), address, InterfaceIdErc1155)
+	probed.InterfacesProbed = true
+
+	c.mu.Lock()
+	if _, ok := c.tokens[address]; !ok {
+		// the metadata lookup failed, probe again with it
+		c.mu.Unlock()
+		return &probed
+	}
+	c.tokens[address] = &probed
+	c.mu.Unlock()
+	c.changed()
+	return &probed
+}
+
+// load reads the metadata from the chain. err is set when the node could not be asked,
+// a contract without decimals is not an error and comes back with IsErc20 false.
+func (c *Cache) load(address common.Address) (info *model.TokenInfo, err error) {
+	info = &model.TokenInfo{
+		Address: address,
+	}
+	decimals, er := c.RpcWrapper.GetValueRetUint(tools.GetContextDefault(), address, "decimals", nil)
+	if er == nil && decimals != nil && decimals.IsUint64() && decimals.Uint64() <= 255 {
+		info.Decimals = uint8(decimals.Uint64())
+		info.IsErc20 = true
+	} else if er != nil && !isCallRejected(er) {
+		err = er
+		return
+	}
+	// name and symbol fall back to bytes32 for old tokens like MKR
+	info.Name, _ = c.RpcWrapper.GetValueRetString(tools.GetContextDefault(), address, "name")
+	info.Symbol, _ = c.RpcWrapper.GetValueRetString(tools.GetContextDefault(), address, "symbol")
+	return
+}
+
+// isCallRejected tells answers of the contract, a revert or an empty or malformed return value,
+// from failures to reach the node
+func isCallRejected(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "revert") ||
+		strings.Contains(msg, "abi: ") ||
+		strings.Contains(msg, "invalid opcode")
+}
+
+func (c *Cache) changed() {
+	c.mu.Lock()
+	c.dirty = true
+	due := time.Since(c.lastSave) > SaveInterval
+	c.mu.Unlock()
+	if due {
+		c.Flush()
+	}
+}
+
+// Flush writes pending metadata to disk
+func (c *Cache) Flush() {
+	c.mu.Lock()
+	if !c.dirty {
+		c.mu.Unlock()
+		return
+	}
+	c.dirty = false
+	c.lastSave = time.Now()
+	c.mu.Unlock()
+	c.save()
+}
+
+func (c *Cache) save() {
+	if c.File == "" {
+		return
+	}
+	c.mu.RLock()
+	infos := make([]*model.TokenInfo, 0, len(c.tokens))
+	for _, info := range c.tokens {
+		infos = append(infos, info)
+	}
+	content, err := json.MarshalIndent(infos, "", "  ")
+	c.mu.RUnlock()
+	if err != nil {
+		logrus.WithError(err).Warn("failed to marshal token cache")
+		return
+	}
+
+	c.saveMu.Lock()
+	defer c.saveMu.Unlock()
+	tmp := c.File + ".tmp"
+	err = ioutil.WriteFile(tmp, content, 0644)
+	if err == nil {
+		err = os.Rename(tmp, c.File)
+	}
+	if err != nil {
+		logrus.WithError(err).WithField("file", c.File).Warn("failed to write token cache")
+	}
+}
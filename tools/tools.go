@@ -131,3 +131,8 @@ func SafeToString(to *common.Address) string {
 	}
 	return to.String()
 }
+
+func FromDecimals(value *big.Int, decimals uint8) *big.Rat {
+	e := big.NewInt(0).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	return big.NewRat(1, 1).SetFrac(value, e)
+}
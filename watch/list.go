@@ -0,0 +1,133 @@
+package watch
+
+import (
+	"encoding/json"
+	"github.com/annchain/commongo/files"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/latifrons/etherxray/model"
+	"github.com/sirupsen/logrus"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Watchlist keeps the watched addresses on disk
+type Watchlist struct {
+	File string
+
+	mu      sync.RWMutex
+	saveMu  sync.Mutex
+	watches map[common.Address]*model.Watch
+}
+
+func (l *Watchlist) InitDefault() {
+	l.watches = make(map[common.Address]*model.Watch)
+	if l.File == "" || !files.FileExists(l.File) {
+		return
+	}
+	content, err := ioutil.ReadFile(l.File)
+	if err != nil {
+		logrus.WithError(err).WithField("file", l.File).Warn("failed to read watchlist")
+		return
+	}
+	var watches []*model.Watch
+	err = json.Unmarshal(content, &watches)
+	if err != nil {
+		logrus.WithError(err).WithField("file", l.File).Warn("failed to parse watchlist")
+		return
+	}
+	for _, watch := range watches {
+		l.watches[watch.Address] = watch
+	}
+}
+
+// Add watches the address, or relabels it when it is already watched
+func (l *Watchlist) Add(address common.Address, label string) *model.Watch {
+	watch := &model.Watch{
+		Address:   address,
+		Label:     label,
+		CreatedAt: time.Now().Unix(),
+	}
+	l.mu.Lock()
+	if existing, ok := l.watches[address]; ok {
+		watch.CreatedAt = existing.CreatedAt
+	}
+	l.watches[address] = watch
+	l.mu.Unlock()
+	l.save()
+	return watch
+}
+
+// Remove stops watching the address and tells whether it was watched
+func (l *Watchlist) Remove(address common.Address) bool {
+	l.mu.Lock()
+	_, ok := l.watches[address]
+	delete(l.watches, address)
+	l.mu.Unlock()
+	if ok {
+		l.save()
+	}
+	return ok
+}
+
+func (l *Watchlist) Get(address common.Address) (watch *model.Watch, ok bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	watch, ok = l.watches[address]
+	return
+}
+
+// All returns the watches sorted by address
+func (l *Watchlist) All() []*model.Watch {
+	l.mu.RLock()
+	watches := make([]*model.Watch, 0, len(l.watches))
+	for _, watch := range l.watches {
+		watches = append(watches, watch)
+	}
+	l.mu.RUnlock()
+	sort.Slice(watches, func(i, j int) bool {
+		return watches[i].Address.Hex() < watches[j].Address.Hex()
+	})
+	return watches
+}
+
+// Addresses returns the watched addresses
+func (l *Watchlist) Addresses() []common.Address {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	addresses := make([]common.Address, 0, len(l.watches))
+	for address := range l.watches {
+		addresses = append(addresses, address)
+	}
+	return addresses
+}
+
+func (l *Watchlist) Len() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return len(l.watches)
+}
+
+func (l *Watchlist) save() {
+	if l.File == "" {
+		return
+	}
+	content, err := json.Marshal(l.All())
+	if err != nil {
+		logrus.WithError(err).Warn("failed to marshal watchlist")
+		return
+	}
+
+	l.saveMu.Lock()
+	defer l.saveMu.Unlock()
+	tmp := l.File + ".tmp"
+	err = ioutil.WriteFile(tmp, content, 0644)
+	if err == nil {
+		err = os.Rename(tmp, l.File)
+	}
+	if err != nil {
+		logrus.WithError(err).WithField("file", l.File).Warn("failed to write watchlist")
+	}
+}
@@ -0,0 +1,139 @@
+package watch
+
+import (
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/latifrons/etherxray/ethnode"
+	"github.com/latifrons/etherxray/model"
+	"github.com/latifrons/etherxray/notify"
+)
+
+// Watcher posts the activity of the watched addresses in every new block to the webhook
+type Watcher struct {
+	EthNode *ethnode.EthNode
+	List    *Watchlist
+	Webhook *notify.Webhook
+}
+
+func (w *Watcher) Name() string {
+	return "Watcher"
+}
+
+func (w *Watcher) HandleBlock(height uint64, hash common.Hash, txs []model.Tx) {
+	if w.List.Len() == 0 {
+		return
+	}
+	alerts := w.Match(txs)
+	if len(alerts) == 0 {
+		return
+	}
+	// the hash tells apart the payloads of a block replaced by a reorg
+	id := fmt.Sprintf("watch-%d-%s", height, hash.Hex()[2:10])
+	w.Webhook.Send(id, model.WatchPayload{
+		Id:     id,
+		Height: height,
+		Alerts: alerts,
+	})
+}
+
+// Match lists the activity of the watched addresses in the transactions
+func (w *Watcher) Match(txs []model.Tx) (alerts []model.WatchAlert) {
+	for _, tx := range txs {
+		hash := tx.BasicTx.Hash()
+		alert := func(kind string, watch *model.Watch) model.WatchAlert {
+			return model.WatchAlert{
+				Kind:    kind,
+				Address: watch.Address,
+				Label:   watch.Label,
+				TxHash:  hash,
+			}
+		}
+
+		var to common.Address
+		if tx.BasicTx.To() != nil {
+			to = *tx.BasicTx.To()
+		}
+		for _, watch := range w.involved(tx.From, to) {
+			a := alert(model.WatchMatchTx, watch)
+			a.From = tx.From
+			a.To = tx.BasicTx.To()
+			a.Value = tx.BasicTx.Value().String()
+			alerts = append(alerts, a)
+		}
+
+		for _, transfer := range tx.InternalTransfers {
+			for _, watch := range w.involved(transfer.From, transfer.To) {
+				a := alert(model.WatchMatchInternalTransfer, watch)
+				a.From = transfer.From
+				a.To = addressPtr(transfer.To)
+				a.Value = transfer.Value.String()
+				alerts = append(alerts, a)
+			}
+		}
+
+		for _, transfer := range tx.TokenTransfers {
+			for _, watch := range w.involved(transfer.From, transfer.To) {
+				a := alert(model.WatchMatchTokenTransfer, watch)
+				a.From = transfer.From
+				a.To = addressPtr(transfer.To)
+				a.Value = transfer.Amount.String()
+				a.Token = addressPtr(transfer.Token)
+				a.Symbol = transfer.Symbol
+				a.LogIndex = uintPtr(transfer.LogIndex)
+				alerts = append(alerts, a)
+			}
+		}
+
+		for _, transfer := range tx.NftTransfers {
+			for _, watch := range w.involved(transfer.From, transfer.To) {
+				a := alert(model.WatchMatchNftTransfer, watch)
+				a.From = transfer.From
+				a.To = addressPtr(transfer.To)
+				a.Value = transfer.Amount.String()
+				a.Token = addressPtr(transfer.Contract)
+				a.Symbol = transfer.Collection
+				a.TokenId = transfer.TokenId.String()
+				a.LogIndex = uintPtr(transfer.LogIndex)
+				alerts = append(alerts, a)
+			}
+		}
+
+		for _, log := range tx.Receipt.Logs {
+			watch, ok := w.List.Get(log.Address)
+			if !ok {
+				continue
+			}
+			a := alert(model.WatchMatchEvent, watch)
+			a.From = tx.From
+			a.LogIndex = uintPtr(log.Index)
+			if len(log.Topics) > 0 {
+				a.Event = log.Topics[0].Hex()
+				if sig, ok := w.EthNode.Registry.Event(log.Topics[0]); ok {
+					a.Event = sig.Text
+				}
+			}
+			alerts = append(alerts, a)
+		}
+	}
+	return
+}
+
+// involved returns the watches of the sender and the receiver of a transfer.
+// The watches are returned rather than looked up again, the list may change meanwhile.
+func (w *Watcher) involved(from common.Address, to common.Address) (watches []*model.Watch) {
+	if watch, ok := w.List.Get(from); ok {
+		watches = append(watches, watch)
+	}
+	if watch, ok := w.List.Get(to); ok && to != from {
+		watches = append(watches, watch)
+	}
+	return
+}
+
+func addressPtr(address common.Address) *common.Address {
+	return &address
+}
+
+func uintPtr(v uint) *uint {
+	return &v
+}